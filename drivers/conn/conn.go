@@ -0,0 +1,177 @@
+package netconf
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	rpc "github.com/davedotdev/go-netconf/rpc"
+	session "github.com/davedotdev/go-netconf/session"
+	transport "github.com/davedotdev/go-netconf/transport"
+)
+
+// DriverConn type is for running NETCONF over a connection the caller already established, such as a tunnel
+// or a test pipe. The driver does no dialing of its own. Implements Driver{}
+type DriverConn struct {
+	Datastore string                      // NETCONF datastore
+	Conn      net.Conn                    // Conn for session
+	Transport *transport.TransportBasicIO // Transport data
+	Session   *session.Session            // Session data
+	Debug     io.Writer                   // Receives a transcript of every NETCONF message when set
+
+	// ForceFraming overrides the negotiated message framing with "eom" or "chunked".
+	// It is only meant for debugging device framing quirks and logs a warning when used.
+	ForceFraming string
+
+	// MaxReplySize caps the bytes read for a single reply, see transport.TransportBasicIO.MaxReplySize
+	MaxReplySize int64
+
+	// HelloTimeout bounds the hello exchange, session.DefaultHelloTimeout when zero.
+	// The connection is closed when it expires.
+	HelloTimeout time.Duration
+
+	// MessageID generates the message-id of every RPC, e.g. to embed a trace id, rpc.NextMessageID when nil
+	MessageID func() string
+}
+
+// New creates a new instance of DriverConn using conn, reading the running datastore with GetConfig
+func New(conn net.Conn) *DriverConn {
+	return &DriverConn{Conn: conn, Datastore: "running"}
+}
+
+// SetDatastore sets the target datastore on the data structure, rejecting any but the rpc.Datastore constants
+func (d *DriverConn) SetDatastore(ds string) error {
+	err := rpc.Datastore(ds).Validate()
+	if err != nil {
+		return err
+	}
+
+	d.Datastore = ds
+	return nil
+}
+
+// Dial function (call this after New()). Performs the hello exchange over the connection.
+func (d *DriverConn) Dial() error {
+	return d.DialContext(context.Background())
+}
+
+// DialContext function (call this after New()). Cancelling ctx aborts a hello exchange in progress
+// by closing the connection.
+func (d *DriverConn) DialContext(ctx context.Context) error {
+	if d.Conn == nil {
+		return fmt.Errorf("conn driver has no connection")
+	}
+
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	d.Transport = &transport.TransportBasicIO{ReadWriteCloser: d.Conn, Debug: d.Debug, ForceFraming: d.ForceFraming, MaxReplySize: d.MaxReplySize}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			d.Conn.Close()
+		case <-done:
+		}
+	}()
+
+	d.Session, err = session.NewSessionTimeout(d.Transport, d.HelloTimeout, func() { d.Conn.Close() })
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+
+	d.Session.MessageID = d.MessageID
+
+	return nil
+}
+
+// DialTimeout NOT IMPLEMENTED. The connection is already established.
+func (d *DriverConn) DialTimeout() error {
+	return d.Dial()
+}
+
+// Close ends the session and closes the connection
+func (d *DriverConn) Close() error {
+	if d.Session != nil {
+		d.Session.Close()
+	}
+
+	return d.Conn.Close()
+}
+
+// Lock the target datastore
+func (d *DriverConn) Lock(ds string) (*rpc.RPCReply, error) {
+	method, err := rpc.MethodLockDatastore(rpc.Datastore(ds))
+	if err != nil {
+		return nil, err
+	}
+
+	return d.Session.Exec(method)
+}
+
+// Unlock the target datastore
+func (d *DriverConn) Unlock(ds string) (*rpc.RPCReply, error) {
+	method, err := rpc.MethodUnlockDatastore(rpc.Datastore(ds))
+	if err != nil {
+		return nil, err
+	}
+
+	return d.Session.Exec(method)
+}
+
+// SendRaw sends a raw XML envelope
+func (d *DriverConn) SendRaw(rawxml string) (*rpc.RPCReply, error) {
+	return d.Session.Exec(rpc.RawMethod(rawxml))
+}
+
+// SendRawStream sends a raw XML envelope and returns a reader over the reply as it arrives, for replies too
+// large to buffer. The reply must be read to io.EOF before anything else is sent.
+func (d *DriverConn) SendRawStream(rawxml string) (io.Reader, error) {
+	return d.Session.ExecStream(rpc.RawMethod(rawxml))
+}
+
+// Subscribe creates a subscription to the event stream, replaying from startTime unless it is zero
+func (d *DriverConn) Subscribe(stream string, startTime time.Time) error {
+	var start string
+	if !startTime.IsZero() {
+		start = startTime.Format(time.RFC3339Nano)
+	}
+
+	_, err := d.Session.Exec(rpc.MethodCreateSubscription(stream, start))
+
+	return err
+}
+
+// ReceiveNotification blocks until the next notification arrives after Subscribe
+func (d *DriverConn) ReceiveNotification() (*rpc.Notification, error) {
+	return d.Session.ReceiveNotification()
+}
+
+// ServerCapabilities returns the capabilities the server advertised in its hello, or nil before Dial
+func (d *DriverConn) ServerCapabilities() []string {
+	if d.Session == nil {
+		return nil
+	}
+
+	return d.Session.ServerCapabilities
+}
+
+// GetConfig requests the contents of a datastore
+func (d *DriverConn) GetConfig() (*rpc.RPCReply, error) {
+	method, err := rpc.MethodGetConfigDatastore(rpc.Datastore(d.Datastore))
+	if err != nil {
+		return nil, err
+	}
+
+	return d.Session.Exec(method)
+}
@@ -4,7 +4,11 @@
 
 package netconf
 
-import rpc "github.com/davedotdev/go-netconf/rpc"
+import (
+	"context"
+
+	rpc "github.com/davedotdev/go-netconf/rpc"
+)
 
 // Driver interface for building drivers that are self-contained from a user's perspective.
 type Driver interface {
@@ -13,6 +17,7 @@ type Driver interface {
 
 	Close() error
 	Dial() error
+	DialContext(ctx context.Context) error
 	DialTimeout() error
 	SendRaw(rawxml string) (*rpc.RPCReply, error)
 	GetConfig() (*rpc.RPCReply, error)
@@ -1,6 +1,8 @@
 package netconf
 
 import (
+	"context"
+	"io"
 	"time"
 
 	lowlevel "github.com/davedotdev/go-netconf/drivers/junos/lowlevel"
@@ -20,16 +22,31 @@ func New() *DriverJunos {
 	return &DriverJunos{}
 }
 
-// SetDatastore sets the target datastore on the data structure
+// SetDatastore sets the target datastore on the data structure, rejecting any but the rpc.Datastore constants
 func (d *DriverJunos) SetDatastore(ds string) error {
+	err := rpc.Datastore(ds).Validate()
+	if err != nil {
+		return err
+	}
+
 	d.Datastore = ds
 	return nil
 }
 
 // Dial function (call this after New())
 func (d *DriverJunos) Dial() error {
+	return d.DialContext(context.Background())
+}
+
+// DialContext function (call this after New()). The local shell session starts immediately,
+// so the context is only checked before it is opened.
+func (d *DriverJunos) DialContext(ctx context.Context) error {
 
-	var err error
+	err := ctx.Err()
+
+	if err != nil {
+		return err
+	}
 
 	d.Session, err = lowlevel.Dial()
 
@@ -65,7 +82,12 @@ func (d *DriverJunos) Close() error {
 
 // Lock the target datastore
 func (d *DriverJunos) Lock(ds string) (*rpc.RPCReply, error) {
-	reply, err := d.Session.Exec(rpc.MethodLock(ds))
+	method, err := rpc.MethodLockDatastore(rpc.Datastore(ds))
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := d.Session.Exec(method)
 
 	if err != nil {
 		return reply, err
@@ -76,7 +98,12 @@ func (d *DriverJunos) Lock(ds string) (*rpc.RPCReply, error) {
 
 // Unlock the target datastore
 func (d *DriverJunos) Unlock(ds string) (*rpc.RPCReply, error) {
-	reply, err := d.Session.Exec(rpc.MethodUnlock(ds))
+	method, err := rpc.MethodUnlockDatastore(rpc.Datastore(ds))
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := d.Session.Exec(method)
 
 	if err != nil {
 		return reply, err
@@ -96,9 +123,46 @@ func (d *DriverJunos) SendRaw(rawxml string) (*rpc.RPCReply, error) {
 	return reply, nil
 }
 
+// SendRawStream sends a raw XML envelope and returns a reader over the reply as it arrives, for replies too
+// large to buffer. The reply must be read to io.EOF before anything else is sent.
+func (d *DriverJunos) SendRawStream(rawxml string) (io.Reader, error) {
+	return d.Session.ExecStream(rpc.RawMethod(rawxml))
+}
+
+// Subscribe creates a subscription to the event stream, replaying from startTime unless it is zero
+func (d *DriverJunos) Subscribe(stream string, startTime time.Time) error {
+	var start string
+	if !startTime.IsZero() {
+		start = startTime.Format(time.RFC3339Nano)
+	}
+
+	_, err := d.Session.Exec(rpc.MethodCreateSubscription(stream, start))
+
+	return err
+}
+
+// ReceiveNotification blocks until the next notification arrives after Subscribe
+func (d *DriverJunos) ReceiveNotification() (*rpc.Notification, error) {
+	return d.Session.ReceiveNotification()
+}
+
+// ServerCapabilities returns the capabilities the server advertised in its hello, or nil before Dial
+func (d *DriverJunos) ServerCapabilities() []string {
+	if d.Session == nil {
+		return nil
+	}
+
+	return d.Session.ServerCapabilities
+}
+
 // GetConfig requests the contents of a datastore
 func (d *DriverJunos) GetConfig() (*rpc.RPCReply, error) {
-	reply, err := d.Session.Exec(rpc.MethodGetConfig(d.Datastore))
+	method, err := rpc.MethodGetConfigDatastore(rpc.Datastore(d.Datastore))
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := d.Session.Exec(method)
 
 	if err != nil {
 		return reply, err
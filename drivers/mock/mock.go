@@ -0,0 +1,298 @@
+// Package netconf provides MockDriver, a driver replaying a scripted NETCONF session, and Recorder, which
+// captures a real session as such a script. Together they turn a troubleshooting capture into an offline test.
+package netconf
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	rpc "github.com/davedotdev/go-netconf/rpc"
+)
+
+// Operations of the Driver interface an Exchange records
+const (
+	OpSendRaw   = "send-raw"
+	OpLock      = "lock"
+	OpUnlock    = "unlock"
+	OpGetConfig = "get-config"
+
+	// Optional operations, for drivers reporting capabilities, streaming replies or receiving notifications
+	OpServerCapabilities  = "server-capabilities"
+	OpSendRawStream       = "send-raw-stream"
+	OpSubscribe           = "subscribe"
+	OpReceiveNotification = "receive-notification"
+)
+
+// Optional interfaces of the wrapped driver a Recorder forwards to
+type capabilityReporter interface {
+	ServerCapabilities() []string
+}
+
+type streamer interface {
+	SendRawStream(rawxml string) (io.Reader, error)
+}
+
+type notifier interface {
+	Subscribe(stream string, startTime time.Time) error
+	ReceiveNotification() (*rpc.Notification, error)
+}
+
+// Exchange is one request of a session and the reply it got. A script is a file of exchanges, one JSON
+// object per line.
+type Exchange struct {
+	Op      string `json:"op"`                // One of the Op values
+	Request string `json:"request,omitempty"` // Raw XML sent with OpSendRaw and OpSendRawStream, the datastore of OpLock and OpUnlock, the stream of OpSubscribe
+	Reply   string `json:"reply,omitempty"`   // The rpc-reply, or notification, as received, empty when none was
+	Error   string `json:"error,omitempty"`   // Error the request failed with, empty on success
+
+	Capabilities []string `json:"capabilities,omitempty"` // The capabilities reported with OpServerCapabilities
+
+	// The rpc-errors the request failed with when no reply was returned, replayed as *rpc.RPCError or
+	// *rpc.RPCErrors so callers inspecting the error see what the device sent
+	RPCErrors []rpc.RPCError `json:"rpc-errors,omitempty"`
+}
+
+// MockDriver is a driver replaying a script of exchanges. Each request must match the next exchange,
+// otherwise it fails without consuming it. Implements Driver{}
+type MockDriver struct {
+	lock      sync.Mutex
+	exchanges []Exchange
+
+	Dials  int // Times Dial, DialContext or DialTimeout was called
+	Closes int // Times Close was called
+}
+
+// New returns a mock driver replaying exchanges in order
+func New(exchanges ...Exchange) *MockDriver {
+	return &MockDriver{exchanges: exchanges}
+}
+
+// LoadScript returns a mock driver replaying the script read from r, as written by a Recorder
+func LoadScript(r io.Reader) (*MockDriver, error) {
+	var exchanges []Exchange
+
+	dec := json.NewDecoder(bufio.NewReader(r))
+	for {
+		var ex Exchange
+
+		err := dec.Decode(&ex)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid script at exchange %d: %v", len(exchanges)+1, err)
+		}
+
+		exchanges = append(exchanges, ex)
+	}
+
+	return New(exchanges...), nil
+}
+
+// LoadScriptFile returns a mock driver replaying the script in the file at path
+func LoadScriptFile(path string) (*MockDriver, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return LoadScript(f)
+}
+
+// Remaining returns the number of exchanges not yet replayed
+func (d *MockDriver) Remaining() int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return len(d.exchanges)
+}
+
+// next consumes the next exchange, which must be one of ops with request
+func (d *MockDriver) next(request string, ops ...string) (Exchange, error) {
+	request = redact(request)
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if len(d.exchanges) == 0 {
+		return Exchange{}, fmt.Errorf("mock driver: unexpected %s %q, the script is exhausted", ops[0], request)
+	}
+
+	ex := d.exchanges[0]
+	for _, op := range ops {
+		if ex.Op == op && redact(ex.Request) == request {
+			d.exchanges = d.exchanges[1:]
+			return ex, nil
+		}
+	}
+
+	return Exchange{}, fmt.Errorf("mock driver: unexpected %s %q, expected %s %q", ops[0], request, ex.Op, ex.Request)
+}
+
+// failure returns the error an exchange without a reply failed with
+func failure(ex Exchange) error {
+	switch {
+	case len(ex.RPCErrors) == 1:
+		return &ex.RPCErrors[0]
+	case len(ex.RPCErrors) > 1:
+		return &rpc.RPCErrors{Errors: ex.RPCErrors}
+	case ex.Error != "":
+		return errors.New(ex.Error)
+	}
+	return fmt.Errorf("mock driver: exchange has neither a reply nor an error")
+}
+
+// replay returns the reply of the next exchange, which must be op with request
+func (d *MockDriver) replay(op string, request string) (*rpc.RPCReply, error) {
+	ex, err := d.next(request, op)
+	if err != nil {
+		return nil, err
+	}
+
+	if ex.Reply == "" {
+		return nil, failure(ex)
+	}
+
+	reply, err := rpc.NewRPCReply([]byte(ex.Reply), false)
+	if err == nil && ex.Error != "" {
+		// e.g. a warning treated as an error by the recorded session
+		err = errors.New(ex.Error)
+	}
+
+	return reply, err
+}
+
+// Dial counts the dial, there is nothing to connect to
+func (d *MockDriver) Dial() error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.Dials++
+	return nil
+}
+
+// DialContext is Dial
+func (d *MockDriver) DialContext(ctx context.Context) error {
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	return d.Dial()
+}
+
+// DialTimeout is Dial
+func (d *MockDriver) DialTimeout() error {
+	return d.Dial()
+}
+
+// Close counts the close
+func (d *MockDriver) Close() error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.Closes++
+	return nil
+}
+
+// Lock replays the lock of the datastore ds
+func (d *MockDriver) Lock(ds string) (*rpc.RPCReply, error) {
+	return d.replay(OpLock, ds)
+}
+
+// Unlock replays the unlock of the datastore ds
+func (d *MockDriver) Unlock(ds string) (*rpc.RPCReply, error) {
+	return d.replay(OpUnlock, ds)
+}
+
+// SendRaw replays the raw XML request rawxml
+func (d *MockDriver) SendRaw(rawxml string) (*rpc.RPCReply, error) {
+	return d.replay(OpSendRaw, rawxml)
+}
+
+// GetConfig replays a get-config
+func (d *MockDriver) GetConfig() (*rpc.RPCReply, error) {
+	return d.replay(OpGetConfig, "")
+}
+
+// ServerCapabilities replays the capabilities of the next exchange when it reports them. Otherwise, as for a
+// script recorded without them, it returns nil and the device is assumed to support everything.
+func (d *MockDriver) ServerCapabilities() []string {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if len(d.exchanges) == 0 || d.exchanges[0].Op != OpServerCapabilities {
+		return nil
+	}
+
+	ex := d.exchanges[0]
+	d.exchanges = d.exchanges[1:]
+
+	return ex.Capabilities
+}
+
+// SendRawStream replays the raw XML request rawxml, returning a reader over the recorded reply. The request
+// may have been recorded streamed or, from a driver unable to stream, with SendRaw.
+func (d *MockDriver) SendRawStream(rawxml string) (io.Reader, error) {
+	ex, err := d.next(rawxml, OpSendRawStream, OpSendRaw)
+	if err != nil {
+		return nil, err
+	}
+
+	if ex.Reply == "" {
+		return nil, failure(ex)
+	}
+
+	if ex.Op == OpSendRawStream && ex.Error != "" {
+		// The stream broke after part of the reply was read
+		return io.MultiReader(strings.NewReader(ex.Reply), &errReader{err: errors.New(ex.Error)}), nil
+	}
+
+	return strings.NewReader(ex.Reply), nil
+}
+
+// Subscribe replays the subscription to stream, whatever the start time
+func (d *MockDriver) Subscribe(stream string, startTime time.Time) error {
+	ex, err := d.next(stream, OpSubscribe)
+	if err != nil {
+		return err
+	}
+
+	if ex.Error != "" {
+		return errors.New(ex.Error)
+	}
+
+	return nil
+}
+
+// ReceiveNotification replays the next notification
+func (d *MockDriver) ReceiveNotification() (*rpc.Notification, error) {
+	ex, err := d.next("", OpReceiveNotification)
+	if err != nil {
+		return nil, err
+	}
+
+	if ex.Reply == "" {
+		return nil, failure(ex)
+	}
+
+	return rpc.NewNotification([]byte(ex.Reply))
+}
+
+// errReader fails every read with err
+type errReader struct {
+	err error
+}
+
+func (r *errReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
@@ -0,0 +1,281 @@
+package netconf
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	conndriver "github.com/davedotdev/go-netconf/drivers/conn"
+	rpc "github.com/davedotdev/go-netconf/rpc"
+)
+
+const serverHello = `<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<capabilities>
+<capability>urn:ietf:params:netconf:base:1.0</capability>
+</capabilities>
+<session-id>1</session-id>
+</hello>
+]]>]]>`
+
+const versionReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><software-information><host-name>r1</host-name></software-information></rpc-reply>`
+
+const lockDeniedReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><rpc-error><error-type>protocol</error-type><error-tag>lock-denied</error-tag><error-severity>error</error-severity><error-message>configuration database locked</error-message></rpc-error></rpc-reply>`
+
+// serve answers the client hello and then every RPC on conn with the next of replies
+func serve(conn net.Conn, replies ...string) {
+	defer conn.Close()
+
+	conn.Write([]byte(serverHello))
+
+	r := bufio.NewReader(conn)
+	readMessage := func() error {
+		var msg []byte
+		for !bytes.HasSuffix(msg, []byte("]]>]]>")) {
+			b, err := r.ReadByte()
+			if err != nil {
+				return err
+			}
+			msg = append(msg, b)
+		}
+		return nil
+	}
+
+	// The client hello
+	if readMessage() != nil {
+		return
+	}
+
+	for _, reply := range replies {
+		if readMessage() != nil {
+			return
+		}
+		conn.Write([]byte(reply + "]]>]]>"))
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	go func() {
+		server, err := ln.Accept()
+		if err == nil {
+			serve(server, versionReply, lockDeniedReply)
+		}
+	}()
+
+	client, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+
+	var script bytes.Buffer
+	d := NewRecorder(conndriver.New(client), &script)
+
+	err = d.Dial()
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+
+	recorded, err := d.SendRaw("<get-software-information/>")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, recordedErr := d.Lock("candidate")
+	if recordedErr == nil {
+		t.Fatal("expected the lock to be denied")
+	}
+
+	d.Close()
+
+	if d.Err() != nil {
+		t.Fatalf("failed to write the script: %v", d.Err())
+	}
+
+	mock, err := LoadScript(&script)
+	if err != nil {
+		t.Fatalf("failed to load the script: %v", err)
+	}
+
+	if mock.Remaining() != 2 {
+		t.Fatalf("got %d exchanges, expected 2", mock.Remaining())
+	}
+
+	replayed, err := mock.SendRaw("<get-software-information/>")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if replayed.Data != recorded.Data {
+		t.Errorf("got reply %q, expected %q", replayed.Data, recorded.Data)
+	}
+
+	_, err = mock.Lock("candidate")
+	var rpcErr *rpc.RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Tag != "lock-denied" {
+		t.Errorf("got error %v, expected the recorded %v", err, recordedErr)
+	}
+
+	if mock.Remaining() != 0 {
+		t.Errorf("got %d exchanges left, expected 0", mock.Remaining())
+	}
+}
+
+func TestRecordOptionalMethods(t *testing.T) {
+	const notification = `<notification xmlns="urn:ietf:params:xml:ns:netconf:notification:1.0"><eventTime>2026-10-17T10:00:00Z</eventTime><event/></notification>`
+
+	exchanges := []Exchange{
+		{Op: OpServerCapabilities, Capabilities: []string{"urn:ietf:params:netconf:base:1.0"}},
+		{Op: OpSendRawStream, Request: "<get-software-information/>", Reply: versionReply},
+		{Op: OpSubscribe, Request: "NETCONF"},
+		{Op: OpReceiveNotification, Reply: notification},
+	}
+
+	// Record a session with a scripted driver, the script written should be the one replayed
+	var script bytes.Buffer
+	d := NewRecorder(New(exchanges...), &script)
+
+	capabilities := d.ServerCapabilities()
+	if !reflect.DeepEqual(capabilities, exchanges[0].Capabilities) {
+		t.Errorf("got capabilities %q, expected %q", capabilities, exchanges[0].Capabilities)
+	}
+
+	r, err := d.SendRawStream("<get-software-information/>")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	streamed, err := ioutil.ReadAll(r)
+	if err != nil || string(streamed) != versionReply {
+		t.Errorf("got reply %q and error %v, expected %q", streamed, err, versionReply)
+	}
+
+	err = d.Subscribe("NETCONF", time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	n, err := d.ReceiveNotification()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if n.EventTime != "2026-10-17T10:00:00Z" {
+		t.Errorf("got event time %q, expected 2026-10-17T10:00:00Z", n.EventTime)
+	}
+
+	if d.Err() != nil {
+		t.Fatalf("failed to write the script: %v", d.Err())
+	}
+
+	mock, err := LoadScript(&script)
+	if err != nil {
+		t.Fatalf("failed to load the script: %v", err)
+	}
+
+	if !reflect.DeepEqual(mock.exchanges, exchanges) {
+		t.Errorf("got script %+v, expected %+v", mock.exchanges, exchanges)
+	}
+
+	// A script recorded without capabilities assumes the device supports everything
+	if New().ServerCapabilities() != nil {
+		t.Error("expected no capabilities from a script without them")
+	}
+
+	// Replies recorded with SendRaw, from a driver unable to stream, replay streamed
+	r, err = New(Exchange{Op: OpSendRaw, Request: "<get-software-information/>", Reply: versionReply}).SendRawStream("<get-software-information/>")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	streamed, err = ioutil.ReadAll(r)
+	if err != nil || string(streamed) != versionReply {
+		t.Errorf("got reply %q and error %v, expected %q", streamed, err, versionReply)
+	}
+}
+
+func TestRecordRPCErrors(t *testing.T) {
+	failed := []rpc.RPCError{
+		{Severity: "error", Message: "first"},
+		{Severity: "error", Tag: "lock-denied", Message: "second"},
+	}
+
+	var script bytes.Buffer
+	d := NewRecorder(New(Exchange{Op: OpLock, Request: "candidate", RPCErrors: failed}), &script)
+
+	_, err := d.Lock("candidate")
+	if err == nil {
+		t.Fatal("expected the lock to fail")
+	}
+
+	mock, err := LoadScript(&script)
+	if err != nil {
+		t.Fatalf("failed to load the script: %v", err)
+	}
+
+	if len(mock.exchanges) != 1 || !reflect.DeepEqual(mock.exchanges[0].RPCErrors, failed) {
+		t.Errorf("got script %+v, expected every rpc-error recorded", mock.exchanges)
+	}
+}
+
+func TestRecordRedactsPasswords(t *testing.T) {
+	const request = `<load-configuration><configuration><system><login><user><name>admin</name>` +
+		`<authentication><plain-text-password-value>s3cret!</plain-text-password-value></authentication>` +
+		`</user></login></system></configuration></load-configuration>`
+
+	var script bytes.Buffer
+	d := NewRecorder(New(Exchange{Op: OpSendRaw, Request: request, Reply: versionReply}), &script)
+
+	_, err := d.SendRaw(request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if strings.Contains(script.String(), "s3cret!") {
+		t.Fatalf("got script %q, expected the password redacted", script.String())
+	}
+
+	mock, err := LoadScript(&script)
+	if err != nil {
+		t.Fatalf("failed to load the script: %v", err)
+	}
+
+	// The request replays although the script no longer holds the password
+	_, err = mock.SendRaw(request)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestMockDriverUnexpectedRequest(t *testing.T) {
+	d := New(Exchange{Op: OpSendRaw, Request: "<commit/>", Reply: versionReply})
+
+	_, err := d.SendRaw("<discard-changes/>")
+	if err == nil || !strings.Contains(err.Error(), "<commit/>") {
+		t.Errorf("got error %v, expected the mismatch to name the expected request", err)
+	}
+
+	if d.Remaining() != 1 {
+		t.Errorf("got %d exchanges left, expected the mismatched one to be kept", d.Remaining())
+	}
+
+	_, err = d.SendRaw("<commit/>")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = d.GetConfig()
+	if err == nil {
+		t.Error("expected an error once the script is exhausted")
+	}
+}
@@ -0,0 +1,210 @@
+package netconf
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	driver "github.com/davedotdev/go-netconf/drivers/driver"
+	rpc "github.com/davedotdev/go-netconf/rpc"
+	transport "github.com/davedotdev/go-netconf/transport"
+)
+
+// Recorder wraps a driver, writing every request it sends and the reply it gets to a script a MockDriver
+// can replay with LoadScript, with plain-text passwords redacted. Dialing and closing pass straight through
+// and are not recorded. The optional ServerCapabilities, SendRawStream, Subscribe and ReceiveNotification
+// methods are forwarded and recorded too, falling back as the helpers do when the wrapped driver lacks them.
+// Implements Driver{}
+type Recorder struct {
+	driver.Driver
+
+	lock sync.Mutex
+	enc  *json.Encoder
+	err  error
+}
+
+// NewRecorder returns d recording its exchanges to w
+func NewRecorder(d driver.Driver, w io.Writer) *Recorder {
+	return &Recorder{Driver: d, enc: json.NewEncoder(w)}
+}
+
+// Err returns the first error writing the script, which does not fail the recorded requests
+func (r *Recorder) Err() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.err
+}
+
+// record writes the exchange of op with request and returns the reply and error unchanged
+func (r *Recorder) record(op string, request string, reply *rpc.RPCReply, err error) (*rpc.RPCReply, error) {
+	ex := Exchange{Op: op, Request: redact(request)}
+	if reply != nil {
+		ex.Reply = reply.RawReply
+	}
+	if err != nil {
+		ex.Error = err.Error()
+	}
+
+	// Without a reply to replay them from, keep the rpc-errors the request failed with
+	if reply == nil && err != nil {
+		var rpcErr *rpc.RPCError
+		var rpcErrs *rpc.RPCErrors
+		// RPCErrors first, errors.As would recover only its first error as a *RPCError
+		if errors.As(err, &rpcErrs) {
+			ex.RPCErrors = rpcErrs.Errors
+		} else if errors.As(err, &rpcErr) {
+			ex.RPCErrors = []rpc.RPCError{*rpcErr}
+		}
+	}
+
+	r.write(ex)
+
+	return reply, err
+}
+
+// redact returns request with its plain-text passwords replaced, so they are not kept in the script.
+// MockDriver redacts the requests it replays alike before matching them.
+func redact(request string) string {
+	return string(transport.RedactSecrets([]byte(request)))
+}
+
+// write appends ex to the script
+func (r *Recorder) write(ex Exchange) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.err == nil {
+		r.err = r.enc.Encode(ex)
+	}
+}
+
+// Lock locks the datastore ds, recording the exchange
+func (r *Recorder) Lock(ds string) (*rpc.RPCReply, error) {
+	reply, err := r.Driver.Lock(ds)
+	return r.record(OpLock, ds, reply, err)
+}
+
+// Unlock unlocks the datastore ds, recording the exchange
+func (r *Recorder) Unlock(ds string) (*rpc.RPCReply, error) {
+	reply, err := r.Driver.Unlock(ds)
+	return r.record(OpUnlock, ds, reply, err)
+}
+
+// SendRaw sends the raw XML request rawxml, recording the exchange
+func (r *Recorder) SendRaw(rawxml string) (*rpc.RPCReply, error) {
+	reply, err := r.Driver.SendRaw(rawxml)
+	return r.record(OpSendRaw, rawxml, reply, err)
+}
+
+// GetConfig reads the configuration, recording the exchange
+func (r *Recorder) GetConfig() (*rpc.RPCReply, error) {
+	reply, err := r.Driver.GetConfig()
+	return r.record(OpGetConfig, "", reply, err)
+}
+
+// ServerCapabilities returns the capabilities the wrapped driver reports, nil when it can not, recording them
+func (r *Recorder) ServerCapabilities() []string {
+	var capabilities []string
+	if cr, ok := r.Driver.(capabilityReporter); ok {
+		capabilities = cr.ServerCapabilities()
+	}
+
+	r.write(Exchange{Op: OpServerCapabilities, Capabilities: capabilities})
+
+	return capabilities
+}
+
+// SendRawStream sends the raw XML request rawxml, recording the exchange once the reply is read to io.EOF.
+// A wrapped driver unable to stream sends it with SendRaw, recorded as such, and the reply is returned
+// buffered.
+func (r *Recorder) SendRawStream(rawxml string) (io.Reader, error) {
+	s, ok := r.Driver.(streamer)
+	if !ok {
+		reply, err := r.SendRaw(rawxml)
+		if err != nil {
+			return nil, err
+		}
+		return strings.NewReader(reply.RawReply), nil
+	}
+
+	stream, err := s.SendRawStream(rawxml)
+	if err != nil {
+		r.write(Exchange{Op: OpSendRawStream, Request: redact(rawxml), Error: err.Error()})
+		return nil, err
+	}
+
+	return &recordedStream{r: r, request: redact(rawxml), stream: stream}, nil
+}
+
+// Subscribe creates a subscription to the event stream on the wrapped driver, recording the exchange
+func (r *Recorder) Subscribe(stream string, startTime time.Time) error {
+	var err error
+	if n, ok := r.Driver.(notifier); ok {
+		err = n.Subscribe(stream, startTime)
+	} else {
+		err = fmt.Errorf("driver %T does not support notifications", r.Driver)
+	}
+
+	ex := Exchange{Op: OpSubscribe, Request: stream}
+	if err != nil {
+		ex.Error = err.Error()
+	}
+	r.write(ex)
+
+	return err
+}
+
+// ReceiveNotification returns the next notification from the wrapped driver, recording it
+func (r *Recorder) ReceiveNotification() (*rpc.Notification, error) {
+	var notification *rpc.Notification
+	var err error
+	if n, ok := r.Driver.(notifier); ok {
+		notification, err = n.ReceiveNotification()
+	} else {
+		err = fmt.Errorf("driver %T does not support notifications", r.Driver)
+	}
+
+	ex := Exchange{Op: OpReceiveNotification}
+	if notification != nil {
+		ex.Reply = notification.RawReply
+	}
+	if err != nil {
+		ex.Error = err.Error()
+	}
+	r.write(ex)
+
+	return notification, err
+}
+
+// recordedStream is a streamed reply, recorded once it has been read to the end
+type recordedStream struct {
+	r       *Recorder
+	request string
+	stream  io.Reader
+	reply   bytes.Buffer
+	done    bool
+}
+
+// Read reads the reply, recording the exchange at io.EOF or the first error
+func (s *recordedStream) Read(p []byte) (int, error) {
+	n, err := s.stream.Read(p)
+	s.reply.Write(p[:n])
+
+	if err != nil && !s.done {
+		s.done = true
+
+		ex := Exchange{Op: OpSendRawStream, Request: s.request, Reply: s.reply.String()}
+		if err != io.EOF {
+			ex.Error = err.Error()
+		}
+		s.r.write(ex)
+	}
+
+	return n, err
+}
@@ -7,6 +7,7 @@
 package netconf
 
 import (
+	"context"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
@@ -49,13 +50,15 @@ func (t *TransportSSH) Close() error {
 	}
 
 	// Close and check for nil. Even though closed, it will retain data for session etc.
-	err := t.SSHClient.Close()
+	if t.SSHClient != nil {
+		err := t.SSHClient.Close()
 
-	if err != nil {
-		return (err)
+		if err != nil {
+			return (err)
+		}
 	}
 
-	err = t.TransportBasicIO.Close()
+	err := t.TransportBasicIO.Close()
 	if err != nil {
 		return (err)
 	}
@@ -73,6 +76,12 @@ func (t *TransportSSH) Close() error {
 // go.crypto/ssh for documentation.  There is a helper function SSHConfigPassword
 // thar returns a ssh.ClientConfig for simple username/password authentication
 func (t *TransportSSH) DialSSH(target string, config *ssh.ClientConfig, port int) error {
+	return t.DialSSHContext(context.Background(), target, config, port)
+}
+
+// DialSSHContext connects and establishes SSH sessions like DialSSH. Cancelling ctx aborts
+// the TCP connect, the SSH handshake and the NETCONF subsystem request.
+func (t *TransportSSH) DialSSHContext(ctx context.Context, target string, config *ssh.ClientConfig, port int) error {
 	if !strings.Contains(target, ":") {
 		sshport := 0
 		if port != 0 {
@@ -83,15 +92,40 @@ func (t *TransportSSH) DialSSH(target string, config *ssh.ClientConfig, port int
 		target = fmt.Sprintf("%s:%d", target, sshport)
 	}
 
-	SSHClient, err := ssh.Dial("tcp", target, config)
+	dialer := net.Dialer{Timeout: config.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", target)
 	if err != nil {
 		return err
 	}
 
-	t.SSHClient = SSHClient
+	// ssh.NewClientConn knows nothing about contexts, so unblock it by closing the socket
+	stop := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stop:
+		}
+	}()
+
+	c, chans, reqs, err := ssh.NewClientConn(conn, target, config)
+	if err == nil {
+		t.SSHClient = ssh.NewClient(c, chans, reqs)
+		err = t.SetupSession()
+	}
+
+	close(stop)
+	<-exited
+
+	if ctx.Err() != nil {
+		conn.Close()
+		return ctx.Err()
+	}
 
-	err = t.SetupSession()
 	if err != nil {
+		conn.Close()
 		return err
 	}
 
@@ -170,11 +204,12 @@ func DialSSHTimeout(target string, config *ssh.ClientConfig, timeout time.Durati
 		return nil, err
 	}
 
+	client := t.SSHClient
 	go func() {
 		ticker := time.NewTicker(timeout / 2)
 		defer ticker.Stop()
 		for range ticker.C {
-			_, _, err := t.SSHClient.Conn.SendRequest("KEEP_ALIVE", true, nil)
+			_, _, err := client.Conn.SendRequest("KEEP_ALIVE", true, nil)
 			if err != nil {
 				return
 			}
@@ -1,13 +1,16 @@
 package netconf
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"net"
 	"time"
 
 	lowlevel "github.com/davedotdev/go-netconf/drivers/ssh/lowlevel"
 	rpc "github.com/davedotdev/go-netconf/rpc"
 	session "github.com/davedotdev/go-netconf/session"
+	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -22,6 +25,21 @@ type DriverSSH struct {
 	SSHConfig *ssh.ClientConfig      // SSH Config
 	Transport *lowlevel.TransportSSH // Transport data
 	Session   *session.Session       // Session data
+	Debug     io.Writer              // Receives a transcript of every NETCONF message when set
+
+	// ForceFraming overrides the negotiated message framing with "eom" or "chunked".
+	// It is only meant for debugging device framing quirks and logs a warning when used.
+	ForceFraming string
+
+	// MaxReplySize caps the bytes read for a single reply, see transport.TransportBasicIO.MaxReplySize
+	MaxReplySize int64
+
+	// HelloTimeout bounds the hello exchange after the SSH connection is up, so an endpoint that accepts the
+	// netconf subsystem but is not a NETCONF server fails fast. session.DefaultHelloTimeout when zero.
+	HelloTimeout time.Duration
+
+	// MessageID generates the message-id of every RPC, e.g. to embed a trace id, rpc.NextMessageID when nil
+	MessageID func() string
 }
 
 // New creates a new instance of DriverSSH
@@ -33,28 +51,44 @@ func New() *DriverSSH {
 	}
 }
 
-// SetDatastore sets the target datastore on the data structure
+// SetDatastore sets the target datastore on the data structure, rejecting any but the rpc.Datastore constants
 func (d *DriverSSH) SetDatastore(ds string) error {
+	err := rpc.Datastore(ds).Validate()
+	if err != nil {
+		return err
+	}
+
 	d.Datastore = ds
 	return nil
 }
 
 // Dial function (call this after New())
 func (d *DriverSSH) Dial() error {
+	return d.DialContext(context.Background())
+}
+
+// DialContext function (call this after New()). Cancelling ctx aborts the TCP connect and SSH handshake.
+func (d *DriverSSH) DialContext(ctx context.Context) error {
 	d.Target = fmt.Sprintf("%s:%d", d.Host, d.Port)
 
-	err := d.Transport.DialSSH(d.Host, d.SSHConfig, d.Port)
+	d.Transport.Debug = d.Debug
+	d.Transport.ForceFraming = d.ForceFraming
+	d.Transport.MaxReplySize = d.MaxReplySize
+
+	err := d.Transport.DialSSHContext(ctx, d.Host, d.SSHConfig, d.Port)
 
 	if err != nil {
 		return err
 	}
 
-	d.Session, err = session.NewSession(d.Transport)
+	d.Session, err = session.NewSessionTimeout(d.Transport, d.HelloTimeout, func() { d.Transport.SSHClient.Close() })
 
 	if err != nil {
 		return err
 	}
 
+	d.Session.MessageID = d.MessageID
+
 	return nil
 }
 
@@ -85,6 +119,9 @@ func (d *DriverSSH) Close() error {
 	// Close the SSH Session if we have one
 	err := d.Session.Close()
 
+	// The SSH connection went with the session, SSHClient no longer hands it out
+	d.Transport.SSHClient = nil
+
 	if err != nil {
 		return err
 	}
@@ -94,7 +131,12 @@ func (d *DriverSSH) Close() error {
 
 // Lock the target datastore
 func (d *DriverSSH) Lock(ds string) (*rpc.RPCReply, error) {
-	reply, err := d.Session.Exec(rpc.MethodLock(ds))
+	method, err := rpc.MethodLockDatastore(rpc.Datastore(ds))
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := d.Session.Exec(method)
 
 	if err != nil {
 		return reply, err
@@ -105,7 +147,12 @@ func (d *DriverSSH) Lock(ds string) (*rpc.RPCReply, error) {
 
 // Unlock the target datastore
 func (d *DriverSSH) Unlock(ds string) (*rpc.RPCReply, error) {
-	reply, err := d.Session.Exec(rpc.MethodUnlock(ds))
+	method, err := rpc.MethodUnlockDatastore(rpc.Datastore(ds))
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := d.Session.Exec(method)
 
 	if err != nil {
 		return reply, err
@@ -125,9 +172,46 @@ func (d *DriverSSH) SendRaw(rawxml string) (*rpc.RPCReply, error) {
 	return reply, nil
 }
 
+// SendRawStream sends a raw XML envelope and returns a reader over the reply as it arrives, for replies too
+// large to buffer. The reply must be read to io.EOF before anything else is sent.
+func (d *DriverSSH) SendRawStream(rawxml string) (io.Reader, error) {
+	return d.Session.ExecStream(rpc.RawMethod(rawxml))
+}
+
+// Subscribe creates a subscription to the event stream, replaying from startTime unless it is zero
+func (d *DriverSSH) Subscribe(stream string, startTime time.Time) error {
+	var start string
+	if !startTime.IsZero() {
+		start = startTime.Format(time.RFC3339Nano)
+	}
+
+	_, err := d.Session.Exec(rpc.MethodCreateSubscription(stream, start))
+
+	return err
+}
+
+// ReceiveNotification blocks until the next notification arrives after Subscribe
+func (d *DriverSSH) ReceiveNotification() (*rpc.Notification, error) {
+	return d.Session.ReceiveNotification()
+}
+
+// ServerCapabilities returns the capabilities the server advertised in its hello, or nil before Dial
+func (d *DriverSSH) ServerCapabilities() []string {
+	if d.Session == nil {
+		return nil
+	}
+
+	return d.Session.ServerCapabilities
+}
+
 // GetConfig requests the contents of a datastore
 func (d *DriverSSH) GetConfig() (*rpc.RPCReply, error) {
-	reply, err := d.Session.Exec(rpc.MethodGetConfig(d.Datastore))
+	method, err := rpc.MethodGetConfigDatastore(rpc.Datastore(d.Datastore))
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := d.Session.Exec(method)
 
 	if err != nil {
 		return reply, err
@@ -135,3 +219,66 @@ func (d *DriverSSH) GetConfig() (*rpc.RPCReply, error) {
 
 	return reply, nil
 }
+
+// SSHClient returns the SSH connection of the dialed driver, or nil before Dial and after Close, so advanced
+// callers can open sessions of their own, e.g. to run shell commands, without authenticating a second
+// connection. This is unsafe: closing the client or exhausting the device's channel limit breaks the NETCONF
+// session, and the client is closed along with the driver.
+func (d *DriverSSH) SSHClient() *ssh.Client {
+	if d.Transport == nil {
+		return nil
+	}
+
+	return d.Transport.SSHClient
+}
+
+// sftpClient opens an SFTP subsystem on the established SSH connection
+func (d *DriverSSH) sftpClient() (*sftp.Client, error) {
+	client := d.SSHClient()
+	if client == nil {
+		return nil, fmt.Errorf("ssh driver is not dialed")
+	}
+
+	return sftp.NewClient(client)
+}
+
+// GetFile copies a file from the device to w over SFTP, reusing the established SSH connection
+func (d *DriverSSH) GetFile(remotePath string, w io.Writer) error {
+	client, err := d.sftpClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	f, err := client.Open(remotePath)
+	if err != nil {
+		return fmt.Errorf("sftp open %s: %v", remotePath, err)
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+
+	return err
+}
+
+// PutFile copies r to a file on the device over SFTP, reusing the established SSH connection
+func (d *DriverSSH) PutFile(r io.Reader, remotePath string) error {
+	client, err := d.sftpClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	f, err := client.Create(remotePath)
+	if err != nil {
+		return fmt.Errorf("sftp create %s: %v", remotePath, err)
+	}
+
+	_, err = io.Copy(f, r)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,329 @@
+package netconf
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	lowlevel "github.com/davedotdev/go-netconf/drivers/ssh/lowlevel"
+	session "github.com/davedotdev/go-netconf/session"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+const testHello = `<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<capabilities>
+<capability>urn:ietf:params:netconf:base:1.0</capability>
+</capabilities>
+<session-id>1</session-id>
+</hello>
+]]>]]>`
+
+// testSSHServer is an in-process SSH server offering the netconf and sftp subsystems
+type testSSHServer struct {
+	ln     net.Listener
+	config *ssh.ServerConfig
+	files  sftp.Handlers
+
+	noHello bool // Accept the netconf subsystem but never send a hello, like a non-NETCONF endpoint
+}
+
+func newTestSSHServer(t *testing.T) *testSSHServer {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate host key: %v", err)
+	}
+
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatalf("failed to create host key signer: %v", err)
+	}
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(c ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			if c.User() == "test" && string(pass) == "test" {
+				return nil, nil
+			}
+			return nil, errors.New("access denied")
+		},
+	}
+	config.AddHostKey(signer)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	s := &testSSHServer{ln: ln, config: config, files: sftp.InMemHandler()}
+	go s.serve()
+
+	return s
+}
+
+// driver returns an SSH driver pointed at the server
+func (s *testSSHServer) driver() *DriverSSH {
+	addr := s.ln.Addr().(*net.TCPAddr)
+
+	d := New()
+	d.Host = addr.IP.String()
+	d.Port = addr.Port
+	d.SSHConfig = lowlevel.SSHConfigPassword("test", "test")
+
+	return d
+}
+
+func (s *testSSHServer) Close() error {
+	return s.ln.Close()
+}
+
+func (s *testSSHServer) serve() {
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			_, chans, reqs, err := ssh.NewServerConn(c, s.config)
+			if err != nil {
+				c.Close()
+				return
+			}
+			go ssh.DiscardRequests(reqs)
+
+			for newChan := range chans {
+				ch, chReqs, err := newChan.Accept()
+				if err != nil {
+					continue
+				}
+				go s.handleSession(ch, chReqs)
+			}
+		}()
+	}
+}
+
+func (s *testSSHServer) handleSession(ch ssh.Channel, reqs <-chan *ssh.Request) {
+	defer ch.Close()
+
+	for req := range reqs {
+		if req.Type != "subsystem" {
+			req.Reply(false, nil)
+			continue
+		}
+
+		var payload struct{ Name string }
+		ssh.Unmarshal(req.Payload, &payload)
+
+		switch payload.Name {
+		case "netconf":
+			req.Reply(true, nil)
+			if !s.noHello {
+				ch.Write([]byte(testHello))
+			}
+			io.Copy(ioutil.Discard, ch)
+			return
+		case "sftp":
+			req.Reply(true, nil)
+			server := sftp.NewRequestServer(ch, s.files)
+			server.Serve()
+			server.Close()
+			return
+		default:
+			req.Reply(false, nil)
+		}
+	}
+}
+
+// slowServer accepts TCP connections but never sends an SSH version banner, stalling the handshake
+func slowServer(t *testing.T) (net.Listener, chan net.Conn) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	conns := make(chan net.Conn, 1)
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns <- c
+		}
+	}()
+
+	return ln, conns
+}
+
+func TestDialContextCancelDuringHandshake(t *testing.T) {
+	ln, conns := slowServer(t)
+	defer ln.Close()
+
+	addr := ln.Addr().(*net.TCPAddr)
+
+	d := New()
+	d.Host = addr.IP.String()
+	d.Port = addr.Port
+	d.SSHConfig = lowlevel.SSHConfigPassword("test", "test")
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		// Only cancel once the TCP connect has completed, so the handshake is in progress
+		c := <-conns
+		defer c.Close()
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- d.DialContext(ctx)
+	}()
+
+	select {
+	case err := <-errCh:
+		if err != context.Canceled {
+			t.Errorf("got error %v, expected %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("DialContext did not return after the context was cancelled")
+	}
+}
+
+func TestFileTransfer(t *testing.T) {
+	s := newTestSSHServer(t)
+	defer s.Close()
+
+	d := s.driver()
+
+	err := d.Dial()
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer d.Close()
+
+	content := []byte("license-key-data\n")
+
+	err = d.PutFile(bytes.NewReader(content), "/license.txt")
+	if err != nil {
+		t.Fatalf("PutFile failed: %v", err)
+	}
+
+	var out bytes.Buffer
+	err = d.GetFile("/license.txt", &out)
+	if err != nil {
+		t.Fatalf("GetFile failed: %v", err)
+	}
+
+	if !bytes.Equal(out.Bytes(), content) {
+		t.Errorf("got %q, expected %q", out.Bytes(), content)
+	}
+
+	err = d.GetFile("/missing.log", &out)
+	if err == nil {
+		t.Error("expected an error reading a missing file")
+	}
+}
+
+func TestFileTransferNotDialed(t *testing.T) {
+	d := New()
+
+	err := d.GetFile("/var/log/messages", ioutil.Discard)
+	if err == nil {
+		t.Error("expected an error before the driver is dialed")
+	}
+}
+
+func TestSSHClient(t *testing.T) {
+	s := newTestSSHServer(t)
+	defer s.Close()
+
+	d := s.driver()
+
+	if d.SSHClient() != nil {
+		t.Error("expected no SSH client before the driver is dialed")
+	}
+
+	err := d.Dial()
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+
+	client := d.SSHClient()
+	if client == nil {
+		d.Close()
+		t.Fatal("expected an SSH client after the driver is dialed")
+	}
+
+	session, err := client.NewSession()
+	if err != nil {
+		d.Close()
+		t.Fatalf("failed to open a session on the SSH client: %v", err)
+	}
+	session.Close()
+
+	d.Close()
+
+	if d.SSHClient() != nil {
+		t.Error("expected no SSH client once the driver is closed")
+	}
+}
+
+func TestDebugTranscript(t *testing.T) {
+	s := newTestSSHServer(t)
+	defer s.Close()
+
+	var transcript bytes.Buffer
+
+	d := s.driver()
+	d.Debug = &transcript
+
+	err := d.Dial()
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer d.Close()
+
+	for _, expected := range []string{"S: <hello", "C: <?xml"} {
+		if !strings.Contains(transcript.String(), expected) {
+			t.Errorf("got transcript %q, expected it to contain %q", transcript.String(), expected)
+		}
+	}
+
+	if strings.Contains(transcript.String(), "test") {
+		t.Errorf("got transcript %q, expected no SSH credentials", transcript.String())
+	}
+}
+
+func TestHelloTimeout(t *testing.T) {
+	s := newTestSSHServer(t)
+	defer s.Close()
+	s.noHello = true
+
+	d := s.driver()
+	d.HelloTimeout = 100 * time.Millisecond
+
+	start := time.Now()
+	err := d.Dial()
+	if err == nil {
+		d.Close()
+		t.Fatal("expected dialing a server that sends no hello to fail")
+	}
+
+	if !errors.Is(err, session.ErrHelloTimeout) {
+		t.Errorf("got error %v, expected session.ErrHelloTimeout", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("dial took %v, expected it to give up after the hello timeout", elapsed)
+	}
+}
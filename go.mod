@@ -4,5 +4,7 @@ go 1.14
 
 require (
 	github.com/google/go-cmp v0.4.1
-	golang.org/x/crypto v0.0.0-20200604202706-70a84ac30bf9
+	github.com/pkg/sftp v1.12.0
+	github.com/zalando/go-keyring v0.1.0
+	golang.org/x/crypto v0.0.0-20200820211705-5c72a883971a
 )
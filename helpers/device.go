@@ -0,0 +1,82 @@
+package helpers
+
+import (
+	"strings"
+
+	driver "github.com/davedotdev/go-netconf/drivers/driver"
+)
+
+// Device types returned by DetectDeviceType
+const (
+	DeviceJunos   = "junos"
+	DeviceIOSXR   = "iosxr"
+	DeviceIOSXE   = "iosxe"
+	DeviceSROS    = "sros"
+	DeviceEOS     = "eos"
+	DeviceUnknown = "unknown"
+)
+
+// junosProbeStr is answered with <software-information> by Junos only
+const junosProbeStr = `<get-software-information/>`
+
+// capabilityReporter is implemented by drivers able to report the capabilities the server advertised in its hello
+type capabilityReporter interface {
+	ServerCapabilities() []string
+}
+
+// deviceCapabilities maps capability prefixes characteristic of a vendor's NETCONF server to its device type
+var deviceCapabilities = []struct {
+	prefix string
+	device string
+}{
+	{"http://xml.juniper.net/", DeviceJunos},
+	{"http://cisco.com/ns/yang/Cisco-IOS-XR-", DeviceIOSXR},
+	{"http://cisco.com/ns/yang/Cisco-IOS-XE-", DeviceIOSXE},
+	{"urn:nokia.com:sros:", DeviceSROS},
+	{"urn:alcatel-lucent.com:sros:", DeviceSROS},
+	{"http://arista.com/yang/", DeviceEOS},
+}
+
+// deviceFromCapabilities classifies a device by its capabilities, returning DeviceUnknown when none are characteristic
+func deviceFromCapabilities(capabilities []string) string {
+	for _, c := range capabilities {
+		for _, dc := range deviceCapabilities {
+			if strings.HasPrefix(c, dc.prefix) {
+				return dc.device
+			}
+		}
+	}
+	return DeviceUnknown
+}
+
+// DetectDeviceType dials d and classifies the device from the capabilities it advertised, so multi-vendor
+// tools can pick the matching helper package. When the capabilities are not conclusive a Junos
+// <get-software-information/> probe is tried. DeviceUnknown is returned, rather than an error, when
+// the device can not be classified; errors are only returned when the driver fails to dial or close.
+func DetectDeviceType(d driver.Driver) (string, error) {
+	err := d.Dial()
+	if err != nil {
+		return "", err
+	}
+
+	var capabilities []string
+	if cr, ok := d.(capabilityReporter); ok {
+		capabilities = cr.ServerCapabilities()
+	}
+
+	device := deviceFromCapabilities(capabilities)
+
+	if device == DeviceUnknown {
+		reply, err := d.SendRaw(junosProbeStr)
+		if err == nil && strings.Contains(reply.Data, "<software-information") {
+			device = DeviceJunos
+		}
+	}
+
+	err = d.Close()
+	if err != nil {
+		return "", err
+	}
+
+	return device, nil
+}
@@ -0,0 +1,82 @@
+package helpers
+
+import (
+	"testing"
+
+	testdriver "github.com/davedotdev/go-netconf/helpers/internal/testdriver"
+)
+
+func TestDetectDeviceType(t *testing.T) {
+	const base = "urn:ietf:params:netconf:base:1.0"
+
+	tt := []struct {
+		name         string
+		capabilities []string
+		reply        string
+		expected     string
+		probed       bool
+	}{
+		{
+			name:         "junos",
+			capabilities: []string{base, "http://xml.juniper.net/netconf/junos/1.0", "http://xml.juniper.net/dmi/system/1.0"},
+			expected:     DeviceJunos,
+		},
+		{
+			name:         "iosxr",
+			capabilities: []string{base, "http://cisco.com/ns/yang/Cisco-IOS-XR-ifmgr-cfg?module=Cisco-IOS-XR-ifmgr-cfg&revision=2017-09-07"},
+			expected:     DeviceIOSXR,
+		},
+		{
+			name:         "iosxe",
+			capabilities: []string{base, "http://cisco.com/ns/yang/Cisco-IOS-XE-native?module=Cisco-IOS-XE-native&revision=2019-11-01"},
+			expected:     DeviceIOSXE,
+		},
+		{
+			name:         "sros",
+			capabilities: []string{base, "urn:nokia.com:sros:ns:yang:sr:conf?module=nokia-conf&revision=2020-03-12"},
+			expected:     DeviceSROS,
+		},
+		{
+			name:         "eos",
+			capabilities: []string{base, "http://arista.com/yang/openconfig/interfaces/augments?module=arista-intf-augments&revision=2019-11-01"},
+			expected:     DeviceEOS,
+		},
+		{
+			name:         "junos probe",
+			capabilities: []string{base},
+			reply:        `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><software-information><host-name>r1</host-name></software-information></rpc-reply>`,
+			expected:     DeviceJunos,
+			probed:       true,
+		},
+		{
+			name:         "unknown",
+			capabilities: []string{base},
+			reply:        `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><rpc-error><error-type>protocol</error-type><error-tag>operation-not-supported</error-tag><error-severity>error</error-severity></rpc-error></rpc-reply>`,
+			expected:     DeviceUnknown,
+			probed:       true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			fd := testdriver.New()
+			fd.Capabilities = tc.capabilities
+			if tc.reply != "" {
+				fd.Replies = []string{tc.reply}
+			}
+
+			device, err := DetectDeviceType(fd)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if device != tc.expected {
+				t.Errorf("got %s, expected %s", device, tc.expected)
+			}
+
+			if probed := len(fd.Sent) > 0; probed != tc.probed {
+				t.Errorf("got probed %t, expected %t", probed, tc.probed)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+// Package eos_helpers wraps a driver with the RFC 6241 operations Arista EOS expects.
+//
+// EOS accepts OpenConfig and EOS native YANG models over NETCONF. Changes are made in the candidate
+// datastore, backed by an EOS configuration session, and applied with a standard commit. EOS also
+// allows editing the running datastore directly, in which case changes apply immediately and there
+// is nothing to commit.
+package eos_helpers
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	driver "github.com/davedotdev/go-netconf/drivers/driver"
+	helpers "github.com/davedotdev/go-netconf/helpers"
+	rpc "github.com/davedotdev/go-netconf/rpc"
+)
+
+// Namespaces of the OpenConfig models EOS implements that configuration is most often rooted at
+const (
+	NamespaceOCInterfaces       = "http://openconfig.net/yang/interfaces"
+	NamespaceOCSystem           = "http://openconfig.net/yang/system"
+	NamespaceOCNetworkInstances = "http://openconfig.net/yang/network-instance"
+	NamespaceOCRoutingPolicy    = "http://openconfig.net/yang/routing-policy"
+	NamespaceOCLLDP             = "http://openconfig.net/yang/lldp"
+	NamespaceOCACL              = "http://openconfig.net/yang/acl"
+)
+
+const openConfigStr = `<%s xmlns="%s">%s</%s>`
+
+// GoNCClient satisfies the vendor neutral client interface
+var _ helpers.NCClient = (*GoNCClient)(nil)
+
+// GoNCClient type for storing data and wrapping functions
+type GoNCClient struct {
+	helpers.Session
+
+	// Datastore edits are made in, candidate unless set to running. Edits to running apply
+	// immediately and SendCommit does nothing.
+	Datastore string
+}
+
+// NewClient returns a client using the supplied driver, which must not be dialed yet. The client is a
+// *GoNCClient, for the EOS specific methods and the Datastore setting.
+func NewClient(d driver.Driver) helpers.NCClient {
+	g := &GoNCClient{}
+	g.Driver = d
+
+	return g
+}
+
+// datastore returns the datastore edits are made in
+func (g *GoNCClient) datastore() rpc.Datastore {
+	if g.Datastore == "" {
+		return rpc.DatastoreCandidate
+	}
+	return rpc.Datastore(g.Datastore)
+}
+
+// edit loads config into the datastore and, for the candidate, optionally commits it.
+// The candidate is discarded if either step fails so the configuration session does not linger.
+func (g *GoNCClient) edit(config string, commit bool) (string, error) {
+	g.Lock.Lock()
+	defer g.Lock.Unlock()
+
+	ds := g.datastore()
+
+	edit, err := rpc.MethodEditConfigDatastore(ds, config)
+	if err != nil {
+		return "", err
+	}
+
+	err = g.Begin()
+	if err != nil {
+		return "", err
+	}
+
+	reply, err := g.Driver.SendRaw(edit.MarshalMethod())
+	if err == nil && commit && ds == rpc.DatastoreCandidate {
+		_, err = g.Driver.SendRaw(rpc.MethodCommit().MarshalMethod())
+	}
+
+	if err != nil {
+		var errDiscard error
+		if ds == rpc.DatastoreCandidate {
+			_, errDiscard = g.Driver.SendRaw(rpc.MethodDiscardChanges().MarshalMethod())
+		}
+		errInternal := g.End()
+		return "", fmt.Errorf("driver error: %+v, discard error: %+v, driver close error: %+v", err, errDiscard, errInternal)
+	}
+
+	err = g.End()
+	if err != nil {
+		return "", fmt.Errorf("driver close error: %+s", err)
+	}
+
+	return reply.Data, nil
+}
+
+// SendRawConfig merges the YANG modelled netconfcall into the datastore with edit-config
+func (g *GoNCClient) SendRawConfig(netconfcall string, commit bool) (string, error) {
+	return g.edit(netconfcall, commit)
+}
+
+// SendOpenConfig wraps config in the root element of an OpenConfig model, e.g. interfaces and
+// NamespaceOCInterfaces, and merges it into the datastore
+func (g *GoNCClient) SendOpenConfig(root string, namespace string, config string, commit bool) (string, error) {
+	return g.edit(fmt.Sprintf(openConfigStr, root, namespace, config, root), commit)
+}
+
+// SendInterfaces merges config, the content of the OpenConfig <interfaces> container, into the datastore
+func (g *GoNCClient) SendInterfaces(config string, commit bool) (string, error) {
+	return g.SendOpenConfig("interfaces", NamespaceOCInterfaces, config, commit)
+}
+
+// SendSystem merges config, the content of the OpenConfig <system> container, into the datastore
+func (g *GoNCClient) SendSystem(config string, commit bool) (string, error) {
+	return g.SendOpenConfig("system", NamespaceOCSystem, config, commit)
+}
+
+// SendTransaction marshals obj and merges it into the datastore.
+// EOS has no apply-groups, so id is not used to scope the change.
+func (g *GoNCClient) SendTransaction(id string, obj interface{}, commit bool) error {
+	config, err := xml.Marshal(obj)
+	if err != nil {
+		return err
+	}
+
+	_, err = g.edit(string(config), commit)
+
+	return err
+}
+
+// SendCommit commits the candidate datastore. Edits made to running are already applied.
+func (g *GoNCClient) SendCommit() error {
+	if g.datastore() != rpc.DatastoreCandidate {
+		return nil
+	}
+
+	g.Lock.Lock()
+	defer g.Lock.Unlock()
+
+	err := g.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = g.Driver.SendRaw(rpc.MethodCommit().MarshalMethod())
+	if err != nil {
+		errInternal := g.End()
+		return fmt.Errorf("driver error: %+v, driver close error: %+v", err, errInternal)
+	}
+
+	return g.End()
+}
@@ -0,0 +1,119 @@
+package eos_helpers
+
+import (
+	"encoding/xml"
+	"reflect"
+	"testing"
+
+	testdriver "github.com/davedotdev/go-netconf/helpers/internal/testdriver"
+)
+
+func TestSendInterfaces(t *testing.T) {
+	fd := testdriver.New()
+	g := NewClient(fd).(*GoNCClient)
+
+	_, err := g.SendInterfaces(`<interface><name>Ethernet1</name><config><name>Ethernet1</name><description>uplink</description></config></interface>`, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(fd.Sent) != 2 || fd.Sent[1] != "<commit/>" {
+		t.Fatalf("got RPCs %q, expected an edit-config and a commit", fd.Sent)
+	}
+
+	var edit struct {
+		Target struct {
+			Candidate *struct{} `xml:"candidate"`
+		} `xml:"target"`
+		Interfaces struct {
+			XMLName    xml.Name
+			Interfaces []struct {
+				XMLName     xml.Name
+				Description struct {
+					XMLName xml.Name
+					Value   string `xml:",chardata"`
+				} `xml:"config>description"`
+			} `xml:"interface"`
+		} `xml:"config>interfaces"`
+	}
+	err = xml.Unmarshal([]byte(fd.Sent[0]), &edit)
+	if err != nil {
+		t.Fatalf("edit-config is not well-formed: %v", err)
+	}
+
+	if edit.Target.Candidate == nil {
+		t.Errorf("got %q, expected the candidate to be edited", fd.Sent[0])
+	}
+
+	expected := xml.Name{Space: NamespaceOCInterfaces, Local: "interfaces"}
+	if edit.Interfaces.XMLName != expected {
+		t.Errorf("got %v, expected %v", edit.Interfaces.XMLName, expected)
+	}
+
+	if len(edit.Interfaces.Interfaces) != 1 {
+		t.Fatalf("got %d interfaces, expected 1", len(edit.Interfaces.Interfaces))
+	}
+
+	// Children inherit the default namespace of the OpenConfig root
+	description := edit.Interfaces.Interfaces[0].Description
+	if description.XMLName.Space != NamespaceOCInterfaces || description.Value != "uplink" {
+		t.Errorf("got %v %q, expected uplink in %s", description.XMLName, description.Value, NamespaceOCInterfaces)
+	}
+}
+
+func TestSendSystemRunning(t *testing.T) {
+	fd := testdriver.New()
+	g := NewClient(fd).(*GoNCClient)
+	g.Datastore = "running"
+
+	_, err := g.SendSystem(`<config><hostname>eos-1</hostname></config>`, true)
+	if err == nil {
+		err = g.SendCommit()
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		`<edit-config><target><running/></target><config><system xmlns="http://openconfig.net/yang/system"><config><hostname>eos-1</hostname></config></system></config></edit-config>`,
+	}
+	if !reflect.DeepEqual(fd.Sent, expected) {
+		t.Errorf("got RPCs %q, expected %q", fd.Sent, expected)
+	}
+}
+
+func TestSendRawConfigDiscardsOnError(t *testing.T) {
+	fd := testdriver.New(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<rpc-error>
+<error-type>application</error-type>
+<error-tag>invalid-value</error-tag>
+<error-severity>error</error-severity>
+<error-message>invalid interface name</error-message>
+</rpc-error>
+</rpc-reply>`)
+	g := NewClient(fd).(*GoNCClient)
+
+	_, err := g.SendRawConfig("<interfaces/>", true)
+	if err == nil {
+		t.Fatal("expected an error from the rejected edit-config")
+	}
+
+	if len(fd.Sent) != 2 || fd.Sent[1] != "<discard-changes/>" {
+		t.Errorf("got RPCs %q, expected the edit-config followed by discard-changes", fd.Sent)
+	}
+}
+
+func TestSendRawConfigUnknownDatastore(t *testing.T) {
+	fd := testdriver.New()
+	g := NewClient(fd).(*GoNCClient)
+	g.Datastore = "runing"
+
+	_, err := g.SendRawConfig("<interfaces/>", false)
+	if err == nil {
+		t.Fatal("expected an error for the misspelt datastore")
+	}
+
+	if len(fd.Sent) != 0 {
+		t.Errorf("got RPCs %q, expected none to be sent", fd.Sent)
+	}
+}
@@ -0,0 +1,53 @@
+package helpers
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultFanOutWorkers is how many clients FanOut configures at once when no worker count is given
+const DefaultFanOutWorkers = 10
+
+// FanOut sends the same transaction to every client concurrently with SendTransaction, configuring at most
+// workers (DefaultFanOutWorkers when zero or less) clients at once. It returns the outcome for every client,
+// nil on success. Once ctx is cancelled no further client is started and those left over get ctx.Err(),
+// transactions already in flight run to completion. Clients are map keys, so must be comparable, as the
+// pointers returned by the helper packages are.
+func FanOut(ctx context.Context, clients []NCClient, workers int, id string, obj interface{}, commit bool) map[NCClient]error {
+	if workers <= 0 {
+		workers = DefaultFanOutWorkers
+	}
+
+	results := make(map[NCClient]error, len(clients))
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	jobs := make(chan NCClient)
+
+	for i := 0; i < workers && i < len(clients); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for c := range jobs {
+				err := ctx.Err()
+				if err == nil {
+					err = c.SendTransaction(id, obj, commit)
+				}
+
+				mu.Lock()
+				results[c] = err
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, c := range clients {
+		jobs <- c
+	}
+	close(jobs)
+
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,98 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeClient implements NCClient, failing every transaction with err when set
+type fakeClient struct {
+	err      error
+	sent     int32
+	inFlight *int32 // Shared between clients to track how many transactions run at once
+	peak     *int32
+}
+
+func (f *fakeClient) Dial() error                                            { return nil }
+func (f *fakeClient) Close() error                                           { return nil }
+func (f *fakeClient) SendCommit() error                                      { return nil }
+func (f *fakeClient) SendRawConfig(call string, commit bool) (string, error) { return "", nil }
+
+func (f *fakeClient) SendTransaction(id string, obj interface{}, commit bool) error {
+	atomic.AddInt32(&f.sent, 1)
+
+	if f.inFlight != nil {
+		n := atomic.AddInt32(f.inFlight, 1)
+		defer atomic.AddInt32(f.inFlight, -1)
+
+		for {
+			peak := atomic.LoadInt32(f.peak)
+			if n <= peak || atomic.CompareAndSwapInt32(f.peak, peak, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return f.err
+}
+
+func TestFanOut(t *testing.T) {
+	var inFlight, peak int32
+
+	failure := errors.New("commit failed")
+
+	var clients []NCClient
+	for i := 0; i < 6; i++ {
+		clients = append(clients, &fakeClient{inFlight: &inFlight, peak: &peak})
+	}
+	failing := &fakeClient{err: failure, inFlight: &inFlight, peak: &peak}
+	clients = append(clients, failing)
+
+	results := FanOut(context.Background(), clients, 3, "test-group", struct{}{}, true)
+
+	if len(results) != len(clients) {
+		t.Fatalf("got %d results, expected one for each of the %d clients", len(results), len(clients))
+	}
+
+	for _, c := range clients {
+		err, ok := results[c]
+		switch {
+		case !ok:
+			t.Error("got no result for a client")
+		case c == failing && !errors.Is(err, failure):
+			t.Errorf("got error %v for the failing client, expected %v", err, failure)
+		case c != failing && err != nil:
+			t.Errorf("got error %v, expected the transaction to succeed", err)
+		}
+
+		if sent := c.(*fakeClient).sent; sent != 1 {
+			t.Errorf("got %d transactions sent to a client, expected 1", sent)
+		}
+	}
+
+	if peak > 3 {
+		t.Errorf("got %d transactions in flight at once, expected at most 3 workers", peak)
+	}
+}
+
+func TestFanOutCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	clients := []NCClient{&fakeClient{}, &fakeClient{}}
+
+	results := FanOut(ctx, clients, 0, "test-group", struct{}{}, true)
+
+	for _, c := range clients {
+		if !errors.Is(results[c], context.Canceled) {
+			t.Errorf("got error %v, expected %v", results[c], context.Canceled)
+		}
+		if c.(*fakeClient).sent != 0 {
+			t.Error("expected no transaction to be sent after the context was cancelled")
+		}
+	}
+}
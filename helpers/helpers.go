@@ -0,0 +1,19 @@
+// Package helpers holds what the vendor specific helper packages have in common.
+package helpers
+
+import "errors"
+
+// ErrSessionClosed is returned by operations on a client after Close
+var ErrSessionClosed = errors.New("netconf client is closed")
+
+// NCClient is the set of operations every vendor helper client provides.
+//
+// Each operation dials and closes a session of its own, unless Dial has been called, in which
+// case operations share that session until Close.
+type NCClient interface {
+	Dial() error
+	Close() error
+	SendCommit() error
+	SendRawConfig(netconfcall string, commit bool) (string, error)
+	SendTransaction(id string, obj interface{}, commit bool) error
+}
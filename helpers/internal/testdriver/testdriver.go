@@ -0,0 +1,89 @@
+// Package testdriver provides Driver, the fake driver the helpers tests share. It records every RPC sent
+// and replies from a queue, without the exact request matching of the mock driver's scripts.
+package testdriver
+
+import (
+	"context"
+
+	rpc "github.com/davedotdev/go-netconf/rpc"
+)
+
+// OKReply is the reply Driver falls back to once its queue is empty
+const OKReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><ok/></rpc-reply>`
+
+// Driver implements driver.Driver, recording every RPC sent and replying from a queue
+type Driver struct {
+	Sent         []string
+	Replies      []string
+	Dials        int
+	Closes       int
+	Capabilities []string            // Returned by ServerCapabilities, nil like a driver unable to report them
+	Delay        func(rawxml string) // Called before replying, to simulate a slow device
+}
+
+// New returns a driver replying with replies in order
+func New(replies ...string) *Driver {
+	return &Driver{Replies: replies}
+}
+
+// Lock sends the lock of the datastore ds
+func (f *Driver) Lock(ds string) (*rpc.RPCReply, error) {
+	return f.SendRaw(rpc.MethodLock(ds).MarshalMethod())
+}
+
+// Unlock sends the unlock of the datastore ds
+func (f *Driver) Unlock(ds string) (*rpc.RPCReply, error) {
+	return f.SendRaw(rpc.MethodUnlock(ds).MarshalMethod())
+}
+
+// Close counts the close
+func (f *Driver) Close() error {
+	f.Closes++
+	return nil
+}
+
+// Dial counts the dial, there is nothing to connect to
+func (f *Driver) Dial() error {
+	f.Dials++
+	return nil
+}
+
+// DialContext is Dial, failing once ctx is done
+func (f *Driver) DialContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return f.Dial()
+}
+
+// DialTimeout is Dial
+func (f *Driver) DialTimeout() error {
+	return f.Dial()
+}
+
+// SendRaw records rawxml and pops the next queued reply, falling back to OKReply when the queue is empty
+func (f *Driver) SendRaw(rawxml string) (*rpc.RPCReply, error) {
+	f.Sent = append(f.Sent, rawxml)
+
+	if f.Delay != nil {
+		f.Delay(rawxml)
+	}
+
+	reply := OKReply
+	if len(f.Replies) > 0 {
+		reply, f.Replies = f.Replies[0], f.Replies[1:]
+	}
+
+	return rpc.NewRPCReply([]byte(reply), false)
+}
+
+// ServerCapabilities returns Capabilities
+func (f *Driver) ServerCapabilities() []string {
+	return f.Capabilities
+}
+
+// GetConfig sends a get-config of running
+func (f *Driver) GetConfig() (*rpc.RPCReply, error) {
+	return f.SendRaw(rpc.MethodGetConfig("running").MarshalMethod())
+}
@@ -0,0 +1,113 @@
+// Package iosxr_helpers wraps a driver with the RFC 6241 operations Cisco IOS-XR expects.
+//
+// IOS-XR has no notion of the Junos load-configuration RPC or apply-groups. Configuration is
+// edited in the candidate datastore with edit-config and applied with a standard commit. Payloads
+// must carry the namespaces of the IOS-XR YANG models they target.
+package iosxr_helpers
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+
+	driver "github.com/davedotdev/go-netconf/drivers/driver"
+	helpers "github.com/davedotdev/go-netconf/helpers"
+	rpc "github.com/davedotdev/go-netconf/rpc"
+)
+
+// cliNamespace is the namespace of the IOS-XR model accepting configuration as CLI text
+const cliNamespace = "http://cisco.com/ns/yang/Cisco-IOS-XR-cli-cfg"
+
+const cliConfigStr = `<cli xmlns="%s">%s</cli>`
+
+// GoNCClient satisfies the vendor neutral client interface
+var _ helpers.NCClient = (*GoNCClient)(nil)
+
+// GoNCClient type for storing data and wrapping functions
+type GoNCClient struct {
+	helpers.Session
+}
+
+// NewClient returns a client using the supplied driver, which must not be dialed yet. The client is a
+// *GoNCClient, for the IOS XR specific methods.
+func NewClient(d driver.Driver) helpers.NCClient {
+	g := &GoNCClient{}
+	g.Driver = d
+
+	return g
+}
+
+// editCandidate loads config into the candidate and optionally commits it.
+// The candidate is discarded if either step fails so a later session does not inherit the change.
+func (g *GoNCClient) editCandidate(config string, commit bool) (string, error) {
+	g.Lock.Lock()
+	defer g.Lock.Unlock()
+
+	err := g.Begin()
+	if err != nil {
+		return "", err
+	}
+
+	reply, err := g.Driver.SendRaw(rpc.MethodEditConfig("candidate", config).MarshalMethod())
+	if err == nil && commit {
+		_, err = g.Driver.SendRaw(rpc.MethodCommit().MarshalMethod())
+	}
+
+	if err != nil {
+		_, errDiscard := g.Driver.SendRaw(rpc.MethodDiscardChanges().MarshalMethod())
+		errInternal := g.End()
+		return "", fmt.Errorf("driver error: %+v, discard error: %+v, driver close error: %+v", err, errDiscard, errInternal)
+	}
+
+	err = g.End()
+	if err != nil {
+		return "", fmt.Errorf("driver close error: %+s", err)
+	}
+
+	return reply.Data, nil
+}
+
+// SendRawConfig merges the YANG modelled netconfcall into the candidate with edit-config
+func (g *GoNCClient) SendRawConfig(netconfcall string, commit bool) (string, error) {
+	return g.editCandidate(netconfcall, commit)
+}
+
+// SendCLIConfig loads configuration expressed as IOS-XR CLI text through the CLI model
+func (g *GoNCClient) SendCLIConfig(cli string, commit bool) (string, error) {
+	var escaped bytes.Buffer
+	xml.EscapeText(&escaped, []byte(cli))
+
+	return g.editCandidate(fmt.Sprintf(cliConfigStr, cliNamespace, escaped.String()), commit)
+}
+
+// SendTransaction marshals obj and merges it into the candidate.
+// IOS-XR has no apply-groups, so id is not used to scope the change.
+func (g *GoNCClient) SendTransaction(id string, obj interface{}, commit bool) error {
+	config, err := xml.Marshal(obj)
+	if err != nil {
+		return err
+	}
+
+	_, err = g.editCandidate(string(config), commit)
+
+	return err
+}
+
+// SendCommit commits the candidate datastore
+func (g *GoNCClient) SendCommit() error {
+	g.Lock.Lock()
+	defer g.Lock.Unlock()
+
+	err := g.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = g.Driver.SendRaw(rpc.MethodCommit().MarshalMethod())
+	if err != nil {
+		errInternal := g.End()
+		return fmt.Errorf("driver error: %+v, driver close error: %+v", err, errInternal)
+	}
+
+	return g.End()
+}
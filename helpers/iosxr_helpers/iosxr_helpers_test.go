@@ -0,0 +1,119 @@
+package iosxr_helpers
+
+import (
+	"encoding/xml"
+	"reflect"
+	"testing"
+
+	testdriver "github.com/davedotdev/go-netconf/helpers/internal/testdriver"
+)
+
+type hostname struct {
+	XMLName xml.Name `xml:"http://cisco.com/ns/yang/Cisco-IOS-XR-shellutil-cfg host-names"`
+	Name    string   `xml:"host-name"`
+}
+
+func TestSendTransaction(t *testing.T) {
+	fd := testdriver.New()
+	g := NewClient(fd).(*GoNCClient)
+
+	err := g.SendTransaction("", hostname{Name: "xr-1"}, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		`<edit-config><target><candidate/></target><config><host-names xmlns="http://cisco.com/ns/yang/Cisco-IOS-XR-shellutil-cfg"><host-name>xr-1</host-name></host-names></config></edit-config>`,
+		`<commit/>`,
+	}
+	if !reflect.DeepEqual(fd.Sent, expected) {
+		t.Errorf("got RPCs %q, expected %q", fd.Sent, expected)
+	}
+}
+
+func TestSendCLIConfig(t *testing.T) {
+	fd := testdriver.New()
+	g := NewClient(fd).(*GoNCClient)
+
+	_, err := g.SendCLIConfig("hostname xr-1\ninterface Loopback0\n description <lab>", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		`<edit-config><target><candidate/></target><config><cli xmlns="http://cisco.com/ns/yang/Cisco-IOS-XR-cli-cfg">hostname xr-1&#xA;interface Loopback0&#xA; description &lt;lab&gt;</cli></config></edit-config>`,
+	}
+	if !reflect.DeepEqual(fd.Sent, expected) {
+		t.Errorf("got RPCs %q, expected %q", fd.Sent, expected)
+	}
+}
+
+func TestSendRawConfigDiscardsOnError(t *testing.T) {
+	fd := testdriver.New(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<rpc-error>
+<error-type>application</error-type>
+<error-tag>invalid-value</error-tag>
+<error-severity>error</error-severity>
+<error-message>'YANG framework' detected the 'fatal' condition 'Operation failed'</error-message>
+</rpc-error>
+</rpc-reply>`)
+	g := NewClient(fd).(*GoNCClient)
+
+	_, err := g.SendRawConfig("<host-names/>", true)
+	if err == nil {
+		t.Fatal("expected an error from the rejected edit-config")
+	}
+
+	if len(fd.Sent) != 2 || fd.Sent[1] != "<discard-changes/>" {
+		t.Errorf("got RPCs %q, expected the edit-config followed by discard-changes", fd.Sent)
+	}
+
+	if fd.Closes != 1 {
+		t.Errorf("got %d closes, expected 1", fd.Closes)
+	}
+}
+
+func TestSendCommit(t *testing.T) {
+	fd := testdriver.New()
+	g := NewClient(fd).(*GoNCClient)
+
+	err := g.SendCommit()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(fd.Sent, []string{"<commit/>"}) {
+		t.Errorf("got RPCs %q, expected a single commit", fd.Sent)
+	}
+}
+
+func TestManagedSession(t *testing.T) {
+	fd := testdriver.New()
+	g := NewClient(fd).(*GoNCClient)
+
+	err := g.Dial()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = g.SendRawConfig("<host-names/>", false)
+	if err == nil {
+		err = g.SendCommit()
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if fd.Closes != 0 {
+		t.Errorf("got %d closes, expected the session to be held open", fd.Closes)
+	}
+
+	err = g.Close()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if fd.Closes != 1 {
+		t.Errorf("got %d closes, expected Close to end the session", fd.Closes)
+	}
+}
@@ -0,0 +1,145 @@
+package junos_helpers
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const compareStr = `<get-configuration compare="rollback" rollback="0" format="text"/>
+`
+
+// parseCompare returns the text diff of a compare reply, empty when the candidate matches the committed configuration
+func parseCompare(data string) (string, error) {
+	wrapper := struct {
+		Output string `xml:"configuration-information>configuration-output"`
+	}{}
+
+	err := xml.Unmarshal([]byte("<rpc-reply>"+data+"</rpc-reply>"), &wrapper)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(wrapper.Output), nil
+}
+
+// ErrCandidateDirty is returned when GuardDirtyCandidate is set and the candidate already holds uncommitted changes
+var ErrCandidateDirty = errors.New("candidate has uncommitted changes")
+
+// checkCandidateClean returns ErrCandidateDirty, along with the diff, when the candidate differs from the
+// committed configuration on the open session
+func (g *GoNCClient) checkCandidateClean() error {
+	reply, err := g.Driver.SendRaw(compareStr)
+	if err != nil {
+		return fmt.Errorf("driver error: %w", err)
+	}
+
+	diff, err := parseCompare(reply.Data)
+	if err != nil {
+		return err
+	}
+
+	if diff != "" {
+		return fmt.Errorf("%w:\n%s", ErrCandidateDirty, diff)
+	}
+
+	return nil
+}
+
+// HasPendingChanges reports whether the candidate differs from the committed configuration, and the text
+// diff between them, without committing or discarding anything
+func (g *GoNCClient) HasPendingChanges() (bool, string, error) {
+	reply, err := g.sendRaw(compareStr)
+	if err != nil {
+		return false, "", err
+	}
+
+	diff, err := parseCompare(reply.Data)
+	if err != nil {
+		return false, "", err
+	}
+
+	return diff != "", diff, nil
+}
+
+// ApplyIdempotent loads config into the candidate, replacing the apply group id if one is given, and
+// only commits when the candidate then differs from the committed configuration. Re-applying an
+// unchanged configuration therefore leaves no entry in the commit history.
+// It reports whether a commit was made.
+func (g *GoNCClient) ApplyIdempotent(id string, config string) (bool, error) {
+	if id != "" {
+		err := validateGroupName(id)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	var changed bool
+
+	err := g.withSession(func() error {
+		var err error
+
+		changed, err = g.apply(id, config)
+		if err != nil {
+			return fmt.Errorf("driver error: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return changed, nil
+}
+
+// apply loads, compares and commits on the open session, discarding the candidate when nothing changed
+func (g *GoNCClient) apply(id string, config string) (bool, error) {
+	if id != "" {
+		edit, err := g.deleteGroupsEdit(datastoreCandidate, id)
+		if err != nil {
+			return false, err
+		}
+
+		_, err = g.Driver.SendRaw(edit)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	reply, err := g.Driver.SendRaw(buildLoadConfiguration(LoadMerge, FormatXML, config))
+	if err != nil {
+		return false, err
+	}
+
+	// Never commit a partially loaded configuration
+	err = checkLoadResults(reply.Data)
+	if err != nil {
+		return false, err
+	}
+
+	g.lastEdit = datastoreCandidate
+
+	reply, err = g.Driver.SendRaw(compareStr)
+	if err != nil {
+		return false, err
+	}
+
+	diff, err := parseCompare(reply.Data)
+	if err != nil {
+		return false, err
+	}
+
+	if diff == "" {
+		_, err = g.Driver.SendRaw(discardChangesStr)
+		return false, err
+	}
+
+	_, err = g.commit()
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
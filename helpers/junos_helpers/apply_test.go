@@ -0,0 +1,101 @@
+package junos_helpers
+
+import (
+	"strings"
+	"testing"
+)
+
+const emptyCompareReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<configuration-information>
+<configuration-output>
+</configuration-output>
+</configuration-information>
+</rpc-reply>`
+
+const diffCompareReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<configuration-information>
+<configuration-output>
+[edit groups test-group system]
+-  host-name r1;
++  host-name r2;
+</configuration-output>
+</configuration-information>
+</rpc-reply>`
+
+func TestApplyIdempotent(t *testing.T) {
+	tt := []struct {
+		name    string
+		compare string
+		changed bool
+		last    string
+	}{
+		{name: "unchanged", compare: emptyCompareReply, changed: false, last: discardChangesStr},
+		{name: "changed", compare: diffCompareReply, changed: true, last: commitStr},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g, fd := newTestClient(okReply, loadSuccessReply, tc.compare)
+
+			changed, err := g.ApplyIdempotent("test-group", validateConfig)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if changed != tc.changed {
+				t.Errorf("got changed %t, expected %t", changed, tc.changed)
+			}
+
+			if len(fd.Sent) != 4 {
+				t.Fatalf("got %d RPCs sent, expected 4", len(fd.Sent))
+			}
+			if !strings.Contains(fd.Sent[2], `compare="rollback"`) {
+				t.Errorf("got %q, expected a compare", fd.Sent[2])
+			}
+			for _, sent := range fd.Sent[:3] {
+				if sent == commitStr {
+					t.Errorf("commit sent before the compare")
+				}
+			}
+			if fd.Sent[3] != tc.last {
+				t.Errorf("got %q, expected %q", fd.Sent[3], tc.last)
+			}
+		})
+	}
+}
+
+func TestHasPendingChanges(t *testing.T) {
+	tt := []struct {
+		name    string
+		compare string
+		pending bool
+		diff    string
+	}{
+		{name: "diff", compare: diffCompareReply, pending: true, diff: "[edit groups test-group system]\n-  host-name r1;\n+  host-name r2;"},
+		{name: "empty", compare: emptyCompareReply, pending: false, diff: ""},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g, fd := newTestClient(tc.compare)
+
+			pending, diff, err := g.HasPendingChanges()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if pending != tc.pending {
+				t.Errorf("got pending %v, expected %v", pending, tc.pending)
+			}
+
+			if diff != tc.diff {
+				t.Errorf("got diff %q, expected %q", diff, tc.diff)
+			}
+
+			// Read-only, nothing is committed or discarded
+			if len(fd.Sent) != 1 || fd.Sent[0] != compareStr {
+				t.Errorf("got RPCs %q, expected only %q", fd.Sent, compareStr)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package junos_helpers
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// Authentication methods ClientOptions.AuthMethods can list
+const (
+	AuthKey                 = "key"                  // The private key file in SSHKey
+	AuthPassword            = "password"             // SSH password authentication with Password
+	AuthAgent               = "agent"                // The keys held by the SSH agent at SSH_AUTH_SOCK
+	AuthKeyboardInteractive = "keyboard-interactive" // Keyboard-interactive authentication answering prompts with Password
+)
+
+// authMethods returns the SSH authentication methods for opts, in the order they are offered to the device.
+// The SSH library tries each method name once, so AuthKey and AuthAgent share a single public key method,
+// offered where the first of them is listed, trying the key file before the agent's keys. The agent is
+// reached through keys, which the client closes.
+func authMethods(opts ClientOptions, keys *agentKeys) ([]ssh.AuthMethod, error) {
+	if len(opts.AuthMethods) == 0 {
+		// SSH keys takes priority over password based
+		if opts.SSHKey == "" {
+			return passwordAuth(opts.Password), nil
+		}
+
+		opts.AuthMethods = []string{AuthKey}
+	}
+
+	auth := make([]ssh.AuthMethod, 0, len(opts.AuthMethods))
+
+	var signers []ssh.Signer
+	useAgent := false
+	publicKey := -1 // Index of the public key method in auth
+
+	for _, method := range opts.AuthMethods {
+		switch method {
+		case AuthKey, AuthAgent:
+			if publicKey == -1 {
+				publicKey = len(auth)
+				auth = append(auth, nil)
+			}
+
+			if method == AuthAgent {
+				if keys == nil {
+					return nil, fmt.Errorf("ssh agent authentication requested without an agent connection")
+				}
+
+				err := keys.init()
+				if err != nil {
+					return nil, err
+				}
+				useAgent = true
+				continue
+			}
+
+			key, err := loadPrivateKey(opts.SSHKey)
+			if err != nil {
+				return nil, fmt.Errorf("unable to load ssh key %s: %v", opts.SSHKey, err)
+			}
+			signers = append(signers, key)
+		case AuthPassword:
+			auth = append(auth, ssh.Password(opts.Password))
+		case AuthKeyboardInteractive:
+			auth = append(auth, ssh.KeyboardInteractive(answerPassword(opts.Password)))
+		default:
+			return nil, fmt.Errorf("unknown auth method %q, expected key, password, agent or keyboard-interactive", method)
+		}
+	}
+
+	if publicKey != -1 {
+		auth[publicKey] = ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+			if !useAgent {
+				return signers, nil
+			}
+
+			agentSigners, err := keys.Signers()
+			if err != nil {
+				// Still offer the key file when the agent can not be reached
+				if len(signers) > 0 {
+					return signers, nil
+				}
+				return nil, err
+			}
+
+			return append(append([]ssh.Signer{}, signers...), agentSigners...), nil
+		})
+	}
+
+	return auth, nil
+}
+
+// usesAgent reports whether opts authenticates with the keys held by the SSH agent
+func usesAgent(opts ClientOptions) bool {
+	for _, method := range opts.AuthMethods {
+		if method == AuthAgent {
+			return true
+		}
+	}
+	return false
+}
+
+// agentKeys reaches the SSH agent listening on SSH_AUTH_SOCK. The connection is dialed when the agent's keys
+// are first needed and held, as the agent signs over it during authentication, until Close.
+type agentKeys struct {
+	mu   sync.Mutex
+	sock string
+	conn net.Conn
+}
+
+// init records the agent's socket, failing if SSH_AUTH_SOCK is not set
+func (a *agentKeys) init() error {
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return fmt.Errorf("ssh agent authentication requested but SSH_AUTH_SOCK is not set")
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.sock = sock
+	return nil
+}
+
+// Signers returns the keys held by the agent, dialing it unless already connected
+func (a *agentKeys) Signers() ([]ssh.Signer, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.conn == nil {
+		conn, err := net.Dial("unix", a.sock)
+		if err != nil {
+			return nil, fmt.Errorf("unable to reach ssh agent at %s: %v", a.sock, err)
+		}
+		a.conn = conn
+	}
+
+	signers, err := agent.NewClient(a.conn).Signers()
+	if err != nil {
+		// Redial on the next authentication, the agent may have restarted
+		a.conn.Close()
+		a.conn = nil
+		return nil, err
+	}
+
+	return signers, nil
+}
+
+// Close closes the connection to the agent, a later authentication dials it again
+func (a *agentKeys) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.conn == nil {
+		return nil
+	}
+
+	err := a.conn.Close()
+	a.conn = nil
+	return err
+}
+
+// passwordAuth authenticates with password, first as SSH password authentication and then, for devices with
+// RADIUS or TACACS+ backends that only offer it, as keyboard-interactive authentication answering the prompts
+func passwordAuth(password string) []ssh.AuthMethod {
+	return []ssh.AuthMethod{
+		ssh.Password(password),
+		ssh.KeyboardInteractive(answerPassword(password)),
+	}
+}
+
+// answerPassword returns a keyboard-interactive challenge answering every hidden prompt, such as
+// "Password:", with password. Prompts echoing their answer get an empty one.
+func answerPassword(password string) ssh.KeyboardInteractiveChallenge {
+	return func(user, instruction string, questions []string, echos []bool) ([]string, error) {
+		answers := make([]string, len(questions))
+		for i := range questions {
+			if !echos[i] {
+				answers[i] = password
+			}
+		}
+
+		return answers, nil
+	}
+}
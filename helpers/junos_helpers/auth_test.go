@@ -0,0 +1,483 @@
+package junos_helpers
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	sshdriver "github.com/davedotdev/go-netconf/drivers/ssh"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// keyboardInteractiveServer accepts a single SSH connection, offering only keyboard-interactive
+// authentication, and reports the outcome of the handshake
+func keyboardInteractiveServer(t *testing.T, password string) (net.Listener, chan error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate host key: %v", err)
+	}
+
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatalf("failed to create host key signer: %v", err)
+	}
+
+	config := &ssh.ServerConfig{
+		KeyboardInteractiveCallback: func(c ssh.ConnMetadata, client ssh.KeyboardInteractiveChallenge) (*ssh.Permissions, error) {
+			answers, err := client("", "RADIUS login", []string{"Login: ", "Password: "}, []bool{true, false})
+			if err != nil {
+				return nil, err
+			}
+
+			if len(answers) != 2 || answers[1] != password {
+				return nil, errors.New("access denied")
+			}
+
+			return nil, nil
+		},
+	}
+	config.AddHostKey(signer)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			result <- err
+			return
+		}
+		defer conn.Close()
+
+		_, _, _, err = ssh.NewServerConn(conn, config)
+		result <- err
+	}()
+
+	return ln, result
+}
+
+func TestKeyboardInteractiveFallback(t *testing.T) {
+	ln, result := keyboardInteractiveServer(t, "secret")
+	defer ln.Close()
+
+	g, err := newClientWithOptions(ClientOptions{Username: "admin", Password: "secret"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	config := g.Driver.(*sshdriver.DriverSSH).SSHConfig
+
+	client, err := ssh.Dial("tcp", ln.Addr().String(), config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client.Close()
+
+	err = <-result
+	if err != nil {
+		t.Errorf("unexpected server error: %v", err)
+	}
+}
+
+func TestKeyboardInteractiveWrongPassword(t *testing.T) {
+	ln, result := keyboardInteractiveServer(t, "secret")
+	defer ln.Close()
+
+	g, err := newClientWithOptions(ClientOptions{Username: "admin", Password: "wrong"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = ssh.Dial("tcp", ln.Addr().String(), g.Driver.(*sshdriver.DriverSSH).SSHConfig)
+	if err == nil {
+		t.Fatal("expected authentication to fail")
+	}
+
+	<-result
+}
+
+func TestClientVersion(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate host key: %v", err)
+	}
+
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatalf("failed to create host key signer: %v", err)
+	}
+
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(signer)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	// Record the identification string the client sent
+	version := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			version <- ""
+			return
+		}
+		defer conn.Close()
+
+		sconn, _, _, err := ssh.NewServerConn(conn, config)
+		if err != nil {
+			version <- ""
+			return
+		}
+		version <- string(sconn.ClientVersion())
+	}()
+
+	g, err := newClientWithOptions(ClientOptions{Username: "admin", ClientVersion: "SSH-2.0-Automation_1.0"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	client, err := ssh.Dial("tcp", ln.Addr().String(), g.Driver.(*sshdriver.DriverSSH).SSHConfig)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client.Close()
+
+	if v := <-version; v != "SSH-2.0-Automation_1.0" {
+		t.Errorf("got client version %q, expected SSH-2.0-Automation_1.0", v)
+	}
+
+	for _, invalid := range []string{"Automation_1.0", "SSH-1.99-Automation", "SSH-2.0-a\r\nSSH-2.0-b"} {
+		_, err = newClientWithOptions(ClientOptions{Username: "admin", ClientVersion: invalid})
+		if err == nil {
+			t.Errorf("%q: expected an error", invalid)
+		}
+	}
+}
+
+func TestAuthMethods(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Serve an empty keyring as the SSH agent
+	sock := filepath.Join(dir, "agent.sock")
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go agent.ServeAgent(agent.NewKeyring(), conn)
+		}
+	}()
+
+	defer os.Setenv("SSH_AUTH_SOCK", os.Getenv("SSH_AUTH_SOCK"))
+	os.Setenv("SSH_AUTH_SOCK", sock)
+
+	key := filepath.Join(dir, "id_ed25519")
+	writeTestKey(t, key)
+
+	g, err := newClientWithOptions(ClientOptions{
+		Username:    "admin",
+		Password:    "secret",
+		SSHKey:      key,
+		AuthMethods: []string{AuthKey, AuthPassword, AuthAgent, AuthKeyboardInteractive},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	auth := g.Driver.(*sshdriver.DriverSSH).SSHConfig.Auth
+
+	// The methods are unexported types, identified by name in their configured order
+	// with the key and the agent sharing the first public key method
+	expected := []string{"publicKeyCallback", "passwordCallback", "KeyboardInteractiveChallenge"}
+	if len(auth) != len(expected) {
+		t.Fatalf("got %d auth methods, expected %d", len(auth), len(expected))
+	}
+
+	for i := range expected {
+		if name := fmt.Sprintf("%T", auth[i]); !strings.HasSuffix(name, "."+expected[i]) {
+			t.Errorf("got auth method %d of type %s, expected %s", i, name, expected[i])
+		}
+	}
+
+	_, err = newClientWithOptions(ClientOptions{Username: "admin", AuthMethods: []string{"kerberos"}})
+	if err == nil {
+		t.Error("expected an error for an unknown auth method")
+	}
+}
+
+func TestAuthKeyThenAgent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Only the agent holds the key the server accepts, the key file is rejected
+	_, agentKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	keyring := agent.NewKeyring()
+	err = keyring.Add(agent.AddedKey{PrivateKey: agentKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accepted, err := ssh.NewSignerFromKey(agentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sock := filepath.Join(dir, "agent.sock")
+	agentLn, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer agentLn.Close()
+
+	// Report each agent connection once the client closes it
+	closed := make(chan struct{}, 4)
+	go func() {
+		for {
+			conn, err := agentLn.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				agent.ServeAgent(keyring, conn)
+				conn.Close()
+				closed <- struct{}{}
+			}()
+		}
+	}()
+
+	defer os.Setenv("SSH_AUTH_SOCK", os.Getenv("SSH_AUTH_SOCK"))
+	os.Setenv("SSH_AUTH_SOCK", sock)
+
+	key := filepath.Join(dir, "id_ed25519")
+	writeTestKey(t, key)
+
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate host key: %v", err)
+	}
+
+	hostSigner, err := ssh.NewSignerFromKey(hostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(c ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) != string(accepted.PublicKey().Marshal()) {
+				return nil, errors.New("unknown key")
+			}
+			return nil, nil
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	result := make(chan error, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			result <- err
+			return
+		}
+		defer conn.Close()
+
+		_, _, _, err = ssh.NewServerConn(conn, config)
+		result <- err
+	}()
+
+	g, err := newClientWithOptions(ClientOptions{
+		Username:    "admin",
+		SSHKey:      key,
+		AuthMethods: []string{AuthKey, AuthAgent},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	client, err := ssh.Dial("tcp", ln.Addr().String(), g.Driver.(*sshdriver.DriverSSH).SSHConfig)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client.Close()
+
+	err = <-result
+	if err != nil {
+		t.Errorf("unexpected server error: %v", err)
+	}
+
+	err = g.Close()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Error("the agent connection was not closed with the client")
+	}
+}
+
+func TestCloneAgent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	_, agentKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	keyring := agent.NewKeyring()
+	err = keyring.Add(agent.AddedKey{PrivateKey: agentKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sock := filepath.Join(dir, "agent.sock")
+	agentLn, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer agentLn.Close()
+
+	go func() {
+		for {
+			conn, err := agentLn.Accept()
+			if err != nil {
+				return
+			}
+			go agent.ServeAgent(keyring, conn)
+		}
+	}()
+
+	defer os.Setenv("SSH_AUTH_SOCK", os.Getenv("SSH_AUTH_SOCK"))
+	os.Setenv("SSH_AUTH_SOCK", sock)
+
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate host key: %v", err)
+	}
+
+	hostSigner, err := ssh.NewSignerFromKey(hostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(c ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			return nil, nil
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				ssh.NewServerConn(conn, config)
+			}()
+		}
+	}()
+
+	g, err := newClientWithOptions(ClientOptions{Username: "admin", AuthMethods: []string{AuthAgent}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	clone := g.Clone("192.0.2.2", 0)
+
+	if clone.agent == nil || clone.agent == g.agent {
+		t.Fatal("expected the clone to have an agent connection of its own")
+	}
+
+	for _, c := range []*GoNCClient{g, clone} {
+		client, err := ssh.Dial("tcp", ln.Addr().String(), c.Driver.(*sshdriver.DriverSSH).SSHConfig)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		client.Close()
+	}
+
+	if g.agent.conn == nil || clone.agent.conn == nil {
+		t.Fatal("expected each client to dial the agent when authenticating")
+	}
+
+	// Closing the original leaves the clone's agent connection alone
+	g.Close()
+
+	if clone.agent.conn == nil {
+		t.Error("closing the original client closed the clone's agent connection")
+	}
+
+	clone.Close()
+}
+
+// writeTestKey writes a new unencrypted OpenSSH private key to path
+func writeTestKey(t *testing.T, path string) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	err = ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
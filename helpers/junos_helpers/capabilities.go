@@ -0,0 +1,164 @@
+package junos_helpers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	rpc "github.com/davedotdev/go-netconf/rpc"
+)
+
+// ErrCapabilityNotSupported is returned when an operation needs a capability the device did not advertise
+var ErrCapabilityNotSupported = errors.New("capability not advertised by the device")
+
+// ErrCandidateUnsupported is returned when the device advertises neither the candidate nor the writable-running capability
+var ErrCandidateUnsupported = errors.New("device supports neither the candidate datastore nor writable running")
+
+const (
+	capabilityCandidate       = "urn:ietf:params:netconf:capability:candidate:1.0"
+	capabilityWritableRunning = "urn:ietf:params:netconf:capability:writable-running:1.0"
+)
+
+// Datastores edited by edit-config
+const (
+	datastoreCandidate = string(rpc.DatastoreCandidate)
+	datastoreRunning   = string(rpc.DatastoreRunning)
+)
+
+// EditedDatastore returns the datastore the last successful edit was made in, "candidate" or "running", or ""
+// before any edit. Edits fall back to running on devices advertising writable-running but not the candidate,
+// so Terraform style state can record where a change actually landed.
+func (g *GoNCClient) EditedDatastore() string {
+	g.Lock.RLock()
+	defer g.Lock.RUnlock()
+
+	return g.lastEdit
+}
+
+// capabilityReporter is implemented by drivers able to report the capabilities the server advertised in its hello
+type capabilityReporter interface {
+	ServerCapabilities() []string
+}
+
+// serverCapabilities returns the capabilities advertised on the open session, or nil if the driver can not report them
+func (g *GoNCClient) serverCapabilities() []string {
+	cr, ok := g.Driver.(capabilityReporter)
+	if !ok {
+		return nil
+	}
+
+	return cr.ServerCapabilities()
+}
+
+// requireCapability returns ErrCapabilityNotSupported unless the open session advertised uri
+func (g *GoNCClient) requireCapability(uri string) error {
+	if !hasCapability(g.serverCapabilities(), uri) {
+		return fmt.Errorf("%w: %s", ErrCapabilityNotSupported, uri)
+	}
+
+	return nil
+}
+
+// hasCapability reports whether capabilities contains uri, ignoring any query parameters
+func hasCapability(capabilities []string, uri string) bool {
+	for _, c := range capabilities {
+		if i := strings.Index(c, "?"); i > -1 {
+			c = c[:i]
+		}
+		if strings.TrimSpace(c) == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// editTarget returns the datastore to edit on the open session: the candidate when the device advertises it,
+// running when it only advertises writable-running. Drivers unable to report capabilities get the candidate.
+func (g *GoNCClient) editTarget() (string, error) {
+	capabilities := g.serverCapabilities()
+
+	if capabilities == nil || hasCapability(capabilities, capabilityCandidate) {
+		return datastoreCandidate, nil
+	}
+
+	if hasCapability(capabilities, capabilityWritableRunning) {
+		return datastoreRunning, nil
+	}
+
+	return "", ErrCandidateUnsupported
+}
+
+// Capabilities mapped onto Features, beyond those already named above
+const (
+	capabilityBase11          = "urn:ietf:params:netconf:base:1.1"
+	capabilityConfirmedCommit = "urn:ietf:params:netconf:capability:confirmed-commit:1.0"
+	capabilityConfirmed11     = "urn:ietf:params:netconf:capability:confirmed-commit:1.1"
+	capabilityStartup         = "urn:ietf:params:netconf:capability:startup:1.0"
+	capabilityXPath           = "urn:ietf:params:netconf:capability:xpath:1.0"
+	capabilityWithDefaults    = "urn:ietf:params:netconf:capability:with-defaults:1.0"
+	capabilityNotification    = "urn:ietf:params:netconf:capability:notification:1.0"
+)
+
+// Features reports which of the common RFC 6241 capabilities a device advertised, for application code
+// that would otherwise match capability URNs itself
+type Features struct {
+	Base11          bool // :base:1.1, chunked framing
+	Candidate       bool // :candidate
+	WritableRunning bool // :writable-running
+	ConfirmedCommit bool // :confirmed-commit, 1.0 or 1.1
+	Validate        bool // :validate, 1.0 or 1.1
+	Startup         bool // :startup
+	URL             bool // :url, see LoadConfigURL
+	XPath           bool // :xpath, needed by the XPath filters of Get
+	WithDefaults    bool // :with-defaults
+	RollbackOnError bool // :rollback-on-error
+	PartialLock     bool // :partial-lock, see LockGroup
+	Notification    bool // :notification, see SubscribeNotifications
+}
+
+// Features returns the features the device advertised in its hello, dialing a session to read them unless
+// one is held open by Dial. Drivers unable to report capabilities return ErrCapabilityNotSupported.
+func (g *GoNCClient) Features() (Features, error) {
+	var capabilities []string
+
+	err := g.withSession(func() error {
+		if _, ok := g.Driver.(capabilityReporter); !ok {
+			return fmt.Errorf("%w: driver can not report capabilities", ErrCapabilityNotSupported)
+		}
+
+		capabilities = g.serverCapabilities()
+		return nil
+	})
+	if err != nil {
+		return Features{}, err
+	}
+
+	return newFeatures(capabilities), nil
+}
+
+// newFeatures maps the capabilities advertised by a device onto Features
+func newFeatures(capabilities []string) Features {
+	has := func(uris ...string) bool {
+		for _, uri := range uris {
+			if hasCapability(capabilities, uri) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return Features{
+		Base11:          has(capabilityBase11),
+		Candidate:       has(capabilityCandidate),
+		WritableRunning: has(capabilityWritableRunning),
+		ConfirmedCommit: has(capabilityConfirmedCommit, capabilityConfirmed11),
+		Validate:        has(rpc.CapabilityValidate10, rpc.CapabilityValidate11),
+		Startup:         has(capabilityStartup),
+		URL:             has(capabilityURL),
+		XPath:           has(capabilityXPath),
+		WithDefaults:    has(capabilityWithDefaults),
+		RollbackOnError: has(rpc.CapabilityRollbackOnError),
+		PartialLock:     has(capabilityPartialLock),
+		Notification:    has(capabilityNotification),
+	}
+}
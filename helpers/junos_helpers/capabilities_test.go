@@ -0,0 +1,72 @@
+package junos_helpers
+
+import (
+	"testing"
+)
+
+// junosCapabilities is the capability list a Junos device advertises in its hello
+var junosCapabilities = []string{
+	"urn:ietf:params:netconf:base:1.0",
+	"urn:ietf:params:netconf:capability:candidate:1.0",
+	"urn:ietf:params:netconf:capability:confirmed-commit:1.0",
+	"urn:ietf:params:netconf:capability:validate:1.0",
+	"urn:ietf:params:netconf:capability:url:1.0?scheme=http,ftp,file",
+	"urn:ietf:params:xml:ns:netconf:base:1.0",
+	"urn:ietf:params:xml:ns:netconf:capability:candidate:1.0",
+	"urn:ietf:params:xml:ns:netconf:capability:confirmed-commit:1.0",
+	"urn:ietf:params:xml:ns:netconf:capability:validate:1.0",
+	"urn:ietf:params:xml:ns:netconf:capability:url:1.0?scheme=http,ftp,file",
+	"urn:ietf:params:xml:ns:yang:ietf-netconf-monitoring",
+	"http://xml.juniper.net/netconf/junos/1.0",
+	"http://xml.juniper.net/dmi/system/1.0",
+}
+
+func TestFeatures(t *testing.T) {
+	g, fd := newTestClient()
+	fd.Capabilities = junosCapabilities
+
+	features, err := g.Features()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := Features{Candidate: true, ConfirmedCommit: true, Validate: true, URL: true}
+	if features != expected {
+		t.Errorf("got %+v, expected %+v", features, expected)
+	}
+
+	if fd.Dials != 1 || fd.Closes != 1 {
+		t.Errorf("got %d dials and %d closes, expected one of each", fd.Dials, fd.Closes)
+	}
+}
+
+func TestNewFeatures(t *testing.T) {
+	features := newFeatures([]string{
+		capabilityBase11,
+		capabilityWritableRunning,
+		"urn:ietf:params:netconf:capability:confirmed-commit:1.1",
+		"urn:ietf:params:netconf:capability:validate:1.1",
+		capabilityStartup,
+		capabilityXPath,
+		"urn:ietf:params:netconf:capability:with-defaults:1.0?basic-mode=explicit&also-supported=report-all",
+		"urn:ietf:params:netconf:capability:rollback-on-error:1.0",
+		capabilityPartialLock,
+		capabilityNotification,
+	})
+
+	expected := Features{
+		Base11:          true,
+		WritableRunning: true,
+		ConfirmedCommit: true,
+		Validate:        true,
+		Startup:         true,
+		XPath:           true,
+		WithDefaults:    true,
+		RollbackOnError: true,
+		PartialLock:     true,
+		Notification:    true,
+	}
+	if features != expected {
+		t.Errorf("got %+v, expected %+v", features, expected)
+	}
+}
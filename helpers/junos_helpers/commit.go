@@ -0,0 +1,703 @@
+package junos_helpers
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	rpc "github.com/davedotdev/go-netconf/rpc"
+)
+
+// ErrNoCommit is returned by WaitForCommitComplete when the client has not committed anything to wait for
+var ErrNoCommit = errors.New("no commit made by this client")
+
+// ErrCommitNotTracked is returned by WaitForCommitComplete when the last commit was made without TrackCommits,
+// so there is no commit history from before it to compare with
+var ErrCommitNotTracked = errors.New("commit made without TrackCommits")
+
+// ErrConfirmedCommitPending is returned when GuardConfirmedCommit is set and a confirmed commit is awaiting confirmation
+var ErrConfirmedCommitPending = errors.New("a confirmed commit is awaiting confirmation")
+
+// ErrLockDenied is returned when an operation is refused because another session holds the configuration lock,
+// wrapped in a LockDeniedError naming the holder
+var ErrLockDenied = errors.New("configuration database locked by another session")
+
+// CommitResult is the parsed outcome of a commit
+type CommitResult struct {
+	Complete         bool     // The device reported <ok/> or <commit-success/>
+	Warnings         []string // Messages of any warning severity rpc-errors
+	DatabaseModified bool     // Another session modified the configuration database
+	RollbackMinutes  int      // Minutes until a confirmed commit is rolled back, zero if not confirmed
+
+	// SequenceNumber is the sequence number the device assigned to the commit, for correlating it with
+	// audit logs or rolling back to it later. Zero when the commit reply does not report one, as
+	// releases only numbering commits by their rollback index do not.
+	SequenceNumber uint64
+}
+
+// commitReply is the subset of a Junos commit reply needed to build a CommitResult
+type commitReply struct {
+	Ok      *struct{} `xml:"ok"`
+	Success *struct{} `xml:"commit-success"`
+	Results *struct {
+		Errors         []rpc.RPCError `xml:"rpc-error"`
+		Success        *struct{}      `xml:"commit-success"`
+		SequenceNumber string         `xml:"sequence-number"`
+		RoutingEngines []struct {
+			Errors         []rpc.RPCError `xml:"rpc-error"`
+			Success        *struct{}      `xml:"commit-success"`
+			SequenceNumber string         `xml:"sequence-number"`
+		} `xml:"routing-engine"`
+	} `xml:"commit-results"`
+}
+
+const getCommitInformationStr = `<get-commit-information/>
+`
+
+// CommitEntry is a single commit in the device's commit history
+type CommitEntry struct {
+	RollbackIndex  int       // Rollback index of the commit, 0 is the most recent
+	User           string    // Login that made the commit
+	Client         string    // How the commit was made, e.g. cli or netconf
+	DateTime       string    // Commit time as reported by the device
+	Time           time.Time // Commit time, zero if the device did not report it in seconds
+	Comment        string    // Log comment given with the commit, if any
+	PendingConfirm bool      // A confirmed commit awaiting confirmation, rolled back unless confirmed
+}
+
+// commitHistory mirrors a commit-history element of a get-commit-information reply
+type commitHistory struct {
+	SequenceNumber string `xml:"sequence-number"`
+	User           string `xml:"user"`
+	Client         string `xml:"client"`
+	DateTime       struct {
+		Seconds string `xml:"seconds,attr"`
+		Value   string `xml:",chardata"`
+	} `xml:"date-time"`
+	Comment string `xml:"log"`
+	Status  string `xml:"comment"` // e.g. "commit confirmed, rollback in 10mins"
+}
+
+var rollbackMinutesRe = regexp.MustCompile(`rolled back in (\d+) minutes?`)
+
+// parseCommitReply builds a CommitResult, returning an error unless the device reported completion
+func parseCommitReply(reply *rpc.RPCReply) (*CommitResult, error) {
+	var cr commitReply
+
+	err := xml.Unmarshal([]byte("<rpc-reply>"+reply.Data+"</rpc-reply>"), &cr)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CommitResult{
+		Complete: cr.Ok != nil || cr.Success != nil,
+	}
+
+	rpcErrors := reply.Errors
+	if cr.Results != nil {
+		rpcErrors = append(rpcErrors, cr.Results.Errors...)
+		result.Complete = result.Complete || cr.Results.Success != nil
+		sequenceNumber := cr.Results.SequenceNumber
+
+		for _, re := range cr.Results.RoutingEngines {
+			rpcErrors = append(rpcErrors, re.Errors...)
+			result.Complete = result.Complete || re.Success != nil
+
+			if sequenceNumber == "" {
+				sequenceNumber = re.SequenceNumber
+			}
+		}
+
+		result.SequenceNumber, err = parseSequenceNumber(sequenceNumber)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range rpcErrors {
+		if rpcErrors[i].Severity == "error" {
+			return result, &rpcErrors[i]
+		}
+
+		result.Warnings = append(result.Warnings, strings.TrimSpace(rpcErrors[i].Message))
+	}
+
+	// The confirmed commit and database modified notices arrive as warnings or free text depending on the release
+	text, err := replyText(reply.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	result.DatabaseModified = strings.Contains(text, "configuration database modified")
+
+	m := rollbackMinutesRe.FindStringSubmatch(text)
+	if m != nil {
+		result.RollbackMinutes, _ = strconv.Atoi(m[1])
+	}
+
+	if !result.Complete {
+		return result, fmt.Errorf("commit did not report completion")
+	}
+
+	return result, nil
+}
+
+// parseSequenceNumber parses the sequence-number of a commit reply, zero when it is absent
+func parseSequenceNumber(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid sequence-number %q: %v", s, err)
+	}
+
+	return n, nil
+}
+
+// replyText returns all of the character data in the reply data
+func replyText(data string) (string, error) {
+	d := xml.NewDecoder(strings.NewReader("<rpc-reply>" + data + "</rpc-reply>"))
+
+	var text strings.Builder
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return text.String(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if cd, ok := tok.(xml.CharData); ok {
+			text.Write(cd)
+		}
+	}
+}
+
+// SendCommitResult commits the candidate and returns the parsed result of the commit
+func (g *GoNCClient) SendCommitResult() (*CommitResult, error) {
+	return g.sendCommit(commitStr, true)
+}
+
+// SendCommitFull commits the candidate as "commit full" does, making every daemon re-read the whole configuration
+// rather than only the statements that changed, e.g. after an upgrade left a daemon out of step with it
+func (g *GoNCClient) SendCommitFull() error {
+	_, err := g.sendCommit(commitFullStr, true)
+	return err
+}
+
+// ConfirmedCommit commits the candidate, rolling it back unless ConfirmCommit is called within timeout
+// (the device default of 10 minutes when zero). Without a persist token the rollback also happens as soon
+// as the session ends, so either hold a session open with Dial or give a token that ConfirmCommit and
+// CancelCommit can use from any session.
+func (g *GoNCClient) ConfirmedCommit(timeout time.Duration, persist string) (*CommitResult, error) {
+	return g.sendCommit(rpc.MethodConfirmedCommit(uint32(timeout/time.Second), persist).MarshalMethod(), true)
+}
+
+// ConfirmCommit confirms a pending confirmed commit. Pass the persist token given to ConfirmedCommit
+// to confirm it from another session, or an empty string to confirm one made on the current session.
+func (g *GoNCClient) ConfirmCommit(persistID string) (*CommitResult, error) {
+	return g.sendCommit(rpc.MethodConfirmCommit(persistID).MarshalMethod(), false)
+}
+
+// sendCommit dials, commits with the given commit RPC and closes. Only guarded commits are subject to GuardConfirmedCommit.
+func (g *GoNCClient) sendCommit(commitString string, guarded bool) (*CommitResult, error) {
+	var result *CommitResult
+
+	err := g.withSession(func() error {
+		var err error
+
+		if guarded {
+			result, err = g.guardedCommitWith(context.Background(), commitString)
+		} else {
+			result, err = g.commitWith(context.Background(), commitString)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// CommitAndClose commits the candidate and ends the session straight away with close-session, saving the
+// separate close of the common "push one change and disconnect" pattern. Only the commit and close-session
+// are sent, so the commit history is not read for GuardConfirmedCommit or TrackCommits. A session held open
+// by Dial is ended too, the next operation dials a new one. The commit result is returned even if closing
+// fails.
+func (g *GoNCClient) CommitAndClose() (*CommitResult, error) {
+	g.Lock.Lock()
+	defer g.Lock.Unlock()
+
+	err := g.dial()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := g.commitAfter(context.Background(), commitStr, nil, false)
+
+	_, errClose := g.Driver.SendRaw(rpc.MethodCloseSession().MarshalMethod())
+	errInternal := g.Driver.Close()
+
+	g.sessionOpen = false
+	g.groupLocks = nil
+
+	if err != nil {
+		return nil, err
+	}
+
+	if errClose != nil {
+		return result, fmt.Errorf("close-session error: %w", errClose)
+	}
+
+	if errInternal != nil {
+		return result, fmt.Errorf("driver close error: %+s", errInternal)
+	}
+
+	return result, nil
+}
+
+// SendCommitContext commits the candidate like SendCommitResult, but gives up waiting once ctx is done.
+// The device keeps working on a commit it has started, so the session is held until the reply arrives
+// and is then closed in the background, and later operations on the client wait for that to finish.
+// The returned error wraps ctx.Err().
+func (g *GoNCClient) SendCommitContext(ctx context.Context) (*CommitResult, error) {
+	return g.sendCommitContext(ctx, commitStr, false, "")
+}
+
+// ConfirmedCommitContext makes a confirmed commit like ConfirmedCommit, but gives up waiting once ctx is done.
+// A confirmed commit that completes after ctx is done is rolled back with cancel-commit, so a change the
+// caller has given up on is never left waiting to be confirmed.
+func (g *GoNCClient) ConfirmedCommitContext(ctx context.Context, timeout time.Duration, persist string) (*CommitResult, error) {
+	commitString := rpc.MethodConfirmedCommit(uint32(timeout/time.Second), persist).MarshalMethod()
+	return g.sendCommitContext(ctx, commitString, true, persist)
+}
+
+// commitOutcome carries the result of a commit from the goroutine waiting on the device
+type commitOutcome struct {
+	result *CommitResult
+	err    error
+}
+
+// sendCommitContext dials and commits, returning early when ctx is done and leaving a goroutine to
+// cancel an abandoned confirmed commit, close the session and release the lock
+func (g *GoNCClient) sendCommitContext(ctx context.Context, commitString string, confirmed bool, persist string) (*CommitResult, error) {
+	g.Lock.Lock()
+
+	err := g.dial()
+	if err != nil {
+		g.Lock.Unlock()
+		return nil, err
+	}
+
+	var (
+		done      = make(chan commitOutcome, 1)
+		decided   sync.Mutex // Settles whether the caller or the goroutine handles the reply
+		abandoned bool
+		replied   bool
+	)
+
+	go func() {
+		defer g.Lock.Unlock()
+
+		result, err := g.guardedCommitWith(ctx, commitString)
+
+		decided.Lock()
+		replied = !abandoned
+		decided.Unlock()
+
+		if !replied {
+			if confirmed && err == nil {
+				g.Driver.SendRaw(rpc.MethodCancelCommit(persist).MarshalMethod())
+			}
+			g.close()
+			return
+		}
+
+		errInternal := g.close()
+		if err == nil && errInternal != nil {
+			err = fmt.Errorf("driver close error: %+s", errInternal)
+		}
+		done <- commitOutcome{result: result, err: err}
+	}()
+
+	select {
+	case outcome := <-done:
+		return outcome.result, outcome.err
+	case <-ctx.Done():
+	}
+
+	decided.Lock()
+	abandoned = !replied
+	decided.Unlock()
+
+	// The reply won the race, report it rather than a timeout
+	if !abandoned {
+		outcome := <-done
+		return outcome.result, outcome.err
+	}
+
+	if confirmed {
+		return nil, fmt.Errorf("commit abandoned, cancel-commit will be issued if it completes: %w", ctx.Err())
+	}
+	return nil, fmt.Errorf("commit abandoned, it may still complete on the device: %w", ctx.Err())
+}
+
+// parseCommitHistory extracts the commit history from the data of a get-commit-information reply
+func parseCommitHistory(data string) ([]CommitEntry, error) {
+	wrapper := struct {
+		History []commitHistory `xml:"commit-information>commit-history"`
+	}{}
+
+	err := xml.Unmarshal([]byte("<rpc-reply>"+data+"</rpc-reply>"), &wrapper)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]CommitEntry, 0, len(wrapper.History))
+	for _, h := range wrapper.History {
+		entry := CommitEntry{
+			User:     strings.TrimSpace(h.User),
+			Client:   strings.TrimSpace(h.Client),
+			DateTime: strings.TrimSpace(h.DateTime.Value),
+			Comment:  strings.TrimSpace(h.Comment),
+		}
+
+		entry.PendingConfirm = strings.Contains(h.Status, "commit confirmed, rollback in")
+
+		entry.RollbackIndex, err = strconv.Atoi(strings.TrimSpace(h.SequenceNumber))
+		if err != nil {
+			return nil, fmt.Errorf("invalid sequence-number %q: %v", h.SequenceNumber, err)
+		}
+
+		if seconds, err := strconv.ParseInt(strings.TrimSpace(h.DateTime.Seconds), 10, 64); err == nil {
+			entry.Time = time.Unix(seconds, 0).UTC()
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// GetCommitHistory returns the device's recent commits, most recent first
+func (g *GoNCClient) GetCommitHistory() ([]CommitEntry, error) {
+	reply, err := g.sendRaw(getCommitInformationStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseCommitHistory(reply.Data)
+}
+
+const getRollbackCompareStr = `<get-rollback-information>
+  <rollback>%d</rollback>
+  <compare>%d</compare>
+</get-rollback-information>
+`
+
+// parseRollbackCompare extracts the diff from the data of a get-rollback-information compare reply
+func parseRollbackCompare(data string) (string, error) {
+	wrapper := struct {
+		Output *string `xml:"rollback-information>configuration-information>configuration-output"`
+	}{}
+
+	err := xml.Unmarshal([]byte("<rpc-reply>"+data+"</rpc-reply>"), &wrapper)
+	if err != nil {
+		return "", err
+	}
+
+	if wrapper.Output == nil {
+		return "", fmt.Errorf("no configuration-output in reply")
+	}
+
+	return strings.TrimSpace(*wrapper.Output), nil
+}
+
+// GetCommitDiff returns the diff the commit at the given rollback index (CommitEntry.RollbackIndex) made, in
+// the "show | compare" text format, e.g. for audit exports alongside GetCommitHistory. It compares the
+// configuration at that rollback index with the one before it, so rollback must be 0 to 48: rollback 49, the
+// oldest configuration the device keeps, has nothing to be compared with. The diff is empty when the commit
+// changed nothing.
+func (g *GoNCClient) GetCommitDiff(rollback int) (string, error) {
+	if rollback < 0 || rollback >= maxRollback {
+		return "", fmt.Errorf("commit %d out of range, expected 0 to %d as rollback %d is the oldest kept and has no earlier configuration to compare with",
+			rollback, maxRollback-1, maxRollback)
+	}
+
+	reply, err := g.sendRaw(fmt.Sprintf(getRollbackCompareStr, rollback+1, rollback))
+	if err != nil {
+		return "", err
+	}
+
+	return parseRollbackCompare(reply.Data)
+}
+
+// WaitForCommitComplete blocks until the device's commit history shows the last commit made by this client,
+// polling every CommitPollInterval, or until ctx is done. The commit must have been made with TrackCommits
+// set: the history read before it has to reappear one rollback index further down. Only the device's own
+// history is compared, so the device and client clocks need not agree.
+func (g *GoNCClient) WaitForCommitComplete(ctx context.Context) error {
+	g.Lock.RLock()
+	committed, tracked, before := g.committed, g.tracked, g.commitBase
+	g.Lock.RUnlock()
+
+	if !committed {
+		return ErrNoCommit
+	}
+
+	if !tracked {
+		return ErrCommitNotTracked
+	}
+
+	interval := g.CommitPollInterval
+	if interval == 0 {
+		interval = time.Second
+	}
+
+	for {
+		entries, err := g.GetCommitHistory()
+		if err != nil {
+			return err
+		}
+
+		if movedPast(entries, before) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("commit not reflected in the commit history: %w", ctx.Err())
+		case <-time.After(interval):
+		}
+	}
+}
+
+// movedPast reports whether the commit history entries holds a new commit on top of before, the history
+// read before committing. Every entry of before must reappear with its rollback index one higher, so a new
+// commit looking like the previous top, e.g. a second uncommented commit by the same user within the same
+// second, is still told apart.
+func movedPast(entries []CommitEntry, before []CommitEntry) bool {
+	if len(entries) == 0 {
+		return false
+	}
+
+	if len(before) == 0 {
+		return true
+	}
+
+	if len(entries) < 2 {
+		return false
+	}
+
+	// The device keeps a bounded history, the oldest entry of before may have dropped off the end
+	for i := 0; i < len(before) && i+1 < len(entries); i++ {
+		if !sameCommit(entries[i+1], before[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sameCommit reports whether a is the commit-history entry b, moved down by one rollback index
+func sameCommit(a, b CommitEntry) bool {
+	return a.RollbackIndex == b.RollbackIndex+1 &&
+		a.DateTime == b.DateTime && a.User == b.User && a.Client == b.Client && a.Comment == b.Comment
+}
+
+// commitHistory reads the device's commit history on the open session
+func (g *GoNCClient) commitHistory() ([]CommitEntry, error) {
+	reply, err := g.Driver.SendRaw(getCommitInformationStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseCommitHistory(reply.Data)
+}
+
+// CancelCommit cancels a pending confirmed commit, rolling the device back immediately instead of waiting
+// for the confirm timeout. Pass the persist id of a persistent confirmed commit to cancel it from another
+// session, or an empty string to cancel a confirmed commit made on the current session.
+func (g *GoNCClient) CancelCommit(persistID string) error {
+	_, err := g.sendRaw(rpc.MethodCancelCommit(persistID).MarshalMethod())
+	return err
+}
+
+// LockDeniedError is returned when an operation is refused because another session holds the configuration
+// lock, carrying the holder's session-id so automation can decide between killing a stale session and backing
+// off. It matches ErrLockDenied with errors.Is.
+type LockDeniedError struct {
+	HolderSessionID uint32 // session-id of the session holding the lock, zero when the device did not report it
+	Attempts        int    // Attempts made before giving up
+	Err             error  // The device's rpc-error
+}
+
+// Error generates a string representation of the lock denial
+func (e *LockDeniedError) Error() string {
+	holder := ""
+	if e.HolderSessionID != 0 {
+		holder = fmt.Sprintf(" %d", e.HolderSessionID)
+	}
+
+	return fmt.Sprintf("%s%s after %d attempt(s): %v", ErrLockDenied, holder, e.Attempts, e.Err)
+}
+
+// Is reports whether target is ErrLockDenied
+func (e *LockDeniedError) Is(target error) bool {
+	return target == ErrLockDenied
+}
+
+// Unwrap returns the device's rpc-error
+func (e *LockDeniedError) Unwrap() error {
+	return e.Err
+}
+
+// newLockDeniedError returns a LockDeniedError for the lock-denied rpc-error err, reading the holder's
+// session-id from its error-info
+func newLockDeniedError(err error, attempts int) *LockDeniedError {
+	lde := &LockDeniedError{Attempts: attempts, Err: err}
+
+	rpcErr := rpc.FindRPCError(err, lockDenied)
+	if rpcErr == nil {
+		return lde
+	}
+
+	info := struct {
+		SessionID string `xml:"error-info>session-id"`
+	}{}
+
+	if xml.Unmarshal([]byte("<rpc-error>"+rpcErr.Info+"</rpc-error>"), &info) == nil {
+		id, err := strconv.ParseUint(strings.TrimSpace(info.SessionID), 10, 32)
+		if err == nil {
+			lde.HolderSessionID = uint32(id)
+		}
+	}
+
+	return lde
+}
+
+// lockError returns a LockDeniedError when the device refused to lock because another session holds the
+// lock, otherwise the driver error
+func lockError(err error) error {
+	if isLockDenied(err) {
+		return newLockDeniedError(err, 1)
+	}
+
+	return fmt.Errorf("driver error: %w", err)
+}
+
+// isLockDenied reports whether err is the device refusing an operation because another session holds the lock
+func isLockDenied(err error) bool {
+	return rpc.FindRPCError(err, lockDenied) != nil
+}
+
+// lockDenied reports whether rpcErr is a lock-denied error, which some releases only report in the message
+func lockDenied(rpcErr *rpc.RPCError) bool {
+	return rpcErr.Tag == "lock-denied" || strings.Contains(rpcErr.Message, "database locked")
+}
+
+// commit commits on the open session, retrying with backoff while another session holds the lock
+func (g *GoNCClient) commit() (*CommitResult, error) {
+	return g.guardedCommitWith(context.Background(), commitStr)
+}
+
+// guardedCommitWith is commitWith, first refusing with ErrConfirmedCommitPending when GuardConfirmedCommit
+// is set and the commit history shows a confirmed commit awaiting confirmation
+func (g *GoNCClient) guardedCommitWith(ctx context.Context, commitString string) (*CommitResult, error) {
+	if !g.GuardConfirmedCommit && !g.TrackCommits {
+		return g.commitAfter(ctx, commitString, nil, false)
+	}
+
+	entries, err := g.commitHistory()
+	if err != nil {
+		return nil, err
+	}
+
+	if g.GuardConfirmedCommit && len(entries) > 0 && entries[0].PendingConfirm {
+		return nil, fmt.Errorf("%w, made by %s at %s", ErrConfirmedCommitPending, entries[0].User, entries[0].DateTime)
+	}
+
+	return g.commitAfter(ctx, commitString, entries, g.TrackCommits)
+}
+
+// commitWith sends the commit RPC on the open session, retrying with backoff while another session holds the lock.
+// A successful commit is followed by the PostCommitDelay, cut short when ctx is done. With TrackCommits the commit
+// history is read first, for WaitForCommitComplete.
+func (g *GoNCClient) commitWith(ctx context.Context, commitString string) (*CommitResult, error) {
+	if !g.TrackCommits {
+		return g.commitAfter(ctx, commitString, nil, false)
+	}
+
+	entries, err := g.commitHistory()
+	if err != nil {
+		return nil, err
+	}
+
+	return g.commitAfter(ctx, commitString, entries, true)
+}
+
+// commitAfter is commitWith, given the commit history read before committing when tracked. WaitForCommitComplete
+// waits for the history to move past it.
+func (g *GoNCClient) commitAfter(ctx context.Context, commitString string, before []CommitEntry, tracked bool) (*CommitResult, error) {
+	backoff := g.CommitRetryBackoff
+	if backoff == 0 {
+		backoff = time.Second
+	}
+
+	for attempt := 0; ; attempt++ {
+		var result *CommitResult
+
+		reply, err := g.Driver.SendRaw(commitString)
+		if err == nil {
+			result, err = parseCommitReply(reply)
+		}
+
+		if err == nil {
+			g.committed, g.tracked, g.commitBase = true, tracked, before
+			g.settle(ctx)
+		}
+
+		if err == nil || !isLockDenied(err) {
+			return result, err
+		}
+
+		if attempt >= g.CommitRetries {
+			return nil, newLockDeniedError(err, attempt+1)
+		}
+
+		// Stop retrying once the caller gives up, releasing the session for the next operation
+		select {
+		case <-ctx.Done():
+			return nil, newLockDeniedError(err, attempt+1)
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// settle waits PostCommitDelay for the device to settle after a commit, or until ctx is done
+func (g *GoNCClient) settle(ctx context.Context) {
+	if g.PostCommitDelay <= 0 {
+		return
+	}
+
+	t := time.NewTimer(g.PostCommitDelay)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,927 @@
+package junos_helpers
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	testdriver "github.com/davedotdev/go-netconf/helpers/internal/testdriver"
+	rpc "github.com/davedotdev/go-netconf/rpc"
+)
+
+const commitSuccessReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:junos="http://xml.juniper.net/junos/18.2R1/junos">
+<commit-results>
+<routing-engine junos:style="normal">
+<name>re0</name>
+<commit-success/>
+</routing-engine>
+</commit-results>
+</rpc-reply>`
+
+const commitSequenceReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:junos="http://xml.juniper.net/junos/18.2R1/junos">
+<commit-results>
+<routing-engine junos:style="normal">
+<name>re0</name>
+<sequence-number>1042</sequence-number>
+<commit-success/>
+</routing-engine>
+</commit-results>
+</rpc-reply>`
+
+const commitConfirmedReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:junos="http://xml.juniper.net/junos/18.2R1/junos">
+<commit-results>
+<rpc-error>
+<error-type>protocol</error-type>
+<error-tag>operation-failed</error-tag>
+<error-severity>warning</error-severity>
+<error-message>
+configuration database modified
+</error-message>
+</rpc-error>
+<routing-engine junos:style="normal">
+<name>re0</name>
+<commit-success/>
+<message>commit confirmed will be automatically rolled back in 10 minutes unless confirmed</message>
+</routing-engine>
+</commit-results>
+</rpc-reply>`
+
+const commitErrorReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<rpc-error>
+<error-type>protocol</error-type>
+<error-tag>operation-failed</error-tag>
+<error-severity>error</error-severity>
+<error-message>configuration check-out failed</error-message>
+</rpc-error>
+</rpc-reply>`
+
+func TestSendCommitResult(t *testing.T) {
+	tt := []struct {
+		name     string
+		reply    string
+		expected CommitResult
+	}{
+		{
+			name:     "ok",
+			reply:    okReply,
+			expected: CommitResult{Complete: true},
+		},
+		{
+			name:     "commit-success",
+			reply:    commitSuccessReply,
+			expected: CommitResult{Complete: true},
+		},
+		{
+			name:     "sequence-number",
+			reply:    commitSequenceReply,
+			expected: CommitResult{Complete: true, SequenceNumber: 1042},
+		},
+		{
+			name:  "confirmed",
+			reply: commitConfirmedReply,
+			expected: CommitResult{
+				Complete:         true,
+				Warnings:         []string{"configuration database modified"},
+				DatabaseModified: true,
+				RollbackMinutes:  10,
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g, fd := newTestClient(tc.reply)
+
+			result, err := g.SendCommitResult()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if result.Complete != tc.expected.Complete ||
+				result.DatabaseModified != tc.expected.DatabaseModified ||
+				result.RollbackMinutes != tc.expected.RollbackMinutes ||
+				result.SequenceNumber != tc.expected.SequenceNumber ||
+				len(result.Warnings) != len(tc.expected.Warnings) {
+				t.Errorf("got %+v, expected %+v", result, tc.expected)
+			}
+
+			for i := range tc.expected.Warnings {
+				if result.Warnings[i] != tc.expected.Warnings[i] {
+					t.Errorf("got warning %q, expected %q", result.Warnings[i], tc.expected.Warnings[i])
+				}
+			}
+
+			if fd.Dials != 1 || fd.Closes != 1 {
+				t.Errorf("got %d dials and %d closes, expected one of each", fd.Dials, fd.Closes)
+			}
+		})
+	}
+}
+
+func TestSendCommitFull(t *testing.T) {
+	g, fd := newTestClient(commitSuccessReply)
+
+	err := g.SendCommitFull()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(fd.Sent) != 1 || fd.Sent[0] != "<commit><full/></commit>" {
+		t.Errorf("got RPCs %q, expected a single commit full", fd.Sent)
+	}
+
+	g, _ = newTestClient(commitErrorReply)
+
+	err = g.SendCommitFull()
+	if err == nil {
+		t.Error("expected the failed commit to be returned")
+	}
+}
+
+func TestSendCommitError(t *testing.T) {
+	g, fd := newTestClient(commitErrorReply)
+
+	err := g.SendCommit()
+
+	var rpcErr *rpc.RPCError
+	if !errors.As(err, &rpcErr) {
+		t.Fatalf("got error %v, expected *rpc.RPCError", err)
+	}
+
+	if rpcErr.Message != "configuration check-out failed" {
+		t.Errorf("got message %q, expected configuration check-out failed", rpcErr.Message)
+	}
+
+	if fd.Closes != 1 {
+		t.Errorf("got %d closes, expected the driver to be closed after the error", fd.Closes)
+	}
+}
+
+func TestSendCommitIncomplete(t *testing.T) {
+	g, _ := newTestClient(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><commit-results></commit-results></rpc-reply>`)
+
+	err := g.SendCommit()
+	if err == nil {
+		t.Fatal("expected an error when the commit does not report completion")
+	}
+}
+
+const lockDeniedReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<rpc-error>
+<error-type>protocol</error-type>
+<error-tag>lock-denied</error-tag>
+<error-severity>error</error-severity>
+<error-message>
+configuration database locked by:
+  admin terminal pts/0 (pid 4242) on since 2020-06-01 10:00:00 UTC
+      exclusive [edit]
+</error-message>
+<error-info>
+<session-id>4242</session-id>
+</error-info>
+</rpc-error>
+</rpc-reply>`
+
+func TestSendCommitLockRetry(t *testing.T) {
+	g, fd := newTestClient(lockDeniedReply, lockDeniedReply, okReply)
+	g.CommitRetries = 3
+	g.CommitRetryBackoff = time.Millisecond
+
+	err := g.SendCommit()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(fd.Sent) != 3 {
+		t.Errorf("got %d commits sent, expected 3", len(fd.Sent))
+	}
+}
+
+func TestSendTransactionLockRetriesExhausted(t *testing.T) {
+	g, fd := newTestClient(loadSuccessReply, lockDeniedReply, lockDeniedReply)
+	g.CommitRetries = 1
+	g.CommitRetryBackoff = time.Millisecond
+
+	err := g.SendTransaction("", struct {
+		XMLName xml.Name `xml:"configuration"`
+	}{}, true)
+	if !errors.Is(err, ErrLockDenied) {
+		t.Fatalf("got error %v, expected %v", err, ErrLockDenied)
+	}
+
+	var lde *LockDeniedError
+	if !errors.As(err, &lde) || lde.HolderSessionID != 4242 || lde.Attempts != 2 {
+		t.Errorf("got error %#v, expected a LockDeniedError held by session 4242 after 2 attempts", err)
+	}
+
+	// The load followed by the first commit and a single retry
+	if len(fd.Sent) != 3 {
+		t.Errorf("got %d RPCs sent, expected 3", len(fd.Sent))
+	}
+}
+
+func TestLockDeniedError(t *testing.T) {
+	tt := []struct {
+		name     string
+		reply    string
+		holder   uint32
+		expected string
+	}{
+		{
+			name:     "holder",
+			reply:    lockDeniedReply,
+			holder:   4242,
+			expected: "configuration database locked by another session 4242 after 1 attempt(s)",
+		},
+		{
+			name: "noHolder",
+			reply: `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<rpc-error>
+<error-type>protocol</error-type>
+<error-tag>lock-denied</error-tag>
+<error-severity>error</error-severity>
+<error-message>configuration database locked</error-message>
+</rpc-error>
+</rpc-reply>`,
+			expected: "configuration database locked by another session after 1 attempt(s)",
+		},
+		{
+			// The lock-denied is found behind another error of the same reply
+			name: "afterOtherError",
+			reply: `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<rpc-error>
+<error-type>application</error-type>
+<error-severity>error</error-severity>
+<error-message>statement not applied</error-message>
+</rpc-error>
+<rpc-error>
+<error-type>protocol</error-type>
+<error-tag>lock-denied</error-tag>
+<error-severity>error</error-severity>
+<error-message>configuration database locked</error-message>
+<error-info>
+<session-id>4242</session-id>
+</error-info>
+</rpc-error>
+</rpc-reply>`,
+			holder:   4242,
+			expected: "configuration database locked by another session 4242 after 1 attempt(s)",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g, _ := newTestClient(tc.reply)
+
+			err := g.Dial()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			err = g.LockGroup("test-group")
+
+			var lde *LockDeniedError
+			if !errors.As(err, &lde) {
+				t.Fatalf("got error %v, expected a LockDeniedError", err)
+			}
+			if lde.HolderSessionID != tc.holder {
+				t.Errorf("got holder session-id %d, expected %d", lde.HolderSessionID, tc.holder)
+			}
+			if !errors.Is(err, ErrLockDenied) {
+				t.Errorf("got error %v, expected it to match %v", err, ErrLockDenied)
+			}
+			if !strings.HasPrefix(err.Error(), tc.expected) {
+				t.Errorf("got error %q, expected it to start with %q", err, tc.expected)
+			}
+
+			if rpc.FindRPCError(err, func(e *rpc.RPCError) bool { return e.Tag == "lock-denied" }) == nil {
+				t.Error("expected the device's rpc-error to be wrapped")
+			}
+		})
+	}
+}
+
+func TestCommitAndClose(t *testing.T) {
+	g, fd := newTestClient(commitSuccessReply, okReply)
+	// Neither adds a commit history read to the sequence
+	g.GuardConfirmedCommit = true
+	g.TrackCommits = true
+
+	err := g.Dial()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := g.CommitAndClose()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result == nil || !result.Complete {
+		t.Errorf("got result %+v, expected a successful commit", result)
+	}
+
+	expected := []string{commitStr, "<close-session/>"}
+	if strings.Join(fd.Sent, ",") != strings.Join(expected, ",") {
+		t.Errorf("got RPCs %q, expected %q", fd.Sent, expected)
+	}
+
+	if fd.Closes != 1 || g.sessionOpen {
+		t.Errorf("got %d closes with session open %v, expected the session held by Dial to be closed", fd.Closes, g.sessionOpen)
+	}
+}
+
+func TestPostCommitDelay(t *testing.T) {
+	const delay = 100 * time.Millisecond
+
+	g, _ := newTestClient(commitSuccessReply, commitErrorReply)
+	g.PostCommitDelay = delay
+
+	start := time.Now()
+	_, err := g.SendCommitResult()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < delay {
+		t.Errorf("successful commit returned after %v, expected it to wait %v", elapsed, delay)
+	}
+
+	start = time.Now()
+	_, err = g.SendCommitResult()
+	if err == nil {
+		t.Fatal("expected the commit to fail")
+	}
+	if elapsed := time.Since(start); elapsed >= delay {
+		t.Errorf("failed commit returned after %v, expected no delay", elapsed)
+	}
+}
+
+func TestPostCommitDelayCancelled(t *testing.T) {
+	g, _ := newTestClient(commitSuccessReply)
+	g.PostCommitDelay = time.Minute
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	g.SendCommitContext(ctx)
+
+	// The client is only released once the delay is over
+	g.Lock.Lock()
+	g.Lock.Unlock()
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("commit returned after %v, expected the context to cut the delay short", elapsed)
+	}
+}
+
+func TestSendCommitOtherErrorNotRetried(t *testing.T) {
+	g, fd := newTestClient(commitErrorReply, okReply)
+	g.CommitRetries = 3
+	g.CommitRetryBackoff = time.Millisecond
+
+	err := g.SendCommit()
+	if err == nil || errors.Is(err, ErrLockDenied) {
+		t.Fatalf("got error %v, expected the commit error", err)
+	}
+
+	if len(fd.Sent) != 1 {
+		t.Errorf("got %d commits sent, expected no retry", len(fd.Sent))
+	}
+}
+
+const commitInformationReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:junos="http://xml.juniper.net/junos/18.4R1/junos">
+<commit-information>
+<commit-history>
+<sequence-number>0</sequence-number>
+<user>netconf</user>
+<client>netconf</client>
+<date-time junos:seconds="1591005600">2020-06-01 10:00:00 UTC</date-time>
+<log>terraform apply</log>
+</commit-history>
+<commit-history>
+<sequence-number>1</sequence-number>
+<user>admin</user>
+<client>cli</client>
+<date-time junos:seconds="1590919200">2020-05-31 10:00:00 UTC</date-time>
+</commit-history>
+</commit-information>
+</rpc-reply>`
+
+func TestGetCommitHistory(t *testing.T) {
+	g, fd := newTestClient(commitInformationReply)
+
+	entries, err := g.GetCommitHistory()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(fd.Sent) != 1 || fd.Sent[0] != getCommitInformationStr {
+		t.Errorf("got RPC %q, expected %q", fd.Sent, getCommitInformationStr)
+	}
+
+	expected := []CommitEntry{
+		{
+			RollbackIndex: 0,
+			User:          "netconf",
+			Client:        "netconf",
+			DateTime:      "2020-06-01 10:00:00 UTC",
+			Time:          time.Date(2020, 6, 1, 10, 0, 0, 0, time.UTC),
+			Comment:       "terraform apply",
+		},
+		{
+			RollbackIndex: 1,
+			User:          "admin",
+			Client:        "cli",
+			DateTime:      "2020-05-31 10:00:00 UTC",
+			Time:          time.Date(2020, 5, 31, 10, 0, 0, 0, time.UTC),
+		},
+	}
+
+	if len(entries) != len(expected) {
+		t.Fatalf("got %d entries, expected %d", len(entries), len(expected))
+	}
+	for i := range expected {
+		if entries[i] != expected[i] {
+			t.Errorf("got %+v, expected %+v", entries[i], expected[i])
+		}
+	}
+}
+
+const rollbackCompareReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:junos="http://xml.juniper.net/junos/18.4R1/junos">
+<rollback-information>
+<ok/>
+<configuration-information>
+<configuration-output>
+[edit system]
+-  host-name r1;
++  host-name r2;
+</configuration-output>
+</configuration-information>
+</rollback-information>
+</rpc-reply>`
+
+func TestGetCommitDiff(t *testing.T) {
+	g, fd := newTestClient(rollbackCompareReply)
+
+	diff, err := g.GetCommitDiff(3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedRPC := "<get-rollback-information>\n  <rollback>4</rollback>\n  <compare>3</compare>\n</get-rollback-information>\n"
+	if len(fd.Sent) != 1 || fd.Sent[0] != expectedRPC {
+		t.Errorf("got RPCs %q, expected %q", fd.Sent, expectedRPC)
+	}
+
+	expected := "[edit system]\n-  host-name r1;\n+  host-name r2;"
+	if diff != expected {
+		t.Errorf("got diff %q, expected %q", diff, expected)
+	}
+
+	// The oldest configuration kept has nothing before it, the newest can always be compared
+	for _, rollback := range []int{-1, maxRollback, maxRollback + 1} {
+		g, fd := newTestClient()
+
+		_, err := g.GetCommitDiff(rollback)
+		if err == nil {
+			t.Errorf("expected an error for commit %d", rollback)
+		}
+		if len(fd.Sent) != 0 {
+			t.Errorf("got RPCs %q, expected none for commit %d", fd.Sent, rollback)
+		}
+	}
+
+	g, _ = newTestClient(okReply)
+	_, err = g.GetCommitDiff(maxRollback - 1)
+	if err == nil {
+		t.Error("expected an error for a reply without configuration-output")
+	}
+}
+
+func TestCancelCommit(t *testing.T) {
+	tt := []struct {
+		name      string
+		persistID string
+		expected  string
+	}{
+		{name: "session", expected: "<cancel-commit/>"},
+		{name: "persist", persistID: "change-42", expected: "<cancel-commit><persist-id>change-42</persist-id></cancel-commit>"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g, fd := newTestClient()
+
+			err := g.CancelCommit(tc.persistID)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(fd.Sent) != 1 || fd.Sent[0] != tc.expected {
+				t.Errorf("got RPC %q, expected %q", fd.Sent, tc.expected)
+			}
+		})
+	}
+}
+
+const confirmedCommitReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:junos="http://xml.juniper.net/junos/18.4R1/junos">
+<commit-results>
+<routing-engine junos:style="normal">
+<name>re0</name>
+<rpc-error>
+<error-severity>warning</error-severity>
+<error-message>
+commit confirmed will be automatically rolled back in 5 minutes unless confirmed
+</error-message>
+</rpc-error>
+<commit-success/>
+</routing-engine>
+</commit-results>
+</rpc-reply>`
+
+func TestConfirmedCommitPersist(t *testing.T) {
+	g, fd := newTestClient(confirmedCommitReply)
+
+	result, err := g.ConfirmedCommit(5*time.Minute, "change-42")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.RollbackMinutes != 5 {
+		t.Errorf("got %d rollback minutes, expected 5", result.RollbackMinutes)
+	}
+
+	// A second client confirms it from another session
+	other, otherFd := newTestClient()
+
+	_, err = other.ConfirmCommit("change-42")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "<commit><confirmed/><confirm-timeout>300</confirm-timeout><persist>change-42</persist></commit>"
+	if len(fd.Sent) != 1 || fd.Sent[0] != expected {
+		t.Errorf("got RPC %q, expected %q", fd.Sent, expected)
+	}
+
+	expected = "<commit><persist-id>change-42</persist-id></commit>"
+	if len(otherFd.Sent) != 1 || otherFd.Sent[0] != expected {
+		t.Errorf("got RPC %q, expected %q", otherFd.Sent, expected)
+	}
+}
+
+// holdCommits makes the fake driver wait on the returned channel before replying to a commit
+func holdCommits(fd *testdriver.Driver) chan struct{} {
+	release := make(chan struct{})
+	fd.Delay = func(rawxml string) {
+		if strings.HasPrefix(rawxml, "<commit") {
+			<-release
+		}
+	}
+	return release
+}
+
+func TestSendCommitContext(t *testing.T) {
+	g, fd := newTestClient(commitSuccessReply)
+
+	result, err := g.SendCommitContext(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Complete {
+		t.Errorf("got incomplete commit, expected complete")
+	}
+
+	if fd.Dials != 1 || fd.Closes != 1 {
+		t.Errorf("got %d dials and %d closes, expected 1 of each", fd.Dials, fd.Closes)
+	}
+}
+
+func TestSendCommitContextLockRetry(t *testing.T) {
+	g, fd := newTestClient(lockDeniedReply, lockDeniedReply)
+	g.CommitRetries = 5
+	g.CommitRetryBackoff = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// Either the caller gives up first or the retry loop ends with the lock still denied
+	_, err := g.SendCommitContext(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, ErrLockDenied) {
+		t.Fatalf("got error %v, expected %v or %v", err, context.DeadlineExceeded, ErrLockDenied)
+	}
+
+	// The retry backoff ends with ctx, so the client is released without waiting it out
+	released := make(chan struct{})
+	go func() {
+		g.Lock.Lock()
+		close(released)
+		g.Lock.Unlock()
+	}()
+
+	select {
+	case <-released:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the abandoned commit to release the client")
+	}
+
+	if len(fd.Sent) != 1 {
+		t.Errorf("got RPCs %q, expected a single commit", fd.Sent)
+	}
+}
+
+func TestCommitContextTimeout(t *testing.T) {
+	tt := []struct {
+		name     string
+		commit   func(g *GoNCClient, ctx context.Context) (*CommitResult, error)
+		reply    string
+		expected []string
+	}{
+		{
+			name: "commit",
+			commit: func(g *GoNCClient, ctx context.Context) (*CommitResult, error) {
+				return g.SendCommitContext(ctx)
+			},
+			reply:    commitSuccessReply,
+			expected: []string{"<commit/>"},
+		},
+		{
+			name: "confirmed",
+			commit: func(g *GoNCClient, ctx context.Context) (*CommitResult, error) {
+				return g.ConfirmedCommitContext(ctx, 5*time.Minute, "change-42")
+			},
+			reply: confirmedCommitReply,
+			expected: []string{
+				"<commit><confirmed/><confirm-timeout>300</confirm-timeout><persist>change-42</persist></commit>",
+				"<cancel-commit><persist-id>change-42</persist-id></cancel-commit>",
+			},
+		},
+		{
+			name: "confirmed failed",
+			commit: func(g *GoNCClient, ctx context.Context) (*CommitResult, error) {
+				return g.ConfirmedCommitContext(ctx, 5*time.Minute, "")
+			},
+			reply:    commitErrorReply,
+			expected: []string{"<commit><confirmed/><confirm-timeout>300</confirm-timeout></commit>"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g, fd := newTestClient(tc.reply)
+			release := holdCommits(fd)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			_, err := tc.commit(g, ctx)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("got error %v, expected %v", err, context.DeadlineExceeded)
+			}
+
+			// The delayed reply arrives, the cleanup runs in the background and releases the client
+			close(release)
+			g.Lock.Lock()
+			defer g.Lock.Unlock()
+
+			if len(fd.Sent) != len(tc.expected) {
+				t.Fatalf("got RPCs %q, expected %q", fd.Sent, tc.expected)
+			}
+			for i := range tc.expected {
+				if fd.Sent[i] != tc.expected[i] {
+					t.Errorf("got RPC %q, expected %q", fd.Sent[i], tc.expected[i])
+				}
+			}
+
+			if fd.Closes != 1 {
+				t.Errorf("got %d closes, expected 1", fd.Closes)
+			}
+		})
+	}
+}
+
+// commitHistoryReply returns a get-commit-information reply of commits made at times, the latest first
+func commitHistoryReply(times ...time.Time) string {
+	var entries strings.Builder
+	for i, t := range times {
+		fmt.Fprintf(&entries, `<commit-history>
+<sequence-number>%d</sequence-number>
+<user>netconf</user>
+<client>netconf</client>
+<date-time junos:seconds="%d">%s</date-time>
+</commit-history>
+`, i, t.Unix(), t.UTC().Format("2006-01-02 15:04:05 MST"))
+	}
+
+	return `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:junos="http://xml.juniper.net/junos/18.2R1/junos">
+<commit-information>
+` + entries.String() + `</commit-information>
+</rpc-reply>`
+}
+
+func TestWaitForCommitComplete(t *testing.T) {
+	now := time.Now()
+	earlier := now.Add(-time.Hour)
+	previous := commitHistoryReply(earlier)
+
+	// The device clock runs half an hour behind the client's, only the change in the history counts
+	g, fd := newTestClient(previous, okReply, previous, previous, commitHistoryReply(now.Add(-30*time.Minute), earlier))
+	g.CommitPollInterval = time.Millisecond
+	g.TrackCommits = true
+
+	err := g.SendCommit()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err = g.WaitForCommitComplete(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	polls := 0
+	for _, sent := range fd.Sent {
+		if sent == getCommitInformationStr {
+			polls++
+		}
+	}
+	// The read before committing and three polls
+	if polls != 4 {
+		t.Errorf("got %d history reads, expected 4", polls)
+	}
+}
+
+func TestWaitForCommitCompleteSameSecond(t *testing.T) {
+	// A second uncommented commit by the same user within the same second looks like the previous top
+	at := time.Now().Add(-time.Hour)
+	previous := commitHistoryReply(at)
+
+	g, fd := newTestClient(previous, okReply, previous, commitHistoryReply(at, at))
+	g.CommitPollInterval = time.Millisecond
+	g.TrackCommits = true
+
+	err := g.SendCommit()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err = g.WaitForCommitComplete(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(fd.Sent) != 4 {
+		t.Errorf("got RPCs %q, expected the read before committing, the commit and two polls", fd.Sent)
+	}
+}
+
+func TestWaitForCommitCompleteTimeout(t *testing.T) {
+	previous := commitHistoryReply(time.Now().Add(-time.Hour))
+
+	g, fd := newTestClient(previous, okReply)
+	g.CommitPollInterval = time.Millisecond
+	g.TrackCommits = true
+
+	err := g.SendCommit()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The history never advances past the previous commit
+	fd.Delay = func(rawxml string) {
+		fd.Replies = []string{previous}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err = g.WaitForCommitComplete(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, expected %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestWaitForCommitCompleteNotTracked(t *testing.T) {
+	g, fd := newTestClient()
+
+	err := g.SendCommit()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Without TrackCommits nothing but the commit is sent
+	if len(fd.Sent) != 1 || fd.Sent[0] != commitStr {
+		t.Errorf("got RPCs %q, expected only the commit", fd.Sent)
+	}
+
+	err = g.WaitForCommitComplete(context.Background())
+	if !errors.Is(err, ErrCommitNotTracked) {
+		t.Errorf("got error %v, expected %v", err, ErrCommitNotTracked)
+	}
+}
+
+func TestWaitForCommitCompleteNoCommit(t *testing.T) {
+	g, fd := newTestClient()
+
+	err := g.WaitForCommitComplete(context.Background())
+	if !errors.Is(err, ErrNoCommit) {
+		t.Errorf("got error %v, expected %v", err, ErrNoCommit)
+	}
+
+	if len(fd.Sent) != 0 {
+		t.Errorf("got RPCs %q, expected none", fd.Sent)
+	}
+}
+
+const pendingConfirmReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:junos="http://xml.juniper.net/junos/18.4R1/junos">
+<commit-information>
+<commit-history>
+<sequence-number>0</sequence-number>
+<user>admin</user>
+<client>cli</client>
+<date-time junos:seconds="1591005600">2020-06-01 10:00:00 UTC</date-time>
+<comment>commit confirmed, rollback in 10mins</comment>
+</commit-history>
+<commit-history>
+<sequence-number>1</sequence-number>
+<user>netconf</user>
+<client>netconf</client>
+<date-time junos:seconds="1591002000">2020-06-01 09:00:00 UTC</date-time>
+</commit-history>
+</commit-information>
+</rpc-reply>`
+
+func TestGuardConfirmedCommit(t *testing.T) {
+	tt := []struct {
+		name    string
+		replies []string
+		call    func(g *GoNCClient) error
+	}{
+		{name: "SendCommit", replies: []string{pendingConfirmReply}, call: func(g *GoNCClient) error { return g.SendCommit() }},
+		{name: "SendTransaction", replies: []string{loadSuccessReply, pendingConfirmReply}, call: func(g *GoNCClient) error {
+			return g.SendTransaction("", struct {
+				XMLName xml.Name `xml:"configuration"`
+			}{}, true)
+		}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g, fd := newTestClient(tc.replies...)
+			g.GuardConfirmedCommit = true
+
+			err := tc.call(g)
+			if !errors.Is(err, ErrConfirmedCommitPending) {
+				t.Fatalf("got error %v, expected %v", err, ErrConfirmedCommitPending)
+			}
+
+			for _, sent := range fd.Sent {
+				if sent == commitStr {
+					t.Errorf("unexpected commit while a confirmed commit is pending")
+				}
+			}
+		})
+	}
+}
+
+func TestGuardConfirmedCommitAllowsConfirm(t *testing.T) {
+	g, fd := newTestClient(pendingConfirmReply)
+	g.GuardConfirmedCommit = true
+
+	entries, err := g.GetCommitHistory()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !entries[0].PendingConfirm || entries[1].PendingConfirm {
+		t.Errorf("got %+v, expected only the latest commit to be pending confirmation", entries)
+	}
+
+	_, err = g.ConfirmCommit("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(fd.Sent) != 2 || fd.Sent[1] != commitStr {
+		t.Errorf("got RPCs %q, expected ConfirmCommit to commit without checking the history", fd.Sent)
+	}
+
+	// Without a pending confirmed commit the guarded commit goes ahead
+	g, fd = newTestClient(commitInformationReply)
+	g.GuardConfirmedCommit = true
+
+	err = g.SendCommit()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(fd.Sent) != 2 || fd.Sent[0] != getCommitInformationStr || fd.Sent[1] != commitStr {
+		t.Errorf("got RPCs %q, expected the history to be checked before committing", fd.Sent)
+	}
+}
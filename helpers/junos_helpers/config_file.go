@@ -0,0 +1,61 @@
+package junos_helpers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// formatByExtension maps config file extensions to the format SendConfigFile loads them in
+var formatByExtension = map[string]string{
+	".xml":  FormatXML,
+	".conf": FormatText,
+	".cfg":  FormatText,
+	".txt":  FormatText,
+	".set":  FormatSet,
+	".json": FormatJSON,
+}
+
+// SendConfigFile merges the configuration in the file at path into the candidate and optionally commits it.
+// An empty format is inferred from the file extension: .xml, .conf/.cfg/.txt (curly brace text), .set or .json.
+// Text, set and JSON configurations are wrapped in the element Junos expects, an XML file must hold a
+// <configuration> element. The whole file is read into memory before it is sent.
+func (g *GoNCClient) SendConfigFile(path string, format string, commit bool) (string, error) {
+	if format == "" {
+		var ok bool
+		format, ok = formatByExtension[strings.ToLower(filepath.Ext(path))]
+		if !ok {
+			return "", fmt.Errorf("unable to infer the configuration format of %s, expected a .xml, .conf, .cfg, .txt, .set or .json file", path)
+		}
+	}
+
+	err := validateFormat(format)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		return "", fmt.Errorf("config file %s does not exist: %w", path, err)
+	case os.IsPermission(err):
+		return "", fmt.Errorf("no permission to read config file %s: %w", path, err)
+	case err != nil:
+		return "", fmt.Errorf("unable to read config file %s: %w", path, err)
+	}
+
+	config := string(data)
+
+	switch format {
+	case FormatText:
+		return g.LoadConfig("<configuration-text>"+xmlEscape(config)+"</configuration-text>", LoadMerge, FormatText, commit)
+	case FormatSet:
+		return g.LoadConfig("<configuration-set>"+xmlEscape(config)+"</configuration-set>", LoadSet, FormatText, commit)
+	case FormatJSON:
+		return g.LoadConfig("<configuration-json>"+xmlEscape(config)+"</configuration-json>", LoadMerge, FormatJSON, commit)
+	}
+
+	return g.LoadConfig(config, LoadMerge, FormatXML, commit)
+}
@@ -0,0 +1,101 @@
+package junos_helpers
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSendConfigFile(t *testing.T) {
+	tt := []struct {
+		name     string
+		ext      string
+		config   string
+		expected []string
+	}{
+		{
+			name:     "xml",
+			ext:      ".xml",
+			config:   "<configuration><system><host-name>r1</host-name></system></configuration>",
+			expected: []string{`action="merge" format="xml"`, "<configuration><system><host-name>r1</host-name></system></configuration>"},
+		},
+		{
+			name:     "text",
+			ext:      ".conf",
+			config:   "system { host-name r1; }",
+			expected: []string{`action="merge" format="text"`, "<configuration-text>system { host-name r1; }</configuration-text>"},
+		},
+		{
+			name:     "set",
+			ext:      ".set",
+			config:   "set system host-name r1 & r2",
+			expected: []string{`action="set" format="text"`, "<configuration-set>set system host-name r1 &amp; r2</configuration-set>"},
+		},
+		{
+			name:     "json",
+			ext:      ".json",
+			config:   `{"configuration": {"system": {"host-name": "r1"}}}`,
+			expected: []string{`action="merge" format="json"`, "<configuration-json>{&#34;configuration&#34;"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := ioutil.TempFile("", "config-*"+tc.ext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(f.Name())
+
+			_, err = f.WriteString(tc.config)
+			f.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			g, fd := newTestClient(loadSuccessReply)
+
+			_, err = g.SendConfigFile(f.Name(), "", false)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(fd.Sent) != 1 {
+				t.Fatalf("got %d RPCs, expected only the load", len(fd.Sent))
+			}
+
+			for _, s := range tc.expected {
+				if !strings.Contains(fd.Sent[0], s) {
+					t.Errorf("got %q, expected it to contain %q", fd.Sent[0], s)
+				}
+			}
+		})
+	}
+}
+
+func TestSendConfigFileErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	g, fd := newTestClient()
+
+	_, err = g.SendConfigFile(filepath.Join(dir, "missing.xml"), "", true)
+	if !errors.Is(err, os.ErrNotExist) || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("got error %v, expected a missing file error", err)
+	}
+
+	_, err = g.SendConfigFile(filepath.Join(dir, "config.yaml"), "", true)
+	if err == nil || !strings.Contains(err.Error(), "unable to infer") {
+		t.Errorf("got error %v, expected the format to be rejected", err)
+	}
+
+	if len(fd.Sent) != 0 {
+		t.Errorf("got %d RPCs, expected none", len(fd.Sent))
+	}
+}
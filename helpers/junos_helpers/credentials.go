@@ -0,0 +1,133 @@
+package junos_helpers
+
+import (
+	"fmt"
+	"os"
+
+	sshdriver "github.com/davedotdev/go-netconf/drivers/ssh"
+	keyring "github.com/zalando/go-keyring"
+	"golang.org/x/crypto/ssh"
+)
+
+// Environment variables read by NewClientFromEnv
+const (
+	EnvUsername = "NETCONF_USERNAME"
+	EnvPassword = "NETCONF_PASSWORD"
+	EnvSSHKey   = "NETCONF_SSH_KEY" // Path to a private key file
+)
+
+// NewClientFromEnv returns a client built from opts, filling any unset credentials from the
+// NETCONF_USERNAME, NETCONF_PASSWORD and NETCONF_SSH_KEY environment variables so secrets stay out of source.
+func NewClientFromEnv(opts ClientOptions) (*GoNCClient, error) {
+	if opts.Username == "" {
+		opts.Username = os.Getenv(EnvUsername)
+	}
+
+	if opts.Password == "" && opts.SSHKey == "" {
+		opts.Password = os.Getenv(EnvPassword)
+		opts.SSHKey = os.Getenv(EnvSSHKey)
+	}
+
+	if opts.Username == "" {
+		return nil, fmt.Errorf("no username given and %s is not set", EnvUsername)
+	}
+
+	if opts.Password == "" && opts.SSHKey == "" {
+		return nil, fmt.Errorf("no credentials given and neither %s nor %s is set", EnvPassword, EnvSSHKey)
+	}
+
+	return newClientWithOptions(opts)
+}
+
+// NewClientFromKeyring returns a client built from opts, authenticating as account with the password
+// stored in the OS keyring (macOS Keychain, Windows Credential Manager or the Secret Service on Linux) under service.
+func NewClientFromKeyring(service string, account string, opts ClientOptions) (*GoNCClient, error) {
+	password, err := keyring.Get(service, account)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read password for %s from keyring service %s: %v", account, service, err)
+	}
+
+	opts.Username = account
+	opts.Password = password
+	opts.SSHKey = ""
+
+	return newClientWithOptions(opts)
+}
+
+const changePasswordStr = `<configuration>
+  <system>
+    <login>
+      <user>
+        <name>%s</name>
+        <authentication>
+          <plain-text-password-value>%s</plain-text-password-value>
+        </authentication>
+      </user>
+    </login>
+  </system>
+</configuration>`
+
+// ChangeLocalPassword sets the password of the user the client logs in as and commits it. The open session
+// stays authenticated, so when updateCredentials is set the client's stored password is also replaced so
+// any later reconnect, Clone or Reset authenticates with the new one. The password is sent in plain text
+// within the SSH session and redacted from the Debug transcript and recorded scripts. A failed load or
+// commit is discarded from the candidate.
+func (g *GoNCClient) ChangeLocalPassword(password string, updateCredentials bool) error {
+	if g.options.Username == "" {
+		return fmt.Errorf("client has no username to change the password of")
+	}
+
+	if password == "" {
+		return fmt.Errorf("password is empty")
+	}
+
+	opts := g.options
+	opts.Password = password
+
+	// Build the new authentication before changing anything, so a failure here leaves the device untouched
+	var auth []ssh.AuthMethod
+	if updateCredentials {
+		var err error
+		auth, err = authMethods(opts, g.agent)
+		if err != nil {
+			return err
+		}
+	}
+
+	return g.withSession(func() error {
+		config := fmt.Sprintf(changePasswordStr, xmlEscape(g.options.Username), xmlEscape(password))
+
+		reply, err := g.Driver.SendRaw(buildLoadConfiguration(LoadMerge, FormatXML, config))
+		if err != nil {
+			return fmt.Errorf("driver error: %w", err)
+		}
+
+		// Never leave the password change pending in the candidate, a later commit would apply it
+		err = checkLoadResults(reply.Data)
+		if err != nil {
+			g.Driver.SendRaw(discardChangesStr)
+			return err
+		}
+
+		g.lastEdit = datastoreCandidate
+
+		_, err = g.commit()
+		if err != nil {
+			g.Driver.SendRaw(discardChangesStr)
+			return fmt.Errorf("driver error: %w", err)
+		}
+
+		if updateCredentials {
+			g.options.Password = password
+
+			// Replace rather than modify the SSH config, clones may share it
+			if nc, ok := g.Driver.(*sshdriver.DriverSSH); ok && nc.SSHConfig != nil {
+				config := *nc.SSHConfig
+				config.Auth = auth
+				nc.SSHConfig = &config
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,159 @@
+package junos_helpers
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	sshdriver "github.com/davedotdev/go-netconf/drivers/ssh"
+	keyring "github.com/zalando/go-keyring"
+)
+
+// setenv sets the environment variables for the duration of a test
+func setenv(t *testing.T, vars map[string]string) {
+	for k, v := range vars {
+		old, ok := os.LookupEnv(k)
+		os.Setenv(k, v)
+
+		k := k
+		t.Cleanup(func() {
+			if ok {
+				os.Setenv(k, old)
+			} else {
+				os.Unsetenv(k)
+			}
+		})
+	}
+}
+
+func TestNewClientFromEnv(t *testing.T) {
+	setenv(t, map[string]string{
+		EnvUsername: "admin",
+		EnvPassword: "secret",
+		EnvSSHKey:   "",
+	})
+
+	g, err := NewClientFromEnv(ClientOptions{Address: "192.0.2.1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if g.options.Username != "admin" || g.options.Password != "secret" {
+		t.Errorf("got credentials %s/%s, expected admin/secret", g.options.Username, g.options.Password)
+	}
+
+	nc := g.Driver.(*sshdriver.DriverSSH)
+	if nc.SSHConfig.User != "admin" {
+		t.Errorf("got user %s, expected admin", nc.SSHConfig.User)
+	}
+}
+
+func TestNewClientFromEnvMissing(t *testing.T) {
+	setenv(t, map[string]string{
+		EnvUsername: "admin",
+		EnvPassword: "",
+		EnvSSHKey:   "",
+	})
+
+	_, err := NewClientFromEnv(ClientOptions{Address: "192.0.2.1"})
+	if err == nil {
+		t.Errorf("expected an error without a password or key")
+	}
+}
+
+func TestNewClientFromKeyring(t *testing.T) {
+	keyring.MockInit()
+
+	err := keyring.Set("netconf-test", "admin", "secret")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	g, err := NewClientFromKeyring("netconf-test", "admin", ClientOptions{Address: "192.0.2.1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if g.options.Username != "admin" || g.options.Password != "secret" {
+		t.Errorf("got credentials %s/%s, expected admin/secret", g.options.Username, g.options.Password)
+	}
+
+	_, err = NewClientFromKeyring("netconf-test", "nobody", ClientOptions{Address: "192.0.2.1"})
+	if err == nil {
+		t.Errorf("expected an error for an account missing from the keyring")
+	}
+}
+
+func TestChangeLocalPassword(t *testing.T) {
+	tt := []struct {
+		name              string
+		updateCredentials bool
+		expected          string // Stored password afterwards
+	}{
+		{name: "update credentials", updateCredentials: true, expected: "n3w<pass>"},
+		{name: "keep credentials", updateCredentials: false, expected: "old"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g, fd := newTestClient(loadSuccessReply, okReply)
+			g.options = ClientOptions{Username: "admin", Password: "old"}
+
+			err := g.ChangeLocalPassword("n3w<pass>", tc.updateCredentials)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(fd.Sent) != 2 || fd.Sent[1] != commitStr {
+				t.Fatalf("got RPCs %q, expected a load and a commit", fd.Sent)
+			}
+
+			for _, expected := range []string{"<name>admin</name>", "<plain-text-password-value>n3w&lt;pass&gt;</plain-text-password-value>"} {
+				if !strings.Contains(fd.Sent[0], expected) {
+					t.Errorf("got %q, expected it to contain %q", fd.Sent[0], expected)
+				}
+			}
+
+			if g.options.Password != tc.expected {
+				t.Errorf("got stored password %q, expected %q", g.options.Password, tc.expected)
+			}
+		})
+	}
+}
+
+func TestChangeLocalPasswordLoadFailed(t *testing.T) {
+	g, fd := newTestClient(loadErrorReply)
+	g.options = ClientOptions{Username: "admin", Password: "old"}
+
+	err := g.ChangeLocalPassword("new", true)
+	if err == nil {
+		t.Fatal("expected an error when the load fails")
+	}
+
+	if g.options.Password != "old" {
+		t.Errorf("got stored password %q, expected the old one to be kept", g.options.Password)
+	}
+
+	if len(fd.Sent) != 2 || fd.Sent[1] != discardChangesStr {
+		t.Errorf("got RPCs %q, expected the load then a discard-changes", fd.Sent)
+	}
+}
+
+func TestChangeLocalPasswordCommitFailed(t *testing.T) {
+	g, fd := newTestClient(loadSuccessReply, commitErrorReply)
+	g.options = ClientOptions{Username: "admin", Password: "old"}
+
+	err := g.ChangeLocalPassword("new", true)
+	if err == nil {
+		t.Fatal("expected an error when the commit fails")
+	}
+
+	if g.options.Password != "old" {
+		t.Errorf("got stored password %q, expected the old one to be kept", g.options.Password)
+	}
+
+	expected := []string{commitStr, discardChangesStr}
+	if len(fd.Sent) != 3 || strings.Join(fd.Sent[1:], "\n") != strings.Join(expected, "\n") {
+		t.Errorf("got RPCs %q, expected the load, commit then a discard-changes", fd.Sent)
+	}
+}
@@ -0,0 +1,63 @@
+package junos_helpers
+
+import (
+	"fmt"
+	"io"
+
+	helpers "github.com/davedotdev/go-netconf/helpers"
+)
+
+// fileTransferer is implemented by drivers able to move files over their established transport
+type fileTransferer interface {
+	GetFile(remotePath string, w io.Writer) error
+	PutFile(r io.Reader, remotePath string) error
+}
+
+// transferFile dials the driver and runs f against it, if the driver supports file transfer
+func (g *GoNCClient) transferFile(f func(ft fileTransferer) error) error {
+	g.Lock.Lock()
+
+	if g.Driver == nil {
+		g.Lock.Unlock()
+		return helpers.ErrSessionClosed
+	}
+
+	ft, ok := g.Driver.(fileTransferer)
+	if !ok {
+		g.Lock.Unlock()
+		return fmt.Errorf("driver %T does not support file transfer", g.Driver)
+	}
+
+	err := g.dial()
+	if err != nil {
+		g.Lock.Unlock()
+		return err
+	}
+
+	err = f(ft)
+	if err != nil {
+		errInternal := g.close()
+		g.Lock.Unlock()
+		return fmt.Errorf("driver error: %+v, driver close error: %+s", err, errInternal)
+	}
+
+	err = g.close()
+
+	g.Lock.Unlock()
+
+	return err
+}
+
+// GetFile copies a file from the device to w, reusing the driver's SSH credentials over SFTP
+func (g *GoNCClient) GetFile(remotePath string, w io.Writer) error {
+	return g.transferFile(func(ft fileTransferer) error {
+		return ft.GetFile(remotePath, w)
+	})
+}
+
+// PutFile copies r to a file on the device, reusing the driver's SSH credentials over SFTP
+func (g *GoNCClient) PutFile(r io.Reader, remotePath string) error {
+	return g.transferFile(func(ft fileTransferer) error {
+		return ft.PutFile(r, remotePath)
+	})
+}
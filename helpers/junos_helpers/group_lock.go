@@ -0,0 +1,121 @@
+package junos_helpers
+
+import (
+	"fmt"
+	"log"
+
+	rpc "github.com/davedotdev/go-netconf/rpc"
+)
+
+// groupLock records how a group locked by LockGroup is held
+type groupLock struct {
+	partial bool   // Held by a partial lock, rather than the shared lock of the whole candidate
+	lockID  uint32 // lock-id of the partial lock
+}
+
+// groupSelect returns the XPath selecting the configuration group name, which validateGroupName has
+// checked holds no quotes
+func groupSelect(name string) string {
+	return fmt.Sprintf(`/configuration/groups[name="%s"]`, name)
+}
+
+// LockGroup locks the configuration group name so automations managing other groups are not blocked.
+// On devices advertising :partial-lock only the group's subtree is locked, otherwise a warning is logged
+// and the whole candidate is locked instead, shared by every group locked this way.
+// Locks are released with their session, so the client must have been opened with Dial.
+func (g *GoNCClient) LockGroup(name string) error {
+	err := validateGroupName(name)
+	if err != nil {
+		return err
+	}
+
+	g.Lock.Lock()
+	defer g.Lock.Unlock()
+
+	err = g.requireDialedSession()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := g.groupLocks[name]; ok {
+		return fmt.Errorf("group %s is already locked", name)
+	}
+
+	if hasCapability(g.serverCapabilities(), capabilityPartialLock) {
+		reply, err := g.Driver.SendRaw(rpc.MethodPartialLock([]string{groupSelect(name)}).MarshalMethod())
+		if err != nil {
+			return lockError(err)
+		}
+
+		lockID, err := parsePartialLock(reply.Data)
+		if err != nil {
+			return err
+		}
+
+		g.setGroupLock(name, groupLock{partial: true, lockID: lockID})
+		return nil
+	}
+
+	log.Printf("WARNING: device does not support partial-lock, locking the whole candidate for group %s", name)
+
+	if g.candidateLockHolders() == 0 {
+		_, err = g.Driver.Lock(datastoreCandidate)
+		if err != nil {
+			return lockError(err)
+		}
+	}
+
+	g.setGroupLock(name, groupLock{})
+	return nil
+}
+
+// UnlockGroup releases a lock taken by LockGroup. The candidate lock taken on devices without
+// :partial-lock is only released once every group sharing it has been unlocked.
+func (g *GoNCClient) UnlockGroup(name string) error {
+	g.Lock.Lock()
+	defer g.Lock.Unlock()
+
+	err := g.requireDialedSession()
+	if err != nil {
+		return err
+	}
+
+	lock, ok := g.groupLocks[name]
+	if !ok {
+		return fmt.Errorf("group %s is not locked", name)
+	}
+
+	if lock.partial {
+		_, err = g.Driver.SendRaw(rpc.MethodPartialUnlock(lock.lockID).MarshalMethod())
+	} else if g.candidateLockHolders() == 1 {
+		_, err = g.Driver.Unlock(datastoreCandidate)
+	}
+
+	if err != nil {
+		return fmt.Errorf("driver error: %w", err)
+	}
+
+	delete(g.groupLocks, name)
+	return nil
+}
+
+// setGroupLock records that the group name is locked
+func (g *GoNCClient) setGroupLock(name string, lock groupLock) {
+	if g.groupLocks == nil {
+		g.groupLocks = make(map[string]groupLock)
+	}
+
+	g.groupLocks[name] = lock
+}
+
+// candidateLockHolders counts the groups sharing the lock of the whole candidate
+func (g *GoNCClient) candidateLockHolders() int {
+	n := 0
+	for _, lock := range g.groupLocks {
+		if !lock.partial {
+			n++
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,82 @@
+package junos_helpers
+
+import (
+	"testing"
+
+	rpc "github.com/davedotdev/go-netconf/rpc"
+)
+
+func TestLockGroupPartial(t *testing.T) {
+	g, fd := newTestClient(partialLockReply)
+	fd.Capabilities = []string{capabilityPartialLock}
+
+	err := g.Dial()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err = g.LockGroup("test")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err = g.LockGroup("test")
+	if err == nil {
+		t.Errorf("expected an error locking a group twice")
+	}
+
+	err = g.UnlockGroup("test")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		rpc.MethodPartialLock([]string{`/configuration/groups[name="test"]`}).MarshalMethod(),
+		rpc.MethodPartialUnlock(127).MarshalMethod(),
+	}
+	if len(fd.Sent) != len(expected) || fd.Sent[0] != expected[0] || fd.Sent[1] != expected[1] {
+		t.Errorf("got RPCs %q, expected %q", fd.Sent, expected)
+	}
+
+	err = g.UnlockGroup("test")
+	if err == nil {
+		t.Errorf("expected an error unlocking a group that is not locked")
+	}
+}
+
+func TestLockGroupFallback(t *testing.T) {
+	g, fd := newTestClient()
+
+	err := g.LockGroup("test")
+	if err == nil {
+		t.Errorf("expected an error without a session opened by Dial")
+	}
+
+	err = g.Dial()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, name := range []string{"first", "second"} {
+		err = g.LockGroup(name)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	for _, name := range []string{"first", "second"} {
+		err = g.UnlockGroup(name)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// The candidate lock is shared, taken by the first group and released with the last
+	expected := []string{
+		rpc.MethodLock(datastoreCandidate).MarshalMethod(),
+		rpc.MethodUnlock(datastoreCandidate).MarshalMethod(),
+	}
+	if len(fd.Sent) != len(expected) || fd.Sent[0] != expected[0] || fd.Sent[1] != expected[1] {
+		t.Errorf("got RPCs %q, expected %q", fd.Sent, expected)
+	}
+}
@@ -0,0 +1,343 @@
+package junos_helpers
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrGroupNotFound is returned by DeleteGroupNoCommit in strict mode when the group does not exist
+var ErrGroupNotFound = errors.New("configuration group not found")
+
+// ErrInvalidGroupName is returned when a configuration group name is empty, too long or holds characters
+// Junos does not allow in an identifier
+var ErrInvalidGroupName = errors.New("invalid configuration group name")
+
+// maxGroupNameLength is the longest configuration group name Junos accepts
+const maxGroupNameLength = 254
+
+// validateGroupName checks name is a configuration group name Junos accepts: letters, digits, hyphens,
+// underscores and dots, at most 254 of them. The name is also escaped wherever it is sent, this rejects
+// the names the device would refuse before anything is sent.
+func validateGroupName(name string) error {
+	if name == "" || len(name) > maxGroupNameLength {
+		return fmt.Errorf("%w %q: expected 1 to %d characters", ErrInvalidGroupName, name, maxGroupNameLength)
+	}
+
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return fmt.Errorf("%w %q: %q is not allowed, expected letters, digits, '-', '_' or '.'", ErrInvalidGroupName, name, c)
+		}
+	}
+
+	return nil
+}
+
+const listGroupsStr = `<get-configuration>
+  <configuration>
+  <groups/>
+  </configuration>
+</get-configuration>
+`
+
+// groupExistsStr asks for the group's identifier only, recurse="false" leaving out its statements
+const groupExistsStr = `<get-configuration>
+  <configuration>
+  <groups recurse="false"><name>%s</name></groups>
+  </configuration>
+</get-configuration>
+`
+
+// groupNames returns the names of every group in the data of a get-configuration reply, in configuration order
+func groupNames(data string) ([]string, error) {
+	wrapper := struct {
+		Names []string `xml:"configuration>groups>name"`
+	}{}
+
+	err := xml.Unmarshal([]byte("<rpc-reply>"+data+"</rpc-reply>"), &wrapper)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(wrapper.Names))
+	for _, name := range wrapper.Names {
+		names = append(names, strings.TrimSpace(name))
+	}
+
+	return names, nil
+}
+
+// ListGroups returns the names of the configuration groups on the device, including those not created by this library
+func (g *GoNCClient) ListGroups() ([]string, error) {
+	reply, err := g.sendRaw(listGroupsStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return groupNames(reply.Data)
+}
+
+// groupExists reports whether the data of a get-configuration reply for groups/<name> contains the group
+func groupExists(data string, applygroup string) (bool, error) {
+	wrapper := struct {
+		Names []string `xml:"configuration>groups>name"`
+	}{}
+
+	err := xml.Unmarshal([]byte("<rpc-reply>"+data+"</rpc-reply>"), &wrapper)
+	if err != nil {
+		return false, err
+	}
+
+	for _, name := range wrapper.Names {
+		if strings.TrimSpace(name) == applygroup {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// GroupExists reports whether the configuration group name exists, reading only its name rather than its
+// statements. An absent group is not an error.
+func (g *GoNCClient) GroupExists(name string) (bool, error) {
+	err := validateGroupName(name)
+	if err != nil {
+		return false, err
+	}
+
+	reply, err := g.sendRaw(fmt.Sprintf(groupExistsStr, xmlEscape(name)))
+	if err != nil {
+		return false, err
+	}
+
+	return groupExists(reply.Data, name)
+}
+
+// DeleteGroupNoCommit deletes the group and its apply-groups statement from the candidate without committing,
+// reporting whether the group existed. Deleting an absent group is not an error unless strict is set,
+// in which case ErrGroupNotFound is returned.
+func (g *GoNCClient) DeleteGroupNoCommit(applygroup string, strict bool) (bool, error) {
+	err := validateGroupName(applygroup)
+	if err != nil {
+		return false, err
+	}
+
+	var existed bool
+
+	err = g.withSession(func() error {
+		var err error
+
+		existed, err = g.deleteGroup(applygroup)
+		if err != nil {
+			return fmt.Errorf("driver error: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return existed, err
+	}
+
+	if !existed && strict {
+		return false, fmt.Errorf("%w: %s", ErrGroupNotFound, applygroup)
+	}
+
+	return existed, nil
+}
+
+// deleteGroup reads the group on the open session and deletes it if it exists
+func (g *GoNCClient) deleteGroup(applygroup string) (bool, error) {
+	reply, err := g.Driver.SendRaw(fmt.Sprintf(getGroupXMLStr, xmlEscape(applygroup)))
+	if err != nil {
+		return false, err
+	}
+
+	existed, err := groupExists(reply.Data, applygroup)
+	if err != nil || !existed {
+		return false, err
+	}
+
+	edit, err := g.deleteGroupsEdit(datastoreCandidate, applygroup)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = g.Driver.SendRaw(edit)
+	if err != nil {
+		return true, err
+	}
+
+	g.lastEdit = datastoreCandidate
+	return true, nil
+}
+
+// DeleteGroupsByPrefix deletes every configuration group whose name starts with prefix, and their
+// apply-groups statements, in a single edit of the candidate, optionally committing it. It returns the
+// number of groups deleted, no group matching is not an error and leaves the candidate untouched.
+func (g *GoNCClient) DeleteGroupsByPrefix(prefix string, commit bool) (int, error) {
+	// An empty prefix would match every group, including those not created by this library
+	if prefix == "" {
+		return 0, fmt.Errorf("group prefix is empty")
+	}
+
+	var deleted int
+
+	err := g.withSession(func() error {
+		reply, err := g.Driver.SendRaw(listGroupsStr)
+		if err != nil {
+			return fmt.Errorf("driver error: %w", err)
+		}
+
+		names, err := groupNames(reply.Data)
+		if err != nil {
+			return err
+		}
+
+		var matched []string
+		for _, name := range names {
+			if strings.HasPrefix(name, prefix) {
+				matched = append(matched, name)
+			}
+		}
+
+		if len(matched) == 0 {
+			return nil
+		}
+
+		edit, err := g.deleteGroupsEdit(datastoreCandidate, matched...)
+		if err != nil {
+			return err
+		}
+
+		_, err = g.Driver.SendRaw(edit)
+		if err != nil {
+			return fmt.Errorf("driver error: %w", err)
+		}
+
+		g.lastEdit = datastoreCandidate
+
+		if commit {
+			_, err = g.commit()
+			if err != nil {
+				return fmt.Errorf("driver error: %w", err)
+			}
+		}
+
+		deleted = len(matched)
+		return nil
+	})
+
+	return deleted, err
+}
+
+// ReadGroupRawAndParsed reads the group once in XML and returns both the raw reply data and the group's
+// statements in the single line text form ReadGroup returns. Like ReadGroup it reads the committed
+// configuration, where ReadRawGroup reads the candidate and so includes changes not yet committed.
+func (g *GoNCClient) ReadGroupRawAndParsed(applygroup string) (string, string, error) {
+	err := validateGroupName(applygroup)
+	if err != nil {
+		return "", "", err
+	}
+
+	reply, err := g.sendRaw(fmt.Sprintf(getCommittedGroupXMLStr, xmlEscape(applygroup)))
+	if err != nil {
+		return "", "", err
+	}
+
+	raw := reply.Data
+
+	parsed, err := groupText(raw, applygroup)
+	if err != nil {
+		return "", "", err
+	}
+
+	return raw, parsed, nil
+}
+
+// groupText renders the statements of the group in the data of a get-configuration reply as Junos text,
+// joined onto a single line
+func groupText(data string, applygroup string) (string, error) {
+	root, err := buildElementTree("<rpc-reply>" + data + "</rpc-reply>")
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for _, configuration := range childElements(root, "configuration") {
+		for _, group := range childElements(configuration, "groups") {
+			key, statements := splitKey(group)
+			if key != applygroup {
+				continue
+			}
+
+			for _, n := range statements {
+				writeStatement(&b, n)
+			}
+		}
+	}
+
+	return strings.Join(strings.Fields(b.String()), " "), nil
+}
+
+// childElements returns the children of n with the given local name
+func childElements(n *elementNode, local string) []*elementNode {
+	if n == nil {
+		return nil
+	}
+
+	var children []*elementNode
+	for _, c := range n.children {
+		if c.name.Local == local {
+			children = append(children, c)
+		}
+	}
+	return children
+}
+
+// splitKey separates the <name> key of a list entry from its other statements
+func splitKey(n *elementNode) (string, []*elementNode) {
+	if len(n.children) > 0 && n.children[0].name.Local == "name" && len(n.children[0].children) == 0 {
+		return strings.TrimSpace(n.children[0].text), n.children[1:]
+	}
+	return "", n.children
+}
+
+// writeStatement writes n and its descendants in Junos curly brace syntax
+func writeStatement(b *strings.Builder, n *elementNode) {
+	b.WriteString(n.name.Local)
+
+	if len(n.children) == 0 {
+		if value := strings.TrimSpace(n.text); value != "" {
+			b.WriteString(" " + quoteValue(value))
+		}
+		b.WriteString(";\n")
+		return
+	}
+
+	key, statements := splitKey(n)
+	if key != "" {
+		b.WriteString(" " + quoteValue(key))
+	}
+
+	if len(statements) == 0 {
+		b.WriteString(";\n")
+		return
+	}
+
+	b.WriteString(" {\n")
+	for _, c := range statements {
+		writeStatement(b, c)
+	}
+	b.WriteString("}\n")
+}
+
+// quoteValue quotes a value the way Junos does when it contains whitespace or syntax characters
+func quoteValue(value string) string {
+	if !strings.ContainsAny(value, " \t\n;{}\"#") {
+		return value
+	}
+	return `"` + strings.Replace(value, `"`, `\"`, -1) + `"`
+}
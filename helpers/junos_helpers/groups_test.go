@@ -0,0 +1,273 @@
+package junos_helpers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const groupReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<configuration>
+<groups>
+<name>test-group</name>
+<system><host-name>r1</host-name></system>
+</groups>
+</configuration>
+</rpc-reply>`
+
+const noGroupReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<configuration>
+</configuration>
+</rpc-reply>`
+
+func TestDeleteGroupNoCommit(t *testing.T) {
+	tt := []struct {
+		name    string
+		reply   string
+		strict  bool
+		existed bool
+		err     error
+		sent    int
+	}{
+		{name: "existing", reply: groupReply, existed: true, sent: 2},
+		{name: "existing strict", reply: groupReply, strict: true, existed: true, sent: 2},
+		{name: "absent", reply: noGroupReply, existed: false, sent: 1},
+		{name: "absent strict", reply: noGroupReply, strict: true, existed: false, err: ErrGroupNotFound, sent: 1},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g, fd := newTestClient(tc.reply)
+
+			existed, err := g.DeleteGroupNoCommit("test-group", tc.strict)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("got error %v, expected %v", err, tc.err)
+			}
+
+			if existed != tc.existed {
+				t.Errorf("got existed %t, expected %t", existed, tc.existed)
+			}
+
+			if len(fd.Sent) != tc.sent {
+				t.Fatalf("got %d RPCs sent, expected %d", len(fd.Sent), tc.sent)
+			}
+			if tc.existed && !strings.Contains(fd.Sent[1], `<groups operation="delete">`) {
+				t.Errorf("got %q, expected the group to be deleted", fd.Sent[1])
+			}
+			for _, sent := range fd.Sent {
+				if sent == commitStr {
+					t.Errorf("unexpected commit")
+				}
+			}
+		})
+	}
+}
+
+func TestReadGroupRawAndParsed(t *testing.T) {
+	reply := `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:junos="http://xml.juniper.net/junos/18.4R1/junos">
+<configuration junos:changed-seconds="1591005600">
+<groups>
+<name>test-group</name>
+<system><host-name>r1</host-name></system>
+<interfaces>
+<interface>
+<name>ge-0/0/0</name>
+<description>core uplink</description>
+<unit><name>0</name><family><inet><address><name>192.0.2.1/24</name></address></inet></family></unit>
+</interface>
+</interfaces>
+</groups>
+<groups>
+<name>other-group</name>
+<system><host-name>r2</host-name></system>
+</groups>
+</configuration>
+</rpc-reply>`
+
+	g, fd := newTestClient(reply)
+
+	raw, parsed, err := g.ReadGroupRawAndParsed("test-group")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(fd.Sent) != 1 {
+		t.Fatalf("got %d RPCs sent, expected a single read", len(fd.Sent))
+	}
+
+	// The committed configuration, as ReadGroup reads
+	if fd.Sent[0] != fmt.Sprintf(getCommittedGroupXMLStr, "test-group") {
+		t.Errorf("got %q, expected a read of the committed group", fd.Sent[0])
+	}
+
+	if !strings.Contains(raw, "<name>test-group</name>") || !strings.HasPrefix(strings.TrimSpace(raw), "<configuration") {
+		t.Errorf("got raw %q, expected the configuration reply data", raw)
+	}
+
+	expected := `system { host-name r1; } interfaces { interface ge-0/0/0 { description "core uplink"; unit 0 { family { inet { address 192.0.2.1/24; } } } } }`
+	if parsed != expected {
+		t.Errorf("got %s, expected %s", parsed, expected)
+	}
+}
+
+const listGroupsReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:junos="http://xml.juniper.net/junos/18.2R1/junos">
+<configuration junos:commit-seconds="1600000000" junos:commit-user="netconf">
+<groups>
+<name>
+netconf-system
+</name>
+<system><host-name>r1</host-name></system>
+</groups>
+<groups>
+<name>netconf-interfaces</name>
+<interfaces><interface><name>ge-0/0/0</name></interface></interfaces>
+</groups>
+<groups>
+<name>re0</name>
+</groups>
+</configuration>
+</rpc-reply>`
+
+func TestListGroups(t *testing.T) {
+	g, fd := newTestClient(listGroupsReply)
+
+	names, err := g.ListGroups()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"netconf-system", "netconf-interfaces", "re0"}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Errorf("got %q, expected %q", names, expected)
+	}
+
+	if len(fd.Sent) != 1 || fd.Sent[0] != listGroupsStr {
+		t.Errorf("got RPC %q, expected %q", fd.Sent, listGroupsStr)
+	}
+}
+
+func TestDeleteGroupsByPrefix(t *testing.T) {
+	g, fd := newTestClient(listGroupsReply, okReply, okReply)
+
+	deleted, err := g.DeleteGroupsByPrefix("netconf-", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if deleted != 2 {
+		t.Errorf("got %d groups deleted, expected 2", deleted)
+	}
+
+	expected := []string{
+		listGroupsStr,
+		buildDeleteGroups(datastoreCandidate, "", []string{"netconf-system", "netconf-interfaces"}, true),
+		commitStr,
+	}
+	if strings.Join(fd.Sent, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("got RPCs %q, expected %q", fd.Sent, expected)
+	}
+
+	if strings.Contains(fd.Sent[1], "re0") {
+		t.Errorf("got %q, expected the group re0 not to be deleted", fd.Sent[1])
+	}
+}
+
+func TestDeleteGroupsByPrefixNoMatch(t *testing.T) {
+	g, fd := newTestClient(listGroupsReply)
+
+	deleted, err := g.DeleteGroupsByPrefix("ansible-", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if deleted != 0 || len(fd.Sent) != 1 {
+		t.Errorf("got %d groups deleted with RPCs %q, expected nothing deleted or committed", deleted, fd.Sent)
+	}
+}
+
+func TestListGroupsEmpty(t *testing.T) {
+	g, _ := newTestClient(noGroupReply)
+
+	names, err := g.ListGroups()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(names) != 0 {
+		t.Errorf("got %q, expected no groups", names)
+	}
+}
+
+func TestGroupExists(t *testing.T) {
+	tt := []struct {
+		name     string
+		reply    string
+		expected bool
+	}{
+		{name: "present", reply: groupReply, expected: true},
+		{name: "absent", reply: noGroupReply, expected: false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g, fd := newTestClient(tc.reply)
+
+			exists, err := g.GroupExists("test-group")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if exists != tc.expected {
+				t.Errorf("got exists %t, expected %t", exists, tc.expected)
+			}
+
+			if len(fd.Sent) != 1 || !strings.Contains(fd.Sent[0], `<groups recurse="false"><name>test-group</name></groups>`) {
+				t.Errorf("got RPCs %q, expected a get-configuration of the group's name", fd.Sent)
+			}
+		})
+	}
+}
+
+func TestInvalidGroupName(t *testing.T) {
+	names := []string{
+		"",
+		"r&d",
+		"a<b",
+		"x</name></groups><system><host-name>pwned</host-name></system><groups><name>y",
+		strings.Repeat("a", maxGroupNameLength+1),
+	}
+
+	for _, name := range names {
+		g, fd := newTestClient()
+		g.Dial()
+
+		calls := map[string]func() error{
+			"ReadGroup":            func() error { _, err := g.ReadGroup(name); return err },
+			"ReadRawGroup":         func() error { _, err := g.ReadRawGroup(name); return err },
+			"UpdateRawConfig":      func() error { _, err := g.UpdateRawConfig(name, "<configuration/>", true); return err },
+			"DeleteConfig":         func() error { _, err := g.DeleteConfig(name); return err },
+			"DeleteConfigNoCommit": func() error { _, err := g.DeleteConfigNoCommit(name); return err },
+			"DeleteRawConfig":      func() error { _, err := g.DeleteRawConfig(name, false); return err },
+			"DeleteGroupNoCommit":  func() error { _, err := g.DeleteGroupNoCommit(name, false); return err },
+			"GroupExists":          func() error { _, err := g.GroupExists(name); return err },
+			"LockGroup":            func() error { return g.LockGroup(name) },
+		}
+
+		for method, call := range calls {
+			err := call()
+			if !errors.Is(err, ErrInvalidGroupName) {
+				t.Errorf("%s(%.20q): got error %v, expected %v", method, name, err, ErrInvalidGroupName)
+			}
+		}
+
+		if len(fd.Sent) != 0 {
+			t.Errorf("got RPCs %q, expected none to be sent for %.20q", fd.Sent, name)
+		}
+	}
+
+	err := validateGroupName("tf_group-1.v2")
+	if err != nil {
+		t.Errorf("unexpected error for a valid name: %v", err)
+	}
+}
@@ -2,41 +2,195 @@ package junos_helpers
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/xml"
 	"fmt"
+	"io"
 	"io/ioutil"
-	"log"
+	"net"
 	"strings"
 	"sync"
+	"time"
 
+	conndriver "github.com/davedotdev/go-netconf/drivers/conn"
 	driver "github.com/davedotdev/go-netconf/drivers/driver"
 	sshdriver "github.com/davedotdev/go-netconf/drivers/ssh"
+	lowlevel "github.com/davedotdev/go-netconf/drivers/ssh/lowlevel"
+	helpers "github.com/davedotdev/go-netconf/helpers"
+	rpc "github.com/davedotdev/go-netconf/rpc"
 
 	"golang.org/x/crypto/ssh"
 )
 
-const groupStrXML = `<load-configuration action="merge" format="xml">
-%s
+// The load-configuration RPC, written around the payload rather than formatted with it so a large payload
+// is only copied once
+const (
+	loadConfigurationStartStr = `<load-configuration action="%s" format="%s">
+`
+	loadConfigurationEndStr = `
 </load-configuration>
 `
+)
+
+// Actions of load-configuration, controlling how the payload is combined with the candidate
+const (
+	LoadMerge    = "merge"    // Merge the payload into the candidate
+	LoadReplace  = "replace"  // Replace the statements tagged with a replace: operation
+	LoadOverride = "override" // Discard the whole candidate and load the payload in its place
+	LoadUpdate   = "update"   // Like override, but only statements that change are marked as changed
+	LoadSet      = "set"      // Apply set and delete commands given in a <configuration-set> element, with FormatText
+)
+
+// buildLoadConfiguration renders the load-configuration RPC carrying config with the given action and format
+func buildLoadConfiguration(action string, format string, config string) string {
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	fmt.Fprintf(buf, loadConfigurationStartStr, xmlEscape(action), xmlEscape(format))
+	writeEscapedPayload(buf, config)
+	buf.WriteString(loadConfigurationEndStr)
+
+	return buf.String()
+}
+
+// maxPooledBuffer is the capacity above which a buffer is dropped rather than pooled, so one huge
+// configuration does not stay in memory for good
+const maxPooledBuffer = 4 * 1024 * 1024
+
+// bufferPool recycles the buffers configurations are marshaled and wrapped in, as high volume provisioning
+// would otherwise allocate and grow fresh ones for every transaction
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// getBuffer returns an empty buffer from the pool
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+// putBuffer returns buf to the pool, the caller must not use it afterwards
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBuffer {
+		return
+	}
+
+	bufferPool.Put(buf)
+}
+
+// marshalConfig marshals obj as xml.Marshal does, into a pooled buffer
+func marshalConfig(obj interface{}) (string, error) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	err := xml.NewEncoder(buf).Encode(obj)
+	if err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
 
 const deleteStr = `<edit-config>
 	<target>
-		<candidate/>
+		<%s/>
 	</target>
-	<default-operation>none</default-operation> 
+	<default-operation>none</default-operation> %s
 	<config>
-		<configuration>
-			<groups operation="delete">
-				<name>%s</name>
-			</groups>
-			<apply-groups operation="delete">%s</apply-groups>
+		<configuration>%s%s
 		</configuration>
 	</config>
 </edit-config>`
 
+const deleteGroupsStr = `
+			<groups operation="delete">
+				<name>%s</name>
+			</groups>`
+
+const deleteApplyGroupsStr = `
+			<apply-groups operation="delete">%s</apply-groups>`
+
+// buildDeleteGroup renders the edit-config deleting the group from target, along with its apply-groups
+// statement if the group is applied. options are the rendered edit-config options, see editOptions.
+func buildDeleteGroup(target string, options string, applygroup string, applied bool) string {
+	return buildDeleteGroups(target, options, []string{applygroup}, applied)
+}
+
+// buildDeleteGroups renders a single edit-config deleting every group in names from target, along with
+// their apply-groups statements if the groups are applied
+func buildDeleteGroups(target string, options string, names []string, applied bool) string {
+	var groups, applyGroups strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&groups, deleteGroupsStr, xmlEscape(name))
+		if applied {
+			fmt.Fprintf(&applyGroups, deleteApplyGroupsStr, xmlEscape(name))
+		}
+	}
+
+	return fmt.Sprintf(deleteStr, target, options, groups.String(), applyGroups.String())
+}
+
+// deleteGroupsEdit renders the edit-config deleting the groups in names from target with the client's
+// edit-config options, along with their apply-groups statements unless UnappliedGroups is set
+func (g *GoNCClient) deleteGroupsEdit(target string, names ...string) (string, error) {
+	options, err := g.editOptions()
+	if err != nil {
+		return "", err
+	}
+
+	return buildDeleteGroups(target, options, names, !g.UnappliedGroups), nil
+}
+
+// editOptions checks TestOption and ErrorOption against the capabilities of the open session, when the
+// driver reports them, and renders them as the options elements of an edit-config
+func (g *GoNCClient) editOptions() (string, error) {
+	err := g.checkEditOptions()
+	if err != nil {
+		return "", err
+	}
+
+	var options string
+
+	if g.TestOption != "" {
+		options += "<test-option>" + g.TestOption + "</test-option>"
+	}
+
+	if g.ErrorOption != "" {
+		options += "<error-option>" + g.ErrorOption + "</error-option>"
+	}
+
+	return options, nil
+}
+
+// checkEditOptions returns an error unless TestOption and ErrorOption are valid and advertised by the
+// open session. Drivers unable to report capabilities only have the values checked.
+func (g *GoNCClient) checkEditOptions() error {
+	err := rpc.EditConfig{Target: rpc.DatastoreCandidate, TestOption: g.TestOption, ErrorOption: g.ErrorOption}.Validate()
+	if err != nil {
+		return err
+	}
+
+	capabilities := g.serverCapabilities()
+	if capabilities == nil {
+		return nil
+	}
+
+	err = rpc.CheckTestOption(g.TestOption, capabilities)
+	if err != nil {
+		return err
+	}
+
+	return rpc.CheckErrorOption(g.ErrorOption, capabilities)
+}
+
 const commitStr = `<commit/>`
 
+const commitFullStr = `<commit><full/></commit>`
+
 const getGroupStr = `<get-configuration database="committed" format="text" >
   <configuration>
   <groups><name>%s</name></groups>
@@ -51,18 +205,249 @@ const getGroupXMLStr = `<get-configuration>
 </get-configuration>
 `
 
+const getCommittedGroupXMLStr = `<get-configuration database="committed">
+  <configuration>
+  <groups><name>%s</name></groups>
+  </configuration>
+</get-configuration>
+`
+
+// GoNCClient satisfies the vendor neutral client interface
+var _ helpers.NCClient = (*GoNCClient)(nil)
+
 // GoNCClient type for storing data and wrapping functions
 type GoNCClient struct {
 	Driver driver.Driver
-	Lock   sync.RWMutex
+
+	// Lock guards the client. The driver carries a single session whose requests and replies can not
+	// interleave, so every operation that talks to the device holds it exclusively, through withSession.
+	// Only reads of the client's own state, such as the time of the last commit, take the read lock.
+	Lock sync.RWMutex
+
+	// StripNewlines removes every newline from the replies returned by DeleteConfig and
+	// DeleteConfigNoCommit. Junos pretty-prints its replies and the stripping used to be
+	// unconditional so the reply could be logged or stored as a single line. By default the
+	// device's reply is now returned unmodified.
+	StripNewlines bool
+
+	// UnappliedGroups is for groups created without an apply-groups statement. Deleting a group then leaves
+	// apply-groups untouched, rather than also deleting a reference that does not exist.
+	UnappliedGroups bool
+
+	// CommitRetries is how many times a commit rejected because another session holds the
+	// configuration database lock is retried, waiting CommitRetryBackoff (default 1s) before
+	// the first retry and doubling the wait each time.
+	CommitRetries      int
+	CommitRetryBackoff time.Duration
+
+	// VerifyLoad makes SendRawConfig read the loaded statements back from the candidate before
+	// committing, catching a large payload the device silently truncated. A mismatch discards the
+	// candidate and returns a LoadMismatchError.
+	VerifyLoad bool
+
+	// GuardConfirmedCommit makes every commit other than ConfirmCommit first read the commit history and
+	// refuse with ErrConfirmedCommitPending while a confirmed commit is awaiting confirmation, as committing
+	// would confirm a change another session may still want rolled back.
+	GuardConfirmedCommit bool
+
+	// GuardDirtyCandidate makes UpdateRawConfig first compare the candidate with the committed configuration
+	// and refuse with ErrCandidateDirty when it holds uncommitted changes, such as another tool's work in
+	// progress its commit would otherwise push.
+	GuardDirtyCandidate bool
+
+	// JSONNamespaces maps the module prefixes of JSON keys to their namespaces for GetConfigJSONWithNS,
+	// the namespace of unprefixed top level keys being mapped to ""
+	JSONNamespaces map[string]string
+
+	// PostCommitDelay pauses every successful commit before it returns, for platforms that briefly drop
+	// management connectivity after a commit, e.g. one touching the management interface, so the next
+	// operation does not fail. The contexts of SendCommitContext and ConfirmedCommitContext cut it short.
+	PostCommitDelay time.Duration
+
+	// CommitPollInterval is how often WaitForCommitComplete reads the commit history, 1s when zero
+	CommitPollInterval time.Duration
+
+	// TrackCommits makes every commit first read the commit history, so WaitForCommitComplete can tell
+	// when the device reflects it. Off by default, sparing each commit the extra round trip and the need
+	// for permission to read the history.
+	TrackCommits bool
+
+	// ErrorOption is the edit-config error-option, one of the rpc.ErrorOption values, sent with the group
+	// deletes of the load and delete methods and with the edits written straight to running on devices
+	// without the candidate. rpc.ErrorOptionRollbackOnError needs the device to advertise
+	// rpc.CapabilityRollbackOnError. Empty leaves the device to stop at the first error.
+	ErrorOption string
+
+	// TestOption is the edit-config test-option, one of the rpc.TestOption values, sent with the same
+	// edit-configs as ErrorOption. The set values need rpc.CapabilityValidate10 or rpc.CapabilityValidate11
+	// and rpc.TestOptionTestOnly needs rpc.CapabilityValidate11. Empty leaves validation to the device.
+	TestOption string
+
+	options     ClientOptions        // Options the client was built from
+	committed   bool                 // A commit succeeded, for WaitForCommitComplete
+	tracked     bool                 // The commit history was read before the last successful commit
+	commitBase  []CommitEntry        // Commit history before the last successful commit, when tracked
+	lastEdit    string               // Datastore the last successful edit was made in, for EditedDatastore
+	sessionOpen bool                 // A session opened by Dial is held until Close
+	dropped     bool                 // The session was torn down mid-operation, so there is nothing left to close
+	groupLocks  map[string]groupLock // Groups locked by LockGroup on the session opened by Dial
+	yangLibrary *YANGLibrary         // Cached by GetYANGLibrary
+	agent       *agentKeys           // SSH agent connection used to authenticate, closed by Close
 }
 
-// Close is a functional thing to close the Driver
+// Dial opens a session that every operation reuses until Close is called.
+// Without it each operation dials and closes a session of its own.
+func (g *GoNCClient) Dial() error {
+	return g.DialContext(context.Background())
+}
+
+// DialContext is Dial, aborting the connection attempt and returning ctx.Err() when ctx is cancelled
+func (g *GoNCClient) DialContext(ctx context.Context) error {
+	g.Lock.Lock()
+	defer g.Lock.Unlock()
+
+	if g.Driver == nil {
+		return helpers.ErrSessionClosed
+	}
+
+	if g.sessionOpen {
+		return nil
+	}
+
+	err := g.Driver.DialContext(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+
+	g.sessionOpen = true
+
+	return nil
+}
+
+// Close is a functional thing to close the Driver, ending any session opened by Dial.
+// It waits for in-flight operations to finish and may be called more than once, operations
+// started after it return helpers.ErrSessionClosed.
 func (g *GoNCClient) Close() error {
+	g.Lock.Lock()
+	defer g.Lock.Unlock()
+
+	if g.Driver == nil {
+		return nil
+	}
+
+	var err error
+	if g.sessionOpen {
+		err = g.Driver.Close()
+		g.sessionOpen = false
+		g.groupLocks = nil
+	}
+
+	if g.agent != nil {
+		g.agent.Close()
+	}
+
 	g.Driver = nil
+	return err
+}
+
+// dial opens a session for a single operation, unless one is held open by Dial
+func (g *GoNCClient) dial() error {
+	if g.Driver == nil {
+		return helpers.ErrSessionClosed
+	}
+
+	if g.sessionOpen {
+		return nil
+	}
+
+	return g.Driver.Dial()
+}
+
+// close ends the session of a single operation, leaving a session opened by Dial untouched
+func (g *GoNCClient) close() error {
+	if g.dropped {
+		g.dropped = false
+		return nil
+	}
+
+	if g.sessionOpen {
+		return nil
+	}
+
+	return g.Driver.Close()
+}
+
+// withSession runs fn under the exclusive lock on a session dialed for it, or the one held open by Dial,
+// closing the session afterwards. A close error is reported alongside any error from fn.
+func (g *GoNCClient) withSession(fn func() error) error {
+	g.Lock.Lock()
+	defer g.Lock.Unlock()
+
+	err := g.dial()
+	if err != nil {
+		return err
+	}
+
+	err = fn()
+
+	errInternal := g.close()
+
+	if err != nil {
+		if errInternal != nil {
+			return fmt.Errorf("%w, driver close error: %+s", err, errInternal)
+		}
+		return err
+	}
+
+	if errInternal != nil {
+		return fmt.Errorf("driver close error: %+s", errInternal)
+	}
+
 	return nil
 }
 
+// sendRaw sends a single RPC with withSession and returns the reply, or only the error if it failed
+func (g *GoNCClient) sendRaw(rpcString string) (*rpc.RPCReply, error) {
+	reply, err := g.SendRawNetconf(rpcString)
+	if err != nil {
+		return nil, err
+	}
+
+	return reply, nil
+}
+
+// SendRawNetconf sends any RPC and returns the structured reply, carrying its message-id, rpc-errors and
+// raw bytes along with the data. A reply holding rpc-errors is returned along with the error, so callers
+// can inspect both. SendRawConfig remains for callers only wanting the data.
+func (g *GoNCClient) SendRawNetconf(rpcString string) (*rpc.RPCReply, error) {
+	var reply *rpc.RPCReply
+
+	err := g.withSession(func() error {
+		var err error
+
+		reply, err = g.Driver.SendRaw(rpcString)
+		if err != nil {
+			return fmt.Errorf("driver error: %w", err)
+		}
+
+		return nil
+	})
+
+	return reply, err
+}
+
+// formatReply returns the reply data, stripped of newlines if StripNewlines is set
+func (g *GoNCClient) formatReply(data string) string {
+	if g.StripNewlines {
+		return strings.Replace(data, "\n", "", -1)
+	}
+
+	return data
+}
+
 // parseGroupData is a function that cleans up the returned data for generic config groups
 func parseGroupData(input string) (reply string, err error) {
 	var cfgSlice []string
@@ -109,24 +494,12 @@ func parseGroupData(input string) (reply string, err error) {
 
 // ReadGroup is a helper function
 func (g *GoNCClient) ReadGroup(applygroup string) (string, error) {
-	g.Lock.Lock()
-	err := g.Driver.Dial()
-
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	getGroupString := fmt.Sprintf(getGroupStr, applygroup)
-
-	reply, err := g.Driver.SendRaw(getGroupString)
+	err := validateGroupName(applygroup)
 	if err != nil {
 		return "", err
 	}
 
-	err = g.Driver.Close()
-
-	g.Lock.Unlock()
-
+	reply, err := g.sendRaw(fmt.Sprintf(getGroupStr, xmlEscape(applygroup)))
 	if err != nil {
 		return "", err
 	}
@@ -140,146 +513,178 @@ func (g *GoNCClient) ReadGroup(applygroup string) (string, error) {
 }
 
 // UpdateRawConfig deletes group data and replaces it (for Update in TF)
+// On a device without the candidate capability the change is written straight to running when it advertises
+// writable-running, commit then has no effect. Otherwise ErrCandidateUnsupported is returned.
 func (g *GoNCClient) UpdateRawConfig(applygroup string, netconfcall string, commit bool) (string, error) {
+	return g.updateRawConfig(context.Background(), applygroup, netconfcall, commit)
+}
 
-	deleteString := fmt.Sprintf(deleteStr, applygroup, applygroup)
-
-	g.Lock.Lock()
-	err := g.Driver.Dial()
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	_, err = g.Driver.SendRaw(deleteString)
+// updateRawConfig is UpdateRawConfig, checking ctx between its steps as SendTransactionContext documents.
+// A load failing after the group was deleted from the candidate is discarded, so the group is not left deleted.
+func (g *GoNCClient) updateRawConfig(ctx context.Context, applygroup string, netconfcall string, commit bool) (string, error) {
+	err := validateGroupName(applygroup)
 	if err != nil {
-		errInternal := g.Driver.Close()
-		g.Lock.Unlock()
-		return "", fmt.Errorf("driver error: %+v, driver close error: %+s", err, errInternal)
+		return "", err
 	}
 
-	groupString := fmt.Sprintf(groupStrXML, netconfcall)
-
-	reply, err := g.Driver.SendRaw(groupString)
-	if err != nil {
-		errInternal := g.Driver.Close()
-		g.Lock.Unlock()
-		return "", fmt.Errorf("driver error: %+v, driver close error: %+s", err, errInternal)
-	}
+	var data string
 
-	if commit {
-		_, err = g.Driver.SendRaw(commitStr)
+	err = g.withSession(func() error {
+		err := ctx.Err()
 		if err != nil {
-			errInternal := g.Driver.Close()
-			g.Lock.Unlock()
-			return "", fmt.Errorf("driver error: %+v, driver close error: %+s", err, errInternal)
+			return err
 		}
-	}
 
-	err = g.Driver.Close()
+		target, err := g.editTarget()
+		if err != nil {
+			return err
+		}
 
-	if err != nil {
-		g.Lock.Unlock()
-		return "", fmt.Errorf("driver close error: %+s", err)
-	}
+		if g.GuardDirtyCandidate && target == datastoreCandidate {
+			err = g.checkCandidateClean()
+			if err != nil {
+				return err
+			}
+		}
 
-	g.Lock.Unlock()
+		edit, err := g.deleteGroupsEdit(target, applygroup)
+		if err != nil {
+			return err
+		}
 
-	return reply.Data, nil
-}
+		_, err = g.Driver.SendRaw(edit)
+		if err != nil {
+			return fmt.Errorf("driver error: %w", err)
+		}
 
-// DeleteConfig is a wrapper for driver.SendRaw()
-func (g *GoNCClient) DeleteConfig(applygroup string) (string, error) {
+		if target == datastoreRunning {
+			// Running is edited in place, there is nothing to discard once cancelled
+			err = ctx.Err()
+			if err != nil {
+				return err
+			}
+
+			edit := rpc.EditConfig{
+				Target:      rpc.Datastore(target),
+				TestOption:  g.TestOption,
+				ErrorOption: g.ErrorOption,
+				Config:      escapePayload(netconfcall),
+			}
+
+			err = edit.Validate()
+			if err != nil {
+				return err
+			}
+
+			reply, err := g.Driver.SendRaw(edit.MarshalMethod())
+			if err != nil {
+				return fmt.Errorf("driver error: %w", err)
+			}
+
+			g.lastEdit = target
+			data = reply.Data
+			return nil
+		}
 
-	deleteString := fmt.Sprintf(deleteStr, applygroup, applygroup)
+		if ctx.Err() != nil {
+			return g.abortTransaction(ctx)
+		}
 
-	g.Lock.Lock()
-	err := g.Driver.Dial()
-	if err != nil {
-		log.Fatal(err)
-	}
+		reply, err := g.Driver.SendRaw(buildLoadConfiguration(LoadMerge, FormatXML, netconfcall))
+		if err != nil {
+			return fmt.Errorf("driver error: %w", err)
+		}
 
-	reply, err := g.Driver.SendRaw(deleteString)
-	if err != nil {
-		errInternal := g.Driver.Close()
-		g.Lock.Unlock()
-		return "", fmt.Errorf("driver error: %+v, driver close error: %+s", err, errInternal)
-	}
+		// Never commit a partially loaded configuration, the load results are returned with the error
+		err = checkLoadResults(reply.Data)
+		if err != nil {
+			g.Driver.SendRaw(discardChangesStr)
+			data = reply.Data
+			return err
+		}
 
-	_, err = g.Driver.SendRaw(commitStr)
-	if err != nil {
-		errInternal := g.Driver.Close()
-		g.Lock.Unlock()
-		return "", fmt.Errorf("driver error: %+v, driver close error: %+s", err, errInternal)
-	}
+		g.lastEdit = target
 
-	output := strings.Replace(reply.Data, "\n", "", -1)
+		if ctx.Err() != nil {
+			return g.abortTransaction(ctx)
+		}
 
-	err = g.Driver.Close()
+		if commit {
+			_, err = g.guardedCommitWith(ctx, commitStr)
+			if err != nil {
+				return fmt.Errorf("driver error: %w", err)
+			}
+		}
 
-	g.Lock.Unlock()
+		data = reply.Data
+		return nil
+	})
 
-	if err != nil {
-		log.Fatal(err)
-	}
+	return data, err
+}
 
-	return output, nil
+// DeleteConfig is a wrapper for driver.SendRaw()
+// Deletes the group and commits, as DeleteRawConfig with commit set
+func (g *GoNCClient) DeleteConfig(applygroup string) (string, error) {
+	return g.DeleteRawConfig(applygroup, true)
 }
 
 // DeleteConfigNoCommit is a wrapper for driver.SendRaw()
-// Does not provide mandatory commit unlike DeleteConfig()
+// Does not provide mandatory commit unlike DeleteConfig(), as DeleteRawConfig with commit unset
 func (g *GoNCClient) DeleteConfigNoCommit(applygroup string) (string, error) {
+	return g.DeleteRawConfig(applygroup, false)
+}
 
-	deleteString := fmt.Sprintf(deleteStr, applygroup, applygroup)
-
-	g.Lock.Lock()
-	err := g.Driver.Dial()
+// DeleteRawConfig deletes the group and its apply-groups statement from the candidate, committing
+// when commit is set, and returns the reply to the delete. Go has no overloading, so this is the
+// DeleteConfig taking commit as a parameter, mirroring UpdateRawConfig.
+func (g *GoNCClient) DeleteRawConfig(applygroup string, commit bool) (string, error) {
+	err := validateGroupName(applygroup)
 	if err != nil {
-		log.Fatal(err)
+		return "", err
 	}
 
-	reply, err := g.Driver.SendRaw(deleteString)
-	if err != nil {
-		errInternal := g.Driver.Close()
-		g.Lock.Unlock()
-		return "", fmt.Errorf("driver error: %+v, driver close error: %+s", err, errInternal)
-	}
+	var output string
+
+	err = g.withSession(func() error {
+		edit, err := g.deleteGroupsEdit(datastoreCandidate, applygroup)
+		if err != nil {
+			return err
+		}
 
-	output := strings.Replace(reply.Data, "\n", "", -1)
+		reply, err := g.Driver.SendRaw(edit)
+		if err != nil {
+			return fmt.Errorf("driver error: %w", err)
+		}
 
-	err = g.Driver.Close()
+		g.lastEdit = datastoreCandidate
 
+		if commit {
+			_, err = g.commit()
+			if err != nil {
+				return fmt.Errorf("driver error: %w", err)
+			}
+		}
+
+		output = g.formatReply(reply.Data)
+		return nil
+	})
 	if err != nil {
-		g.Lock.Unlock()
-		return "", fmt.Errorf("driver close error: %+s", err)
+		return "", err
 	}
 
-	g.Lock.Unlock()
-
 	return output, nil
 }
 
 // SendCommit is a wrapper for driver.SendRaw()
+// Use SendCommitResult to inspect warnings and confirmed commit details
 func (g *GoNCClient) SendCommit() error {
-	g.Lock.Lock()
-
-	err := g.Driver.Dial()
-
-	if err != nil {
-		g.Lock.Unlock()
-		return err
-	}
-
-	_, err = g.Driver.SendRaw(commitStr)
-	if err != nil {
-		g.Lock.Unlock()
-		return err
-	}
-
-	g.Lock.Unlock()
-	return nil
+	_, err := g.SendCommitResult()
+	return err
 }
 
 // MarshalGroup accepts a struct of type X and then marshals data onto it
+// Elements the struct has no matching tag for are silently dropped, use MarshalGroupNS to detect them
 func (g *GoNCClient) MarshalGroup(id string, obj interface{}) error {
 
 	reply, err := g.ReadRawGroup(id)
@@ -296,18 +701,37 @@ func (g *GoNCClient) MarshalGroup(id string, obj interface{}) error {
 
 // SendTransaction is a method that unnmarshals the XML, creates the transaction and passes in a commit
 func (g *GoNCClient) SendTransaction(id string, obj interface{}, commit bool) error {
-	jconfig, err := xml.Marshal(obj)
+	jconfig, err := marshalConfig(obj)
 
 	if err != nil {
 		return err
 	}
 
+	return g.sendTransaction(context.Background(), id, jconfig, commit)
+}
+
+// SendTransactionRaw is SendTransaction for configuration already rendered as XML, e.g. from a template or
+// a cached marshal, sent verbatim without re-encoding. xmlConfig is only checked to be well-formed.
+func (g *GoNCClient) SendTransactionRaw(id string, xmlConfig string, commit bool) error {
+	err := checkWellFormed(xmlConfig)
+	if err != nil {
+		return err
+	}
+
+	return g.sendTransaction(context.Background(), id, xmlConfig, commit)
+}
+
+// sendTransaction replaces the group id with, or merges when id is empty, the marshaled configuration,
+// checking ctx between its steps
+func (g *GoNCClient) sendTransaction(ctx context.Context, id string, jconfig string, commit bool) error {
+	var err error
+
 	// UpdateRawConfig deletes old group by, re-creates it then commits.
 	// As far as Junos cares, it's an edit.
 	if id != "" {
-		_, err = g.UpdateRawConfig(id, string(jconfig), commit)
+		_, err = g.updateRawConfig(ctx, id, jconfig, commit)
 	} else {
-		_, err = g.SendRawConfig(string(jconfig), commit)
+		_, err = g.loadConfig(ctx, jconfig, LoadMerge, FormatXML, commit)
 	}
 
 	if err != nil {
@@ -316,121 +740,522 @@ func (g *GoNCClient) SendTransaction(id string, obj interface{}, commit bool) er
 	return nil
 }
 
-// SendRawConfig is a wrapper for driver.SendRaw()
-func (g *GoNCClient) SendRawConfig(netconfcall string, commit bool) (string, error) {
+// checkWellFormed returns an error unless data is well-formed XML holding at least one element
+func checkWellFormed(data string) error {
+	d := xml.NewDecoder(strings.NewReader(data))
+	elements := 0
 
-	groupString := fmt.Sprintf(groupStrXML, netconfcall)
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("configuration is not well-formed XML: %w", err)
+		}
 
-	g.Lock.Lock()
+		if _, ok := tok.(xml.StartElement); ok {
+			elements++
+		}
+	}
 
-	err := g.Driver.Dial()
+	if elements == 0 {
+		return fmt.Errorf("configuration holds no XML element")
+	}
 
+	return nil
+}
+
+// transactionCleanupTimeout bounds the discard-changes SendTransactionContext issues once cancelled, so
+// cleaning up can not hang on an unresponsive device
+var transactionCleanupTimeout = 10 * time.Second
+
+// SendTransactionContext is SendTransaction, checking ctx between deleting the old group, loading the new
+// configuration and committing it. Once ctx is done anything already loaded is discarded with
+// discard-changes and ctx.Err() is returned, so a cancelled transaction leaves the candidate clean. A step
+// already sent to the device is not interrupted, and the transaction takes no lock that could be left held.
+func (g *GoNCClient) SendTransactionContext(ctx context.Context, id string, obj interface{}, commit bool) error {
+	jconfig, err := marshalConfig(obj)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+
+	return g.sendTransaction(ctx, id, jconfig, commit)
+}
+
+// abortTransaction discards the candidate of a transaction cancelled by ctx on the open session and returns
+// ctx.Err() along with any cleanup failure. If the device does not answer within transactionCleanupTimeout
+// the session is torn down, so no later operation can read the late reply in place of its own.
+func (g *GoNCClient) abortTransaction(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		_, err := g.Driver.SendRaw(discardChangesStr)
+		done <- err
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(transactionCleanupTimeout):
+		// Closing the driver unblocks the discard-changes, wait for it so nothing is left reading the session
+		g.Driver.Close()
+		<-done
+
+		g.sessionOpen = false
+		g.groupLocks = nil
+		g.dropped = true
+
+		err = fmt.Errorf("timed out after %v, session closed", transactionCleanupTimeout)
 	}
 
-	reply, err := g.Driver.SendRaw(groupString)
 	if err != nil {
-		errInternal := g.Driver.Close()
-		g.Lock.Unlock()
-		return "", fmt.Errorf("driver error: %+v, driver close error: %+s", err, errInternal)
+		return fmt.Errorf("%w, discard-changes error: %v", ctx.Err(), err)
 	}
 
-	if commit {
-		_, err = g.Driver.SendRaw(commitStr)
+	return ctx.Err()
+}
+
+// SendRawConfig is a wrapper for driver.SendRaw()
+func (g *GoNCClient) SendRawConfig(netconfcall string, commit bool) (string, error) {
+	return g.LoadConfig(netconfcall, LoadMerge, FormatXML, commit)
+}
+
+// LoadConfig loads config into the candidate with the given load-configuration action and format,
+// e.g. LoadOverride to replace the whole configuration, and optionally commits it.
+// FormatXML payloads are a <configuration> element, FormatText and FormatJSON payloads are wrapped in
+// <configuration-text> and <configuration-json>. VerifyLoad only applies to FormatXML payloads. A load
+// rejecting any line is discarded from the candidate.
+func (g *GoNCClient) LoadConfig(config string, action string, format string, commit bool) (string, error) {
+	return g.loadConfig(context.Background(), config, action, format, commit)
+}
+
+// loadConfig is LoadConfig, checking ctx between loading and committing as SendTransactionContext documents
+func (g *GoNCClient) loadConfig(ctx context.Context, config string, action string, format string, commit bool) (string, error) {
+	var data string
+
+	err := g.withSession(func() error {
+		err := ctx.Err()
 		if err != nil {
-			errInternal := g.Driver.Close()
-			g.Lock.Unlock()
-			return "", fmt.Errorf("driver error: %+v, driver close error: %+s", err, errInternal)
+			return err
+		}
+
+		reply, err := g.Driver.SendRaw(buildLoadConfiguration(action, format, config))
+		if err != nil {
+			return fmt.Errorf("driver error: %w", err)
+		}
+
+		// Never commit a partially loaded configuration, the load results are returned with the error
+		err = checkLoadResults(reply.Data)
+		if err != nil {
+			g.Driver.SendRaw(discardChangesStr)
+			data = reply.Data
+			return err
+		}
+
+		if g.VerifyLoad && format == FormatXML {
+			err = g.verifyLoad(escapePayload(config))
+			if err != nil {
+				g.Driver.SendRaw(discardChangesStr)
+				data = reply.Data
+				return err
+			}
 		}
-	}
 
-	err = g.Driver.Close()
+		g.lastEdit = datastoreCandidate
 
+		if ctx.Err() != nil {
+			return g.abortTransaction(ctx)
+		}
+
+		if commit {
+			_, err = g.guardedCommitWith(ctx, commitStr)
+			if err != nil {
+				return fmt.Errorf("driver error: %w", err)
+			}
+		}
+
+		data = reply.Data
+		return nil
+	})
+
+	return data, err
+}
+
+// ReadRawGroup is a helper function
+func (g *GoNCClient) ReadRawGroup(applygroup string) (string, error) {
+	err := validateGroupName(applygroup)
 	if err != nil {
-		g.Lock.Unlock()
 		return "", err
 	}
 
-	g.Lock.Unlock()
+	reply, err := g.sendRaw(fmt.Sprintf(getGroupXMLStr, xmlEscape(applygroup)))
+	if err != nil {
+		return "", err
+	}
 
 	return reply.Data, nil
 }
 
-// ReadRawGroup is a helper function
-func (g *GoNCClient) ReadRawGroup(applygroup string) (string, error) {
-	g.Lock.Lock()
-	err := g.Driver.Dial()
+// xmlEscape escapes user supplied text for interpolation into an RPC
+func xmlEscape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// escapePayload makes a caller's XML payload safe to embed in an RPC. A "]]>" may only end a CDATA
+// section, anywhere else it is not well-formed XML and "]]>]]>" would end the NETCONF 1.0 message early,
+// so every "]]>" outside a CDATA section is escaped as "]]&gt;". CDATA sections are copied unchanged.
+func escapePayload(s string) string {
+	var b strings.Builder
+	writeEscapedPayload(&b, s)
+	return b.String()
+}
+
+// writeEscapedPayload writes s to w escaped as escapePayload does
+func writeEscapedPayload(w io.StringWriter, s string) {
+	for {
+		end := strings.Index(s, "]]>")
+		if end == -1 {
+			w.WriteString(s)
+			return
+		}
 
+		start := strings.Index(s, "<![CDATA[")
+		if start == -1 || end < start {
+			w.WriteString(s[:end])
+			w.WriteString("]]&gt;")
+			s = s[end+len("]]>"):]
+			continue
+		}
+
+		// Copy the CDATA section through its terminator
+		length := strings.Index(s[start:], "]]>")
+		if length == -1 {
+			w.WriteString(s)
+			return
+		}
+
+		n := start + length + len("]]>")
+		w.WriteString(s[:n])
+		s = s[n:]
+	}
+}
+
+// loadPrivateKey reads the unencrypted private key in file
+func loadPrivateKey(file string) (ssh.Signer, error) {
+	buffer, err := ioutil.ReadFile(file)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+
+	return ssh.ParsePrivateKey(buffer)
+}
+
+// ClientOptions holds everything needed to build a client. Zero values take the documented defaults.
+type ClientOptions struct {
+	Username string
+	Password string // Also answers the password prompt of keyboard-interactive authentication
+	SSHKey   string // Path to a private key file, takes priority over Password when set and AuthMethods is empty
+	Address  string
+	Port     int // Defaults to 830, the NETCONF over SSH port, when zero
+
+	// AuthMethods lists the authentication methods offered to the device, in order, from AuthKey, AuthPassword,
+	// AuthAgent and AuthKeyboardInteractive, so fleets with mixed authentication can use whichever a device
+	// accepts. When empty, SSHKey is used if set, otherwise Password with a keyboard-interactive fallback.
+	AuthMethods []string
+
+	// ClientVersion is the identification string sent at the start of the SSH handshake, for devices or IDS
+	// rules keying off it. It must start with SSH-2.0-, the SSH library's own SSH-2.0-Go is sent when empty.
+	ClientVersion string
+
+	Timeout         time.Duration       // TCP connect timeout, zero for none
+	HostKeyCallback ssh.HostKeyCallback // Defaults to ssh.InsecureIgnoreHostKey()
+
+	// LegacyAlgorithms also offers the SHA-1 Diffie-Hellman key exchanges, CBC ciphers and ssh-dss host
+	// keys that older Junos and SRX releases require. These algorithms are considered broken or weak,
+	// so only set it for devices that cannot negotiate anything else.
+	LegacyAlgorithms bool
+
+	Debug io.Writer // Receives a transcript of every NETCONF message sent and received, for debugging interop
+
+	// MaxReplySize caps the bytes read for a single reply, 512MiB when zero and unlimited when negative.
+	// A larger reply fails with transport.ErrReplyTooLarge and ends the session.
+	MaxReplySize int64
+
+	// HelloTimeout bounds the wait for the device's hello once SSH is up, 15s when zero and unlimited when
+	// negative. An endpoint that is not a NETCONF server fails with session.ErrHelloTimeout.
+	HelloTimeout time.Duration
+
+	// MessageID generates the message-id of every RPC, e.g. embedding a request trace id so device logs can
+	// be correlated with the automation. Integers counting up from 1 when nil.
+	MessageID func() string
+}
+
+// Algorithms offered when ClientOptions.LegacyAlgorithms is set, modern ones first so they are still preferred
+var (
+	legacyKeyExchanges = []string{
+		"curve25519-sha256@libssh.org",
+		"ecdh-sha2-nistp256", "ecdh-sha2-nistp384", "ecdh-sha2-nistp521",
+		"diffie-hellman-group14-sha1",
+		"diffie-hellman-group-exchange-sha256",
+		"diffie-hellman-group-exchange-sha1",
+		"diffie-hellman-group1-sha1",
+	}
+
+	legacyCiphers = []string{
+		"aes128-gcm@openssh.com", "chacha20-poly1305@openssh.com",
+		"aes128-ctr", "aes192-ctr", "aes256-ctr",
+		"aes128-cbc", "3des-cbc",
+	}
+
+	legacyHostKeyAlgorithms = []string{
+		ssh.KeyAlgoED25519,
+		ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521,
+		ssh.KeyAlgoRSA, ssh.KeyAlgoDSA,
 	}
+)
 
-	getGroupXMLString := fmt.Sprintf(getGroupXMLStr, applygroup)
+// NewClient returns gonetconf new client driver
+func NewClient(username string, password string, sshkey string, address string, port int) (*GoNCClient, error) {
+	return newClientWithOptions(ClientOptions{
+		Username: username,
+		Password: password,
+		SSHKey:   sshkey,
+		Address:  address,
+		Port:     port,
+	})
+}
 
-	reply, err := g.Driver.SendRaw(getGroupXMLString)
+// NewClientWithOptions returns gonetconf new client driver built from opts. The client is a *GoNCClient,
+// for the Junos specific methods and settings.
+func NewClientWithOptions(opts ClientOptions) (helpers.NCClient, error) {
+	g, err := newClientWithOptions(opts)
 	if err != nil {
-		errInternal := g.Driver.Close()
-		g.Lock.Unlock()
-		return "", fmt.Errorf("driver error: %+v, driver close error: %+s", err, errInternal)
+		return nil, err
 	}
 
-	err = g.Driver.Close()
+	return g, nil
+}
+
+// newClientWithOptions is NewClientWithOptions, returning the concrete client
+func newClientWithOptions(opts ClientOptions) (*GoNCClient, error) {
 
-	g.Lock.Unlock()
+	if opts.Port < 0 || opts.Port > 65535 {
+		return nil, fmt.Errorf("invalid port %d, expected 1-65535 or 0 for the default of %d", opts.Port, lowlevel.DefaultPort)
+	}
+
+	if opts.ClientVersion != "" && (!strings.HasPrefix(opts.ClientVersion, "SSH-2.0-") || strings.ContainsAny(opts.ClientVersion, "\r\n")) {
+		return nil, fmt.Errorf("invalid SSH client version %q, expected a single line starting with SSH-2.0-", opts.ClientVersion)
+	}
+
+	// Dummy interface var ready for loading from inputs
+	var nconf driver.Driver
 
+	d := driver.New(sshdriver.New())
+
+	nc := d.(*sshdriver.DriverSSH)
+
+	nc.Host = opts.Address
+	nc.Debug = opts.Debug
+	nc.MaxReplySize = opts.MaxReplySize
+	nc.HelloTimeout = opts.HelloTimeout
+	nc.MessageID = opts.MessageID
+
+	// New() already targets the default NETCONF port
+	if opts.Port != 0 {
+		nc.Port = opts.Port
+	}
+
+	hostKeyCallback := opts.HostKeyCallback
+	if hostKeyCallback == nil {
+		hostKeyCallback = ssh.InsecureIgnoreHostKey()
+	}
+
+	keys := &agentKeys{}
+
+	auth, err := authMethods(opts, keys)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return reply.Data, nil
+	// Sort yourself out with SSH. Easiest to do that here.
+	nc.SSHConfig = &ssh.ClientConfig{
+		User:            opts.Username,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         opts.Timeout,
+		ClientVersion:   opts.ClientVersion,
+	}
+
+	if opts.LegacyAlgorithms {
+		nc.SSHConfig.KeyExchanges = legacyKeyExchanges
+		nc.SSHConfig.Ciphers = legacyCiphers
+		nc.SSHConfig.HostKeyAlgorithms = legacyHostKeyAlgorithms
+	}
+
+	nconf = nc
+
+	return &GoNCClient{Driver: nconf, options: opts, agent: keys}, nil
 }
 
-func publicKeyFile(file string) ssh.AuthMethod {
-	buffer, err := ioutil.ReadFile(file)
+// NewClientContext returns a client built from opts with a session already open, so the first connection
+// honours ctx, e.g. a request scoped context, returning ctx.Err() when it is cancelled. As with Dial, the
+// session is held open until Close. The client is a *GoNCClient, for the Junos specific methods and settings.
+func NewClientContext(ctx context.Context, opts ClientOptions) (helpers.NCClient, error) {
+	g, err := newClientWithOptions(opts)
 	if err != nil {
-		return nil
+		return nil, err
 	}
 
-	key, err := ssh.ParsePrivateKey(buffer)
+	err = g.DialContext(ctx)
 	if err != nil {
-		return nil
+		return nil, err
 	}
-	return ssh.PublicKeys(key)
+
+	return g, nil
 }
 
-// NewClient returns gonetconf new client driver
-func NewClient(username string, password string, sshkey string, address string, port int) (*GoNCClient, error) {
+// Clone returns a new, unconnected client for address and port (830 when zero) with the connection
+// parameters of g: authentication, timeouts, host key policy, algorithms and debug transcript, along with
+// its commit and load settings. Sessions, locks, caches and the SSH agent connection are not shared. Only
+// clients built with an SSH driver can be cloned, for any other, or when the agent authentication can not be
+// set up again, the clone has no driver and its operations return helpers.ErrSessionClosed.
+func (g *GoNCClient) Clone(address string, port int) *GoNCClient {
+	g.Lock.RLock()
+	defer g.Lock.RUnlock()
+
+	clone := &GoNCClient{
+		StripNewlines:        g.StripNewlines,
+		CommitRetries:        g.CommitRetries,
+		CommitRetryBackoff:   g.CommitRetryBackoff,
+		VerifyLoad:           g.VerifyLoad,
+		UnappliedGroups:      g.UnappliedGroups,
+		CommitPollInterval:   g.CommitPollInterval,
+		GuardConfirmedCommit: g.GuardConfirmedCommit,
+		GuardDirtyCandidate:  g.GuardDirtyCandidate,
+		PostCommitDelay:      g.PostCommitDelay,
+		TrackCommits:         g.TrackCommits,
+		ErrorOption:          g.ErrorOption,
+		TestOption:           g.TestOption,
+		options:              g.options,
+	}
 
-	// Dummy interface var ready for loading from inputs
-	var nconf driver.Driver
+	if g.JSONNamespaces != nil {
+		// A map of its own, so adding a namespace to one client leaves the other unchanged
+		clone.JSONNamespaces = make(map[string]string, len(g.JSONNamespaces))
+		for prefix, ns := range g.JSONNamespaces {
+			clone.JSONNamespaces[prefix] = ns
+		}
+	}
 
-	d := driver.New(sshdriver.New())
+	if port == 0 {
+		port = lowlevel.DefaultPort
+	}
 
-	nc := d.(*sshdriver.DriverSSH)
+	clone.options.Address = address
+	clone.options.Port = port
+
+	d, ok := g.Driver.(*sshdriver.DriverSSH)
+	if !ok {
+		return clone
+	}
+
+	clone.Driver = copySSHDriver(d, address, port)
+
+	if g.agent != nil {
+		// An agent connection of its own, dialed when the clone first authenticates
+		clone.agent = &agentKeys{}
+
+		if usesAgent(clone.options) {
+			auth, err := authMethods(clone.options, clone.agent)
+			if err != nil {
+				clone.Driver = nil
+				return clone
+			}
+			clone.Driver.(*sshdriver.DriverSSH).SSHConfig.Auth = auth
+		}
+	}
+
+	return clone
+}
 
+// copySSHDriver returns a new, undialed SSH driver for address and port with the settings of d
+func copySSHDriver(d *sshdriver.DriverSSH, address string, port int) *sshdriver.DriverSSH {
+	nc := sshdriver.New()
 	nc.Host = address
 	nc.Port = port
+	nc.Timeout = d.Timeout
+	nc.Datastore = d.Datastore
+	nc.Debug = d.Debug
+	nc.ForceFraming = d.ForceFraming
+	nc.MaxReplySize = d.MaxReplySize
+	nc.HelloTimeout = d.HelloTimeout
+	nc.MessageID = d.MessageID
+
+	if d.SSHConfig != nil {
+		config := *d.SSHConfig
+		nc.SSHConfig = &config
+	}
+
+	return nc
+}
+
+// Reset recovers the client after an error left it unusable, e.g. a session broken mid-operation or a
+// Driver set to nil by Close, so it need not be rebuilt. Any open session is closed, ignoring errors as
+// the transport may already be gone, and locks taken with LockGroup are forgotten. A client with an SSH
+// driver gets a fresh one with the same settings, a closed client one built again from the options it was
+// created with. Other drivers are kept and redialed by the next operation. Sessions held open by Dial
+// must be dialed again.
+func (g *GoNCClient) Reset() error {
+	g.Lock.Lock()
+	defer g.Lock.Unlock()
+
+	if _, ok := g.Driver.(*conndriver.DriverConn); ok {
+		return fmt.Errorf("a client built with NewClientFromConn can not be reset, its connection can not be redialed")
+	}
+
+	if g.Driver == nil && g.options.Address == "" {
+		return fmt.Errorf("client has no connection parameters to reset from")
+	}
 
-	// SSH keys takes priority over password based
-	if sshkey != "" {
-		nc.SSHConfig = &ssh.ClientConfig{
-			User: username,
-			Auth: []ssh.AuthMethod{
-				publicKeyFile(sshkey),
-			},
-			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	if g.Driver != nil && g.sessionOpen {
+		g.Driver.Close()
+	}
+
+	g.sessionOpen = false
+	g.groupLocks = nil
+	g.yangLibrary = nil
+
+	switch d := g.Driver.(type) {
+	case *sshdriver.DriverSSH:
+		g.Driver = copySSHDriver(d, d.Host, d.Port)
+	case nil:
+		nc, err := newClientWithOptions(g.options)
+		if err != nil {
+			return err
 		}
-	} else {
-		// Sort yourself out with SSH. Easiest to do that here.
-		nc.SSHConfig = &ssh.ClientConfig{
-			User:            username,
-			Auth:            []ssh.AuthMethod{ssh.Password(password)},
-			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		if g.agent != nil {
+			g.agent.Close()
 		}
+		g.Driver = nc.Driver
+		g.agent = nc.agent
 	}
 
-	nconf = nc
+	return nil
+}
+
+// NewClientFromConn returns a client running NETCONF over conn, an already established connection such as
+// a tunnel or a test pipe. The hello exchange happens immediately and, as the connection can not be
+// redialed, the session is held open until Close, which also closes conn. The client is a *GoNCClient, for
+// the Junos specific methods and settings.
+func NewClientFromConn(conn net.Conn) (helpers.NCClient, error) {
+	d := conndriver.New(conn)
+
+	err := d.Dial()
+	if err != nil {
+		return nil, fmt.Errorf("hello exchange failed: %w", err)
+	}
 
-	return &GoNCClient{Driver: nconf}, nil
+	return &GoNCClient{Driver: d, sessionOpen: true}, nil
 }
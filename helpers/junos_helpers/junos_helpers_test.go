@@ -0,0 +1,1402 @@
+package junos_helpers
+
+import (
+	"bufio"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	sshdriver "github.com/davedotdev/go-netconf/drivers/ssh"
+	helpers "github.com/davedotdev/go-netconf/helpers"
+	testdriver "github.com/davedotdev/go-netconf/helpers/internal/testdriver"
+	rpc "github.com/davedotdev/go-netconf/rpc"
+	"golang.org/x/crypto/ssh"
+)
+
+const okReply = testdriver.OKReply
+
+func newTestClient(replies ...string) (*GoNCClient, *testdriver.Driver) {
+	fd := testdriver.New(replies...)
+	return &GoNCClient{Driver: fd}, fd
+}
+
+const deleteReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<ok/>
+</rpc-reply>`
+
+func TestDeleteConfigNewlines(t *testing.T) {
+	tt := []struct {
+		name     string
+		strip    bool
+		expected string
+	}{
+		{name: "raw", strip: false, expected: "\n<ok/>\n"},
+		{name: "stripped", strip: true, expected: "<ok/>"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g, _ := newTestClient(deleteReply)
+			g.StripNewlines = tc.strip
+
+			output, err := g.DeleteConfig("test-group")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if output != tc.expected {
+				t.Errorf("got %q, expected %q", output, tc.expected)
+			}
+
+			g, _ = newTestClient(deleteReply)
+			g.StripNewlines = tc.strip
+
+			output, err = g.DeleteConfigNoCommit("test-group")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if output != tc.expected {
+				t.Errorf("got %q, expected %q", output, tc.expected)
+			}
+		})
+	}
+}
+
+func TestDeleteRawConfig(t *testing.T) {
+	tt := []struct {
+		name   string
+		commit bool
+		rpcs   int
+	}{
+		{name: "commit", commit: true, rpcs: 2},
+		{name: "no commit", commit: false, rpcs: 1},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g, fd := newTestClient(deleteReply, commitSuccessReply)
+
+			_, err := g.DeleteRawConfig("test-group", tc.commit)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(fd.Sent) != tc.rpcs {
+				t.Fatalf("got RPCs %q, expected %d", fd.Sent, tc.rpcs)
+			}
+
+			if !strings.Contains(fd.Sent[0], `<groups operation="delete">`) {
+				t.Errorf("got RPC %q, expected the group to be deleted", fd.Sent[0])
+			}
+
+			if tc.commit && fd.Sent[1] != commitStr {
+				t.Errorf("got RPC %q, expected %q", fd.Sent[1], commitStr)
+			}
+		})
+	}
+}
+
+func TestDeleteRawConfigCommitFailed(t *testing.T) {
+	g, _ := newTestClient(deleteReply, commitErrorReply)
+
+	_, err := g.DeleteRawConfig("test-group", true)
+	if err == nil {
+		t.Error("expected the failed commit to be returned")
+	}
+}
+
+func TestNewClientWithOptions(t *testing.T) {
+	called := false
+	g, err := NewClientWithOptions(ClientOptions{
+		Username: "admin",
+		Password: "secret",
+		Address:  "192.0.2.1",
+		Port:     22,
+		Timeout:  5 * time.Second,
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			called = true
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	d := g.(*GoNCClient).Driver.(*sshdriver.DriverSSH)
+	if d.Host != "192.0.2.1" || d.Port != 22 {
+		t.Errorf("got target %s:%d, expected 192.0.2.1:22", d.Host, d.Port)
+	}
+	if d.SSHConfig.User != "admin" || d.SSHConfig.Timeout != 5*time.Second {
+		t.Errorf("got user %s and timeout %v, expected admin and 5s", d.SSHConfig.User, d.SSHConfig.Timeout)
+	}
+
+	d.SSHConfig.HostKeyCallback("192.0.2.1:22", nil, nil)
+	if !called {
+		t.Error("configured host key callback was not used")
+	}
+
+	// Unset options take their defaults
+	g, err = NewClientWithOptions(ClientOptions{Username: "admin", Address: "192.0.2.2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	d = g.(*GoNCClient).Driver.(*sshdriver.DriverSSH)
+	if d.SSHConfig.HostKeyCallback == nil {
+		t.Error("expected a default host key callback")
+	}
+	if d.SSHConfig.Timeout != 0 {
+		t.Errorf("got timeout %v, expected none", d.SSHConfig.Timeout)
+	}
+	if len(d.SSHConfig.Auth) != 2 {
+		t.Errorf("got %d auth methods, expected password and keyboard-interactive", len(d.SSHConfig.Auth))
+	}
+}
+
+func TestNewClientLegacyAlgorithms(t *testing.T) {
+	g, err := newClientWithOptions(ClientOptions{Username: "admin", Address: "192.0.2.1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	d := g.Driver.(*sshdriver.DriverSSH)
+	if d.SSHConfig.KeyExchanges != nil || d.SSHConfig.Ciphers != nil || d.SSHConfig.HostKeyAlgorithms != nil {
+		t.Errorf("expected the x/crypto/ssh default algorithms without LegacyAlgorithms")
+	}
+
+	g, err = newClientWithOptions(ClientOptions{Username: "admin", Address: "192.0.2.1", LegacyAlgorithms: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	d = g.Driver.(*sshdriver.DriverSSH)
+
+	tt := []struct {
+		name       string
+		algorithms []string
+		expected   []string
+	}{
+		{name: "key exchanges", algorithms: d.SSHConfig.KeyExchanges, expected: []string{"curve25519-sha256@libssh.org", "diffie-hellman-group1-sha1", "diffie-hellman-group-exchange-sha1"}},
+		{name: "ciphers", algorithms: d.SSHConfig.Ciphers, expected: []string{"aes128-ctr", "aes128-cbc", "3des-cbc"}},
+		{name: "host key algorithms", algorithms: d.SSHConfig.HostKeyAlgorithms, expected: []string{ssh.KeyAlgoED25519, ssh.KeyAlgoRSA, ssh.KeyAlgoDSA}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			offered := map[string]bool{}
+			for _, a := range tc.algorithms {
+				offered[a] = true
+			}
+
+			for _, e := range tc.expected {
+				if !offered[e] {
+					t.Errorf("got %v, expected %s to be offered", tc.algorithms, e)
+				}
+			}
+		})
+	}
+}
+
+func TestNewClientWithOptionsMissingKey(t *testing.T) {
+	g, err := NewClientWithOptions(ClientOptions{Username: "admin", SSHKey: "/nonexistent/id_rsa"})
+	if err == nil {
+		t.Fatal("expected an error for an unreadable ssh key")
+	}
+
+	// A nil client, not a nil *GoNCClient in a non-nil interface
+	if g != nil {
+		t.Errorf("got client %#v, expected nil", g)
+	}
+}
+
+func TestNewClientPort(t *testing.T) {
+	tt := []struct {
+		name     string
+		port     int
+		expected int
+		err      bool
+	}{
+		{name: "default", port: 0, expected: 830},
+		{name: "explicit", port: 22, expected: 22},
+		{name: "negative", port: -1, err: true},
+		{name: "tooLarge", port: 65536, err: true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g, err := NewClient("admin", "secret", "", "192.0.2.1", tc.port)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected an error for port %d", tc.port)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if port := g.Driver.(*sshdriver.DriverSSH).Port; port != tc.expected {
+				t.Errorf("got port %d, expected %d", port, tc.expected)
+			}
+		})
+	}
+}
+
+func TestManagedSession(t *testing.T) {
+	g, fd := newTestClient()
+
+	err := g.Dial()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err = g.ReadRawGroup("test-group")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	_, err = g.ReadConfiguration("<interfaces/>", FormatXML)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if fd.Dials != 1 || fd.Closes != 0 {
+		t.Errorf("got %d dials and %d closes, expected a single dial with the session held open", fd.Dials, fd.Closes)
+	}
+
+	if len(fd.Sent) != 4 {
+		t.Errorf("got %d RPCs sent, expected 4", len(fd.Sent))
+	}
+
+	err = g.Close()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if fd.Closes != 1 {
+		t.Errorf("got %d closes, expected Close to end the session", fd.Closes)
+	}
+}
+
+func TestSerialSession(t *testing.T) {
+	g, fd := newTestClient()
+
+	for i := 0; i < 2; i++ {
+		_, err := g.ReadRawGroup("test-group")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if fd.Dials != 2 || fd.Closes != 2 {
+		t.Errorf("got %d dials and %d closes, expected one of each per operation", fd.Dials, fd.Closes)
+	}
+}
+
+func TestEscapePayload(t *testing.T) {
+	tt := []struct {
+		name     string
+		payload  string
+		expected string
+		text     string
+	}{
+		{
+			name:     "plain",
+			payload:  `<description>core &amp; edge &lt;1&gt;</description>`,
+			expected: `<description>core &amp; edge &lt;1&gt;</description>`,
+			text:     "core & edge <1>",
+		},
+		{
+			name:     "terminator",
+			payload:  `<description>a]]>b</description>`,
+			expected: `<description>a]]&gt;b</description>`,
+			text:     "a]]>b",
+		},
+		{
+			name:     "message separator",
+			payload:  `<description>]]>]]></description>`,
+			expected: `<description>]]&gt;]]&gt;</description>`,
+			text:     "]]>]]>",
+		},
+		{
+			name:     "cdata",
+			payload:  `<description><![CDATA[<a> & "b"]]>]]></description>`,
+			expected: `<description><![CDATA[<a> & "b"]]>]]&gt;</description>`,
+			text:     `<a> & "b"]]>`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			escaped := escapePayload(tc.payload)
+			if escaped != tc.expected {
+				t.Errorf("got %s, expected %s", escaped, tc.expected)
+			}
+
+			var v struct {
+				Text string `xml:",chardata"`
+			}
+			err := xml.Unmarshal([]byte(escaped), &v)
+			if err != nil {
+				t.Fatalf("escaped payload is not well-formed: %v", err)
+			}
+			if v.Text != tc.text {
+				t.Errorf("got %q, expected %q", v.Text, tc.text)
+			}
+		})
+	}
+}
+
+func TestUserStringsEscaped(t *testing.T) {
+	// Group names are validated by the public methods, the RPC is escaped regardless
+	name := `a<b>&c]]>]]>`
+
+	var deleted struct {
+		Name        string `xml:"config>configuration>groups>name"`
+		ApplyGroups string `xml:"config>configuration>apply-groups"`
+	}
+	err := xml.Unmarshal([]byte(buildDeleteGroup(datastoreCandidate, "", name, true)), &deleted)
+	if err != nil {
+		t.Fatalf("RPC is not well-formed: %v", err)
+	}
+	if deleted.Name != name || deleted.ApplyGroups != name {
+		t.Errorf("got %q and %q, expected %q", deleted.Name, deleted.ApplyGroups, name)
+	}
+
+	g, fd := newTestClient(loadSuccessReply)
+	_, err = g.SendRawConfig(`<configuration><system><login><message>x]]>]]>y</message></login></system></configuration>`, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if strings.Contains(fd.Sent[0], "]]>") {
+		t.Errorf("got %q, expected every ]]> to be escaped", fd.Sent[0])
+	}
+
+	var loaded struct {
+		Message string `xml:"configuration>system>login>message"`
+	}
+	err = xml.Unmarshal([]byte(fd.Sent[0]), &loaded)
+	if err != nil {
+		t.Fatalf("RPC is not well-formed: %v", err)
+	}
+	if loaded.Message != "x]]>]]>y" {
+		t.Errorf("got %q, expected %q", loaded.Message, "x]]>]]>y")
+	}
+}
+
+func TestBuildLoadConfiguration(t *testing.T) {
+	tt := []struct {
+		name     string
+		action   string
+		format   string
+		config   string
+		expected string
+	}{
+		{
+			name:     "merge",
+			action:   LoadMerge,
+			format:   FormatXML,
+			config:   "<configuration><system><host-name>r1</host-name></system></configuration>",
+			expected: "<load-configuration action=\"merge\" format=\"xml\">\n<configuration><system><host-name>r1</host-name></system></configuration>\n</load-configuration>\n",
+		},
+		{
+			name:     "replace",
+			action:   LoadReplace,
+			format:   FormatXML,
+			config:   `<configuration><system replace="replace"><host-name>r1</host-name></system></configuration>`,
+			expected: "<load-configuration action=\"replace\" format=\"xml\">\n<configuration><system replace=\"replace\"><host-name>r1</host-name></system></configuration>\n</load-configuration>\n",
+		},
+		{
+			name:     "set",
+			action:   LoadSet,
+			format:   FormatText,
+			config:   "<configuration-set>set system host-name r1</configuration-set>",
+			expected: "<load-configuration action=\"set\" format=\"text\">\n<configuration-set>set system host-name r1</configuration-set>\n</load-configuration>\n",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got := buildLoadConfiguration(tc.action, tc.format, tc.config)
+			if got != tc.expected {
+				t.Errorf("got %q, expected %q", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestLoadConfigOverride(t *testing.T) {
+	g, fd := newTestClient(loadSuccessReply)
+
+	config := "<configuration-text>system { host-name r1; }</configuration-text>"
+
+	_, err := g.LoadConfig(config, LoadOverride, FormatText, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{buildLoadConfiguration(LoadOverride, FormatText, config), commitStr}
+	if len(fd.Sent) != len(expected) || fd.Sent[0] != expected[0] || fd.Sent[1] != expected[1] {
+		t.Errorf("got RPCs %q, expected %q", fd.Sent, expected)
+	}
+
+	if !strings.Contains(fd.Sent[0], `action="override" format="text"`) {
+		t.Errorf("got %q, expected an override of text", fd.Sent[0])
+	}
+}
+
+func TestUpdateRawConfigTarget(t *testing.T) {
+	config := "<configuration><groups><name>test-group</name></groups></configuration>"
+
+	tt := []struct {
+		name         string
+		capabilities []string
+		expected     []string
+		datastore    string // Reported by EditedDatastore afterwards
+		err          error
+	}{
+		{
+			name:         "candidate",
+			capabilities: []string{"urn:ietf:params:netconf:base:1.0", capabilityCandidate},
+			datastore:    "candidate",
+			expected: []string{
+				buildDeleteGroup("candidate", "", "test-group", true),
+				buildLoadConfiguration(LoadMerge, FormatXML, config),
+				commitStr,
+			},
+		},
+		{
+			name:         "writable running",
+			capabilities: []string{"urn:ietf:params:netconf:base:1.0", capabilityWritableRunning},
+			datastore:    "running",
+			expected: []string{
+				buildDeleteGroup("running", "", "test-group", true),
+				"<edit-config><target><running/></target><config>" + config + "</config></edit-config>",
+			},
+		},
+		{
+			name:         "neither",
+			capabilities: []string{"urn:ietf:params:netconf:base:1.0"},
+			err:          ErrCandidateUnsupported,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g, fd := newTestClient(okReply, loadSuccessReply)
+			fd.Capabilities = tc.capabilities
+
+			_, err := g.UpdateRawConfig("test-group", config, true)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("got error %v, expected %v", err, tc.err)
+			}
+
+			if ds := g.EditedDatastore(); ds != tc.datastore {
+				t.Errorf("got edited datastore %q, expected %q", ds, tc.datastore)
+			}
+
+			if len(fd.Sent) != len(tc.expected) {
+				t.Fatalf("got RPCs %q, expected %q", fd.Sent, tc.expected)
+			}
+			for i := range tc.expected {
+				if fd.Sent[i] != tc.expected[i] {
+					t.Errorf("got RPC %q, expected %q", fd.Sent[i], tc.expected[i])
+				}
+			}
+
+			if fd.Closes != 1 {
+				t.Errorf("got %d closes, expected 1", fd.Closes)
+			}
+		})
+	}
+}
+
+func TestUpdateRawConfigErrorOption(t *testing.T) {
+	config := "<configuration><groups><name>test-group</name></groups></configuration>"
+
+	g, fd := newTestClient(okReply, loadSuccessReply)
+	fd.Capabilities = []string{"urn:ietf:params:netconf:base:1.0", capabilityCandidate}
+	g.ErrorOption = rpc.ErrorOptionContinueOnError
+
+	_, err := g.UpdateRawConfig("test-group", config, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := buildDeleteGroup(datastoreCandidate, "<error-option>continue-on-error</error-option>", "test-group", true)
+	if len(fd.Sent) == 0 || fd.Sent[0] != expected {
+		t.Errorf("got RPCs %q, expected the delete %q first", fd.Sent, expected)
+	}
+
+	// rollback-on-error is refused before anything is sent unless the device advertises it
+	g, fd = newTestClient()
+	fd.Capabilities = []string{"urn:ietf:params:netconf:base:1.0", capabilityCandidate}
+	g.ErrorOption = rpc.ErrorOptionRollbackOnError
+
+	_, err = g.UpdateRawConfig("test-group", config, false)
+	if err == nil || !strings.Contains(err.Error(), rpc.CapabilityRollbackOnError) {
+		t.Errorf("got error %v, expected the missing rollback-on-error capability", err)
+	}
+
+	if len(fd.Sent) != 0 {
+		t.Errorf("got RPCs %q, expected none", fd.Sent)
+	}
+
+	// On a device editing running the error-option also goes with the edit itself
+	g, fd = newTestClient(okReply, okReply)
+	fd.Capabilities = []string{"urn:ietf:params:netconf:base:1.0", capabilityWritableRunning, rpc.CapabilityRollbackOnError}
+	g.ErrorOption = rpc.ErrorOptionRollbackOnError
+
+	_, err = g.UpdateRawConfig("test-group", config, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	edit := "<edit-config><target><running/></target><error-option>rollback-on-error</error-option><config>" + config + "</config></edit-config>"
+	if len(fd.Sent) != 2 || fd.Sent[1] != edit {
+		t.Errorf("got RPCs %q, expected the delete then %q", fd.Sent, edit)
+	}
+}
+
+func TestUpdateRawConfigTestOption(t *testing.T) {
+	config := "<configuration><groups><name>test-group</name></groups></configuration>"
+
+	g, fd := newTestClient(okReply, loadSuccessReply)
+	fd.Capabilities = []string{"urn:ietf:params:netconf:base:1.0", capabilityCandidate, rpc.CapabilityValidate10}
+	g.TestOption = rpc.TestOptionTestThenSet
+	g.ErrorOption = rpc.ErrorOptionStopOnError
+
+	_, err := g.UpdateRawConfig("test-group", config, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	options := "<test-option>test-then-set</test-option><error-option>stop-on-error</error-option>"
+	expected := buildDeleteGroup(datastoreCandidate, options, "test-group", true)
+	if len(fd.Sent) == 0 || fd.Sent[0] != expected {
+		t.Errorf("got RPCs %q, expected the delete %q first", fd.Sent, expected)
+	}
+
+	// test-only needs :validate:1.1, refused before anything is sent
+	g, fd = newTestClient()
+	fd.Capabilities = []string{"urn:ietf:params:netconf:base:1.0", capabilityCandidate, rpc.CapabilityValidate10}
+	g.TestOption = rpc.TestOptionTestOnly
+
+	_, err = g.UpdateRawConfig("test-group", config, false)
+	if err == nil || !strings.Contains(err.Error(), rpc.CapabilityValidate11) {
+		t.Errorf("got error %v, expected the missing validate:1.1 capability", err)
+	}
+
+	if len(fd.Sent) != 0 {
+		t.Errorf("got RPCs %q, expected none", fd.Sent)
+	}
+
+	// Unknown values are refused even when the driver cannot report capabilities
+	g, fd = newTestClient()
+	g.TestOption = "test-maybe"
+
+	_, err = g.UpdateRawConfig("test-group", config, false)
+	if err == nil || len(fd.Sent) != 0 {
+		t.Errorf("got error %v and RPCs %q, expected the unknown test-option refused", err, fd.Sent)
+	}
+}
+
+func TestUpdateRawConfigDirtyCandidate(t *testing.T) {
+	config := "<configuration><groups><name>test-group</name></groups></configuration>"
+
+	g, fd := newTestClient(diffCompareReply)
+	g.GuardDirtyCandidate = true
+
+	_, err := g.UpdateRawConfig("test-group", config, true)
+	if !errors.Is(err, ErrCandidateDirty) {
+		t.Fatalf("got error %v, expected ErrCandidateDirty", err)
+	}
+
+	if !strings.Contains(err.Error(), "host-name r2") {
+		t.Errorf("got error %v, expected it to carry the pending diff", err)
+	}
+
+	if len(fd.Sent) != 1 || fd.Sent[0] != compareStr {
+		t.Errorf("got RPCs %q, expected only the compare, nothing deleted, loaded or committed", fd.Sent)
+	}
+
+	// A clean candidate goes ahead
+	g, fd = newTestClient(emptyCompareReply, okReply, loadSuccessReply, okReply)
+	g.GuardDirtyCandidate = true
+
+	_, err = g.UpdateRawConfig("test-group", config, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(fd.Sent) != 4 || fd.Sent[3] != commitStr {
+		t.Errorf("got RPCs %q, expected the compare, delete, load and commit", fd.Sent)
+	}
+}
+
+func TestSendTransactionRaw(t *testing.T) {
+	// Self-closing elements, single quoted attributes and comments, all of which a marshal would rewrite
+	config := `<configuration><groups><name>test-group</name><interfaces><interface><name>ge-0/0/0</name>` +
+		`<disable/><description junos:comment='x'>uplink</description><!-- kept --></interface></interfaces></groups></configuration>`
+
+	g, fd := newTestClient(okReply, loadSuccessReply, okReply)
+
+	err := g.SendTransactionRaw("test-group", config, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		buildDeleteGroup(datastoreCandidate, "", "test-group", true),
+		buildLoadConfiguration(LoadMerge, FormatXML, config),
+		commitStr,
+	}
+	if strings.Join(fd.Sent, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("got RPCs %q, expected %q", fd.Sent, expected)
+	}
+
+	for _, invalid := range []string{"", "uplink", "<configuration><groups></configuration>"} {
+		g, fd = newTestClient()
+
+		err = g.SendTransactionRaw("test-group", invalid, true)
+		if err == nil {
+			t.Errorf("%q: expected an error", invalid)
+		}
+
+		if len(fd.Sent) != 0 {
+			t.Errorf("%q: got RPCs %q, expected none", invalid, fd.Sent)
+		}
+	}
+}
+
+func TestSendTransactionContextCancelled(t *testing.T) {
+	g, fd := newTestClient(okReply, loadSuccessReply, okReply)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Cancel once the configuration is loaded, before it is committed
+	fd.Delay = func(rawxml string) {
+		if strings.HasPrefix(rawxml, "<load-configuration") {
+			cancel()
+		}
+	}
+
+	err := g.SendTransactionContext(ctx, "test-group", struct {
+		XMLName xml.Name `xml:"configuration"`
+	}{}, true)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, expected context.Canceled", err)
+	}
+
+	if len(fd.Sent) != 3 || fd.Sent[2] != discardChangesStr {
+		t.Errorf("got RPCs %q, expected the delete and load to be discarded rather than committed", fd.Sent)
+	}
+
+	if fd.Closes != 1 {
+		t.Errorf("got %d closes, expected 1", fd.Closes)
+	}
+}
+
+// hangingDriver never answers discard-changes, until it is closed
+type hangingDriver struct {
+	*testdriver.Driver
+	closed chan struct{}
+}
+
+func (h *hangingDriver) Close() error {
+	close(h.closed)
+	return h.Driver.Close()
+}
+
+func (h *hangingDriver) SendRaw(rawxml string) (*rpc.RPCReply, error) {
+	if rawxml == discardChangesStr {
+		<-h.closed
+		return nil, errors.New("session closed")
+	}
+
+	return h.Driver.SendRaw(rawxml)
+}
+
+func TestSendTransactionContextCleanupTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { transactionCleanupTimeout = timeout }(transactionCleanupTimeout)
+	transactionCleanupTimeout = 10 * time.Millisecond
+
+	hd := &hangingDriver{Driver: testdriver.New(okReply, loadSuccessReply), closed: make(chan struct{})}
+	g := &GoNCClient{Driver: hd}
+
+	err := g.Dial()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hd.Delay = func(rawxml string) {
+		if strings.HasPrefix(rawxml, "<load-configuration") {
+			cancel()
+		}
+	}
+
+	err = g.SendTransactionContext(ctx, "test-group", struct {
+		XMLName xml.Name `xml:"configuration"`
+	}{}, true)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, expected context.Canceled", err)
+	}
+
+	// The unanswered session is torn down once, and the next operation dials a new one
+	if hd.Closes != 1 || g.sessionOpen {
+		t.Errorf("got %d closes with the session held %t, expected the session closed once", hd.Closes, g.sessionOpen)
+	}
+
+	hd.closed = make(chan struct{})
+
+	_, err = g.SendRawConfig("<configuration/>", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if hd.Dials != 2 || hd.Closes != 2 {
+		t.Errorf("got %d dials and %d closes, expected the next operation on a session of its own", hd.Dials, hd.Closes)
+	}
+}
+
+func TestSendTransactionContextSharesUpdatePath(t *testing.T) {
+	g, fd := newTestClient(diffCompareReply)
+	g.GuardDirtyCandidate = true
+
+	err := g.SendTransactionContext(context.Background(), "test-group", struct {
+		XMLName xml.Name `xml:"configuration"`
+	}{}, true)
+	if !errors.Is(err, ErrCandidateDirty) {
+		t.Fatalf("got error %v, expected %v", err, ErrCandidateDirty)
+	}
+
+	if len(fd.Sent) != 1 {
+		t.Errorf("got RPCs %q, expected only the compare", fd.Sent)
+	}
+
+	g, fd = newTestClient()
+	fd.Capabilities = []string{"urn:ietf:params:netconf:base:1.0", capabilityWritableRunning}
+
+	err = g.SendTransactionContext(context.Background(), "test-group", struct {
+		XMLName xml.Name `xml:"configuration"`
+	}{}, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if g.EditedDatastore() != datastoreRunning {
+		t.Errorf("got edited datastore %q, expected running", g.EditedDatastore())
+	}
+}
+
+func TestUpdateRawConfigLoadFailedDiscards(t *testing.T) {
+	g, fd := newTestClient(okReply, loadErrorReply)
+
+	_, err := g.UpdateRawConfig("test-group", "<configuration/>", true)
+	if err == nil {
+		t.Fatal("expected a load error")
+	}
+
+	// The group deleted before the failed load must not be left deleted in the candidate
+	if len(fd.Sent) != 3 || fd.Sent[2] != discardChangesStr {
+		t.Errorf("got RPCs %q, expected the delete and load to be discarded", fd.Sent)
+	}
+}
+
+// readMessage reads a NETCONF message framed with the end-of-message separator
+func readMessage(r *bufio.Reader) (string, error) {
+	var msg strings.Builder
+	for !strings.HasSuffix(msg.String(), "]]>]]>") {
+		s, err := r.ReadString('>')
+		if err != nil {
+			return "", err
+		}
+		msg.WriteString(s)
+	}
+
+	return strings.TrimSpace(strings.TrimSuffix(msg.String(), "]]>]]>")), nil
+}
+
+func TestNewClientFromConn(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+
+	requests := make(chan string, 2)
+
+	go func() {
+		defer serverConn.Close()
+		r := bufio.NewReader(serverConn)
+
+		io.WriteString(serverConn, `<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">`+
+			`<capabilities><capability>urn:ietf:params:netconf:base:1.0</capability></capabilities>`+
+			`<session-id>7</session-id></hello>]]>]]>`)
+
+		for i := 0; i < 2; i++ {
+			msg, err := readMessage(r)
+			if err != nil {
+				return
+			}
+			requests <- msg
+		}
+
+		// net.Pipe is unbuffered, reply while draining the rest of the request and the close-session
+		go io.WriteString(serverConn, `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">`+
+			`<data><configuration><system><host-name>r1</host-name></system></configuration></data>`+
+			`</rpc-reply>]]>]]>`)
+
+		io.Copy(ioutil.Discard, r)
+	}()
+
+	nc, err := NewClientFromConn(clientConn)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	g := nc.(*GoNCClient)
+
+	reply, err := g.Driver.GetConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "<data><configuration><system><host-name>r1</host-name></system></configuration></data>"
+	if reply.Data != expected {
+		t.Errorf("got %q, expected %q", reply.Data, expected)
+	}
+
+	err = g.Close()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	hello := <-requests
+	if !strings.Contains(hello, "<hello") {
+		t.Errorf("got %q, expected the client hello", hello)
+	}
+
+	request := <-requests
+	if !strings.Contains(request, "<get-config><source><running/></source></get-config>") {
+		t.Errorf("got %q, expected a get-config of running", request)
+	}
+}
+
+// blockingDriver holds every RPC until it is released, to close the client mid-operation
+type blockingDriver struct {
+	*testdriver.Driver
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingDriver) SendRaw(rawxml string) (*rpc.RPCReply, error) {
+	b.started <- struct{}{}
+	<-b.release
+	return b.Driver.SendRaw(rawxml)
+}
+
+func TestCloseConcurrent(t *testing.T) {
+	bd := &blockingDriver{
+		Driver:  testdriver.New(),
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	g := &GoNCClient{Driver: bd}
+
+	inFlight := make(chan error)
+	go func() {
+		inFlight <- g.SendCommit()
+	}()
+
+	<-bd.started
+
+	closed := make(chan error)
+	for i := 0; i < 2; i++ {
+		go func() {
+			closed <- g.Close()
+		}()
+	}
+
+	select {
+	case <-closed:
+		t.Fatal("Close returned while an operation was in flight")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(bd.release)
+
+	err := <-inFlight
+	if err != nil {
+		t.Errorf("unexpected error from the in-flight operation: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		err = <-closed
+		if err != nil {
+			t.Errorf("unexpected error from Close: %v", err)
+		}
+	}
+
+	err = g.SendCommit()
+	if !errors.Is(err, helpers.ErrSessionClosed) {
+		t.Errorf("got error %v, expected %v", err, helpers.ErrSessionClosed)
+	}
+
+	_, err = g.SendRawConfig("<configuration/>", true)
+	if !errors.Is(err, helpers.ErrSessionClosed) {
+		t.Errorf("got error %v, expected %v", err, helpers.ErrSessionClosed)
+	}
+
+	err = g.Dial()
+	if !errors.Is(err, helpers.ErrSessionClosed) {
+		t.Errorf("got error %v, expected %v", err, helpers.ErrSessionClosed)
+	}
+
+	err = g.Close()
+	if err != nil {
+		t.Errorf("unexpected error from a repeated Close: %v", err)
+	}
+}
+
+// exclusiveDriver fails the test if two RPCs are ever in flight on its session at once
+type exclusiveDriver struct {
+	*testdriver.Driver
+	t        *testing.T
+	inFlight int32
+}
+
+func (e *exclusiveDriver) SendRaw(rawxml string) (*rpc.RPCReply, error) {
+	if atomic.AddInt32(&e.inFlight, 1) != 1 {
+		e.t.Error("two RPCs were in flight on the same session")
+	}
+	defer atomic.AddInt32(&e.inFlight, -1)
+
+	time.Sleep(time.Millisecond)
+
+	return e.Driver.SendRaw(rawxml)
+}
+
+// Run with -race, the fake driver is only safe because the client serializes access to it
+func TestConcurrentOperations(t *testing.T) {
+	ed := &exclusiveDriver{Driver: testdriver.New(), t: t}
+	g := &GoNCClient{Driver: ed}
+
+	ops := []func() error{
+		func() error { _, err := g.ReadRawGroup("test"); return err },
+		func() error { _, err := g.SendRawConfig("<configuration/>", true); return err },
+		func() error { _, err := g.ListGroups(); return err },
+		func() error { _, err := g.Get(""); return err },
+		func() error { _, err := g.DeleteConfigNoCommit("test"); return err },
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		for _, op := range ops {
+			wg.Add(1)
+			go func(op func() error) {
+				defer wg.Done()
+				if err := op(); err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}(op)
+		}
+	}
+	wg.Wait()
+
+	if ed.Dials != ed.Closes {
+		t.Errorf("got %d dials and %d closes, expected every session to be closed", ed.Dials, ed.Closes)
+	}
+}
+
+// failingDriver fails every RPC
+type failingDriver struct {
+	*testdriver.Driver
+}
+
+func (f *failingDriver) SendRaw(rawxml string) (*rpc.RPCReply, error) {
+	return nil, errors.New("connection reset")
+}
+
+func TestSendErrorReleasesSession(t *testing.T) {
+	fd := &failingDriver{Driver: testdriver.New()}
+	g := &GoNCClient{Driver: fd}
+
+	_, err := g.ReadGroup("test")
+	if err == nil || !strings.Contains(err.Error(), "connection reset") {
+		t.Fatalf("got error %v, expected the driver error", err)
+	}
+
+	if fd.Closes != 1 {
+		t.Errorf("got %d closes, expected the session to be closed", fd.Closes)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		g.ReadRawGroup("test")
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the client lock was not released after a driver error")
+	}
+}
+
+func TestNewClientContextCancel(t *testing.T) {
+	// The listener accepts the connection but never answers, leaving the SSH handshake in progress
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		cancel()
+		time.Sleep(time.Second)
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := NewClientContext(ctx, ClientOptions{Username: "admin", Password: "secret", Address: addr.IP.String(), Port: addr.Port})
+		errCh <- err
+	}()
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("got error %v, expected %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("NewClientContext did not return after the context was cancelled")
+	}
+}
+
+func TestClone(t *testing.T) {
+	var transcript strings.Builder
+
+	g, err := newClientWithOptions(ClientOptions{
+		Username:         "admin",
+		Password:         "secret",
+		Address:          "192.0.2.1",
+		Timeout:          5 * time.Second,
+		LegacyAlgorithms: true,
+		Debug:            &transcript,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	g.StripNewlines = true
+	g.CommitRetries = 3
+	g.JSONNamespaces = map[string]string{"ietf-interfaces": "urn:ietf:params:xml:ns:yang:ietf-interfaces"}
+
+	clone := g.Clone("192.0.2.2", 0)
+
+	d := g.Driver.(*sshdriver.DriverSSH)
+	cd := clone.Driver.(*sshdriver.DriverSSH)
+
+	if cd.Host != "192.0.2.2" || cd.Port != 830 {
+		t.Errorf("got target %s:%d, expected 192.0.2.2:830", cd.Host, cd.Port)
+	}
+	if d.Host != "192.0.2.1" {
+		t.Errorf("got original target %s, expected it to be unchanged", d.Host)
+	}
+
+	if cd.SSHConfig.User != "admin" || cd.SSHConfig.Timeout != 5*time.Second ||
+		len(cd.SSHConfig.Auth) != len(d.SSHConfig.Auth) || len(cd.SSHConfig.Ciphers) != len(d.SSHConfig.Ciphers) {
+		t.Errorf("got ssh config %+v, expected the original's", cd.SSHConfig)
+	}
+	if cd.Debug != d.Debug {
+		t.Error("expected the clone to share the debug transcript")
+	}
+	if !clone.StripNewlines || clone.CommitRetries != 3 {
+		t.Error("expected the clone to share the client settings")
+	}
+
+	// The clone is independent and unconnected
+	cd.SSHConfig.User = "operator"
+	if d.SSHConfig.User != "admin" {
+		t.Error("changing the clone's ssh config changed the original's")
+	}
+
+	if clone.JSONNamespaces["ietf-interfaces"] != g.JSONNamespaces["ietf-interfaces"] {
+		t.Errorf("got JSON namespaces %v, expected the original's", clone.JSONNamespaces)
+	}
+	clone.JSONNamespaces["ietf-ip"] = "urn:ietf:params:xml:ns:yang:ietf-ip"
+	if _, ok := g.JSONNamespaces["ietf-ip"]; ok {
+		t.Error("changing the clone's JSON namespaces changed the original's")
+	}
+	if cd.Transport == d.Transport || cd.Transport.SSHClient != nil || clone.sessionOpen {
+		t.Error("expected the clone to have a transport of its own and no session")
+	}
+
+	// Clients without an SSH driver can not be retargeted
+	fc, _ := newTestClient()
+	err = fc.Clone("192.0.2.2", 830).Dial()
+	if !errors.Is(err, helpers.ErrSessionClosed) {
+		t.Errorf("got error %v, expected %v", err, helpers.ErrSessionClosed)
+	}
+}
+
+func TestDeleteConfigApplyGroups(t *testing.T) {
+	tt := []struct {
+		name      string
+		unapplied bool
+		expected  bool
+	}{
+		{name: "with reference", unapplied: false, expected: true},
+		{name: "without reference", unapplied: true, expected: false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g, fd := newTestClient()
+			g.UnappliedGroups = tc.unapplied
+
+			_, err := g.DeleteConfigNoCommit("test-group")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(fd.Sent) != 1 || !strings.Contains(fd.Sent[0], `<groups operation="delete">`) {
+				t.Fatalf("got RPCs %q, expected the group to be deleted", fd.Sent)
+			}
+
+			deleted := strings.Contains(fd.Sent[0], `<apply-groups operation="delete">test-group</apply-groups>`)
+			if deleted != tc.expected {
+				t.Errorf("got apply-groups deleted %t, expected %t", deleted, tc.expected)
+			}
+		})
+	}
+}
+
+// flakyDriver fails its first failures sends, as a session torn down mid-operation would
+type flakyDriver struct {
+	*testdriver.Driver
+	failures int
+}
+
+func (f *flakyDriver) SendRaw(rawxml string) (*rpc.RPCReply, error) {
+	if f.failures > 0 {
+		f.failures--
+		return nil, errors.New("connection reset")
+	}
+
+	return f.Driver.SendRaw(rawxml)
+}
+
+func TestReset(t *testing.T) {
+	fd := &flakyDriver{Driver: testdriver.New(groupReply), failures: 1}
+	g := &GoNCClient{Driver: fd}
+
+	err := g.Dial()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	g.setGroupLock("test", groupLock{})
+
+	_, err = g.ReadGroup("test")
+	if err == nil {
+		t.Fatal("expected the first operation to fail")
+	}
+
+	err = g.Reset()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if fd.Closes != 1 || g.sessionOpen || len(g.groupLocks) != 0 {
+		t.Errorf("got %d closes, session open %v and %d group locks, expected the session to be disposed of",
+			fd.Closes, g.sessionOpen, len(g.groupLocks))
+	}
+
+	_, err = g.ReadGroup("test")
+	if err != nil {
+		t.Fatalf("got error %v after Reset, expected the client to be usable", err)
+	}
+
+	// A closed client gets its driver back from the options it was built with
+	sc, err := newClientWithOptions(ClientOptions{Username: "admin", Password: "secret", Address: "192.0.2.1", Port: 2830})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sc.Close()
+	keys := sc.agent
+
+	err = sc.Reset()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if sc.agent == nil || sc.agent == keys {
+		t.Error("expected the client to take the agent connection of its rebuilt driver")
+	}
+
+	d, ok := sc.Driver.(*sshdriver.DriverSSH)
+	if !ok || d.Host != "192.0.2.1" || d.Port != 2830 || d.SSHConfig.User != "admin" {
+		t.Errorf("got driver %+v, expected an SSH driver for admin@192.0.2.1:2830", sc.Driver)
+	}
+
+	// Without connection parameters there is nothing to rebuild
+	fc, _ := newTestClient()
+	fc.Close()
+	if fc.Reset() == nil {
+		t.Error("expected resetting a closed client without options to fail")
+	}
+}
+
+// transactionConfig is a configuration as SendTransaction callers marshal it
+type transactionConfig struct {
+	XMLName    xml.Name `xml:"configuration"`
+	Interfaces []struct {
+		Name        string `xml:"name"`
+		Description string `xml:"description"`
+	} `xml:"interfaces>interface"`
+}
+
+func newTransactionConfig(n int) transactionConfig {
+	var config transactionConfig
+	config.Interfaces = make([]struct {
+		Name        string `xml:"name"`
+		Description string `xml:"description"`
+	}, n)
+
+	for i := range config.Interfaces {
+		config.Interfaces[i].Name = fmt.Sprintf("ge-0/0/%d", i)
+		config.Interfaces[i].Description = "uplink <core> & ]]>]]> edge"
+	}
+
+	return config
+}
+
+// legacyTransactionRPC builds the load-configuration RPC of a transaction with xml.Marshal and fmt.Sprintf,
+// as SendTransaction did before pooling its buffers
+func legacyTransactionRPC(obj interface{}) (string, error) {
+	config, err := xml.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("<load-configuration action=\"%s\" format=\"%s\">\n%s\n</load-configuration>\n",
+		xmlEscape(LoadMerge), xmlEscape(FormatXML), escapePayload(string(config))), nil
+}
+
+// pooledTransactionRPC builds the load-configuration RPC of a transaction as SendTransaction does
+func pooledTransactionRPC(obj interface{}) (string, error) {
+	config, err := marshalConfig(obj)
+	if err != nil {
+		return "", err
+	}
+
+	return buildLoadConfiguration(LoadMerge, FormatXML, config), nil
+}
+
+func TestPooledTransactionRPC(t *testing.T) {
+	for _, n := range []int{0, 1, 500} {
+		config := newTransactionConfig(n)
+
+		expected, err := legacyTransactionRPC(config)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// Twice, so the second run reuses a pooled buffer
+		for i := 0; i < 2; i++ {
+			got, err := pooledTransactionRPC(config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != expected {
+				t.Errorf("%d interfaces: got %q, expected %q", n, got, expected)
+			}
+		}
+	}
+
+	_, err := marshalConfig(make(chan int))
+	if err == nil {
+		t.Error("expected an error marshaling an unsupported type")
+	}
+}
+
+func BenchmarkTransactionRPC(b *testing.B) {
+	config := newTransactionConfig(500)
+
+	for _, bc := range []struct {
+		name  string
+		build func(interface{}) (string, error)
+	}{
+		{name: "legacy", build: legacyTransactionRPC},
+		{name: "pooled", build: pooledTransactionRPC},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, err := bc.build(config)
+				if err != nil {
+					b.Fatalf("unexpected error: %v", err)
+				}
+			}
+		})
+	}
+}
+
+const dataWithErrorReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="7">
+<software-information><host-name>r1</host-name></software-information>
+<rpc-error>
+<error-type>application</error-type>
+<error-tag>operation-failed</error-tag>
+<error-severity>error</error-severity>
+<error-message>fpc1 not responding</error-message>
+</rpc-error>
+</rpc-reply>`
+
+func TestSendRawNetconf(t *testing.T) {
+	g, _ := newTestClient(dataWithErrorReply)
+
+	reply, err := g.SendRawNetconf("<get-software-information/>")
+
+	var rpcErr *rpc.RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Message != "fpc1 not responding" {
+		t.Errorf("got error %v, expected the rpc-error", err)
+	}
+
+	if reply == nil {
+		t.Fatal("expected the reply to be returned with the error")
+	}
+
+	if !strings.Contains(reply.Data, "<host-name>r1</host-name>") {
+		t.Errorf("got data %q, expected the software information", reply.Data)
+	}
+
+	if len(reply.Errors) != 1 || reply.MessageID != "7" || reply.RawReply != dataWithErrorReply {
+		t.Errorf("got reply %+v, expected its rpc-error, message-id and raw bytes", reply)
+	}
+
+	// The internal path still only returns the error
+	g, _ = newTestClient(dataWithErrorReply)
+
+	reply, err = g.sendRaw("<get-software-information/>")
+	if err == nil || reply != nil {
+		t.Errorf("got reply %v and error %v, expected only the error", reply, err)
+	}
+}
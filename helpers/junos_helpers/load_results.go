@@ -0,0 +1,85 @@
+package junos_helpers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// LoadError is a single error reported by Junos against a load-configuration payload
+type LoadError struct {
+	Severity   string `xml:"error-severity"`
+	Path       string `xml:"error-path"`
+	Element    string `xml:"error-info>bad-element"`
+	Message    string `xml:"error-message"`
+	LineNumber int    `xml:"line-number"`
+	Column     int    `xml:"column"`
+	Token      string `xml:"token"`
+	Statement  string `xml:"statement"`
+}
+
+// LoadResults holds the parsed <load-configuration-results> of a load-configuration reply
+type LoadResults struct {
+	ErrorCount int         `xml:"load-error-count"`
+	Errors     []LoadError `xml:"rpc-error"`
+}
+
+// LoadConfigError is returned when the device reports a load-error-count greater than zero
+type LoadConfigError struct {
+	Results *LoadResults
+}
+
+// Error generates a string representation of every load error reported by the device
+func (e *LoadConfigError) Error() string {
+	var details []string
+
+	for _, le := range e.Results.Errors {
+		// Junos also returns informational "error recovery" entries, skip anything without a message
+		msg := strings.TrimSpace(le.Message)
+		if msg == "" {
+			continue
+		}
+
+		if le.LineNumber > 0 {
+			msg = fmt.Sprintf("line %d column %d: %s", le.LineNumber, le.Column, msg)
+		}
+
+		if el := strings.TrimSpace(le.Element); el != "" {
+			msg = fmt.Sprintf("%s (%s)", msg, el)
+		}
+
+		details = append(details, msg)
+	}
+
+	return fmt.Sprintf("load-configuration failed with %d error(s): %s", e.Results.ErrorCount, strings.Join(details, "; "))
+}
+
+// ParseLoadResults extracts the load-configuration-results from the data of a reply.
+// A nil result is returned when the reply does not carry any.
+func ParseLoadResults(data string) (*LoadResults, error) {
+	wrapper := struct {
+		Results *LoadResults `xml:"load-configuration-results"`
+	}{}
+
+	// The reply data is a fragment, so wrap it to get a single root element
+	err := xml.Unmarshal([]byte("<rpc-reply>"+data+"</rpc-reply>"), &wrapper)
+	if err != nil {
+		return nil, err
+	}
+
+	return wrapper.Results, nil
+}
+
+// checkLoadResults returns a LoadConfigError when the device rejected part of a load
+func checkLoadResults(data string) error {
+	results, err := ParseLoadResults(data)
+	if err != nil {
+		return err
+	}
+
+	if results != nil && results.ErrorCount > 0 {
+		return &LoadConfigError{Results: results}
+	}
+
+	return nil
+}
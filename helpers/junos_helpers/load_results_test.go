@@ -0,0 +1,106 @@
+package junos_helpers
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const loadErrorReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:junos="http://xml.juniper.net/junos/18.2R1/junos">
+<load-configuration-results>
+<rpc-error>
+<error-severity>error</error-severity>
+<line-number>3</line-number>
+<column>9</column>
+<token>mtuu</token>
+<error-info>
+<bad-element>mtuu</bad-element>
+</error-info>
+<error-message>syntax error</error-message>
+</rpc-error>
+<rpc-error>
+<error-severity>error</error-severity>
+<line-number>7</line-number>
+<column>5</column>
+<error-message>missing mandatory statement: 'address'</error-message>
+</rpc-error>
+<rpc-error>
+<error-severity>error</error-severity>
+<error-message>
+error recovery ignores input until this point
+</error-message>
+</rpc-error>
+<load-error-count>2</load-error-count>
+</load-configuration-results>
+</rpc-reply>`
+
+const loadSuccessReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<load-configuration-results>
+<ok/>
+</load-configuration-results>
+</rpc-reply>`
+
+func TestSendRawConfigLoadErrors(t *testing.T) {
+	g, fd := newTestClient(loadErrorReply)
+
+	_, err := g.SendRawConfig("<configuration/>", true)
+	if err == nil {
+		t.Fatal("expected a load error, got nil")
+	}
+
+	var loadErr *LoadConfigError
+	if !errors.As(err, &loadErr) {
+		t.Fatalf("got error type %T, expected *LoadConfigError", err)
+	}
+
+	if loadErr.Results.ErrorCount != 2 {
+		t.Errorf("got error count %d, expected 2", loadErr.Results.ErrorCount)
+	}
+
+	lines := []int{}
+	for _, le := range loadErr.Results.Errors {
+		if le.LineNumber > 0 {
+			lines = append(lines, le.LineNumber)
+		}
+	}
+	if len(lines) != 2 || lines[0] != 3 || lines[1] != 7 {
+		t.Errorf("got line numbers %v, expected [3 7]", lines)
+	}
+
+	if loadErr.Results.Errors[0].Element != "mtuu" {
+		t.Errorf("got bad element %q, expected mtuu", loadErr.Results.Errors[0].Element)
+	}
+
+	if !strings.Contains(err.Error(), "line 3 column 9: syntax error (mtuu)") {
+		t.Errorf("error string %q does not describe the rejected line", err.Error())
+	}
+
+	// The load failed, so the commit must not have been sent and the partial load is discarded
+	if len(fd.Sent) != 2 || fd.Sent[1] != discardChangesStr {
+		t.Errorf("got RPCs %q, expected the load followed by discard-changes", fd.Sent)
+	}
+}
+
+func TestSendRawConfigLoadSuccess(t *testing.T) {
+	g, fd := newTestClient(loadSuccessReply)
+
+	_, err := g.SendRawConfig("<configuration/>", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(fd.Sent) != 2 || fd.Sent[1] != commitStr {
+		t.Errorf("expected load followed by commit, got %v", fd.Sent)
+	}
+}
+
+func TestParseLoadResultsAbsent(t *testing.T) {
+	results, err := ParseLoadResults("<ok/>")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if results != nil {
+		t.Errorf("got %+v, expected nil results", results)
+	}
+}
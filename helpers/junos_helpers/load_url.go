@@ -0,0 +1,124 @@
+package junos_helpers
+
+import (
+	"fmt"
+	"strings"
+
+	rpc "github.com/davedotdev/go-netconf/rpc"
+)
+
+// capabilityURL is advertised by devices able to fetch a configuration from a URL, listing the schemes
+// they support as a query parameter, e.g. ?scheme=http,ftp,file
+const capabilityURL = "urn:ietf:params:netconf:capability:url:1.0"
+
+const loadConfigurationURLStr = `<load-configuration action="%s" url="%s"/>
+`
+
+// urlSchemes returns the schemes listed by the :url capability, nil when it lists none
+func urlSchemes(capabilities []string) []string {
+	for _, c := range capabilities {
+		i := strings.Index(c, "?")
+		if i == -1 || strings.TrimSpace(c[:i]) != capabilityURL {
+			continue
+		}
+
+		for _, param := range strings.Split(c[i+1:], "&") {
+			if strings.HasPrefix(param, "scheme=") {
+				return strings.Split(strings.TrimPrefix(param, "scheme="), ",")
+			}
+		}
+	}
+
+	return nil
+}
+
+// requireURL returns ErrCapabilityNotSupported unless the open session advertised :url and, when the
+// capability lists schemes, the scheme of url
+func (g *GoNCClient) requireURL(url string) error {
+	err := g.requireCapability(capabilityURL)
+	if err != nil {
+		return err
+	}
+
+	i := strings.Index(url, "://")
+	if i == -1 {
+		return fmt.Errorf("url %q has no scheme", url)
+	}
+	scheme := strings.ToLower(url[:i])
+
+	schemes := urlSchemes(g.serverCapabilities())
+	if schemes == nil {
+		return nil
+	}
+
+	for _, s := range schemes {
+		if s == scheme {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s with scheme %s, the device supports %s", ErrCapabilityNotSupported, capabilityURL, scheme, strings.Join(schemes, ","))
+}
+
+// LoadConfigURL has the device fetch the configuration at url itself, e.g. over HTTP, FTP or TFTP, which is
+// far faster than streaming a large configuration over the session. It is loaded into the candidate with
+// the given load-configuration action and optionally committed. The device must advertise :url, with the
+// url's scheme when it lists schemes. On a device without the candidate capability an edit-config fetching
+// the url is written straight to running, as with UpdateRawConfig, and action and commit have no effect.
+func (g *GoNCClient) LoadConfigURL(url string, action string, commit bool) error {
+	return g.withSession(func() error {
+		err := g.requireURL(url)
+		if err != nil {
+			return err
+		}
+
+		target, err := g.editTarget()
+		if err != nil {
+			return err
+		}
+
+		if target == datastoreRunning {
+			err = g.checkEditOptions()
+			if err != nil {
+				return err
+			}
+
+			edit := rpc.EditConfig{Target: rpc.Datastore(target), TestOption: g.TestOption, ErrorOption: g.ErrorOption, URL: url}
+
+			err = edit.Validate()
+			if err != nil {
+				return err
+			}
+
+			_, err = g.Driver.SendRaw(edit.MarshalMethod())
+			if err != nil {
+				return fmt.Errorf("driver error: %w", err)
+			}
+
+			g.lastEdit = target
+			return nil
+		}
+
+		reply, err := g.Driver.SendRaw(fmt.Sprintf(loadConfigurationURLStr, xmlEscape(action), xmlEscape(url)))
+		if err != nil {
+			return fmt.Errorf("driver error: %w", err)
+		}
+
+		// Never commit a partially loaded configuration
+		err = checkLoadResults(reply.Data)
+		if err != nil {
+			return err
+		}
+
+		g.lastEdit = target
+
+		if commit {
+			_, err = g.commit()
+			if err != nil {
+				return fmt.Errorf("driver error: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,85 @@
+package junos_helpers
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigURL(t *testing.T) {
+	const base = "urn:ietf:params:netconf:base:1.0"
+
+	tt := []struct {
+		name         string
+		capabilities []string
+		url          string
+		commit       bool
+		expected     []string // Prefixes of the RPCs sent
+		unsupported  bool
+	}{
+		{
+			name:         "load",
+			capabilities: []string{base, capabilityCandidate, capabilityURL + "?scheme=http,ftp,file"},
+			url:          "http://192.0.2.10/r1.conf",
+			expected:     []string{`<load-configuration action="override" url="http://192.0.2.10/r1.conf"/>`},
+		},
+		{
+			name:         "commit",
+			capabilities: []string{base, capabilityCandidate, capabilityURL},
+			url:          "tftp://192.0.2.10/r1.conf",
+			commit:       true,
+			expected:     []string{`<load-configuration action="override" url="tftp://192.0.2.10/r1.conf"/>`, "<commit"},
+		},
+		{
+			name:         "running",
+			capabilities: []string{base, capabilityWritableRunning, capabilityURL},
+			url:          "http://192.0.2.10/r1.xml",
+			commit:       true,
+			expected:     []string{"<edit-config><target><running/></target><url>http://192.0.2.10/r1.xml</url></edit-config>"},
+		},
+		{
+			name:         "noCapability",
+			capabilities: []string{base, capabilityCandidate},
+			url:          "http://192.0.2.10/r1.conf",
+			unsupported:  true,
+		},
+		{
+			name:         "unsupportedScheme",
+			capabilities: []string{base, capabilityCandidate, capabilityURL + "?scheme=http,ftp"},
+			url:          "tftp://192.0.2.10/r1.conf",
+			unsupported:  true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g, fd := newTestClient(loadSuccessReply, commitSuccessReply)
+			fd.Capabilities = tc.capabilities
+
+			err := g.LoadConfigURL(tc.url, LoadOverride, tc.commit)
+
+			if tc.unsupported {
+				if !errors.Is(err, ErrCapabilityNotSupported) {
+					t.Errorf("got error %v, expected %v", err, ErrCapabilityNotSupported)
+				}
+				if len(fd.Sent) != 0 {
+					t.Errorf("got RPCs %q, expected none to be sent", fd.Sent)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(fd.Sent) != len(tc.expected) {
+				t.Fatalf("got RPCs %q, expected %d", fd.Sent, len(tc.expected))
+			}
+			for i, expected := range tc.expected {
+				if !strings.HasPrefix(strings.TrimSpace(fd.Sent[i]), expected) {
+					t.Errorf("got RPC %q, expected %q", fd.Sent[i], expected)
+				}
+			}
+		})
+	}
+}
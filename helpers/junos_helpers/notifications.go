@@ -0,0 +1,177 @@
+package junos_helpers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	helpers "github.com/davedotdev/go-netconf/helpers"
+	rpc "github.com/davedotdev/go-netconf/rpc"
+)
+
+// DefaultNotificationStream is the RFC 5277 stream every device offers
+const DefaultNotificationStream = "NETCONF"
+
+// notifier is implemented by drivers able to subscribe to event notifications (RFC 5277)
+type notifier interface {
+	Subscribe(stream string, startTime time.Time) error
+	ReceiveNotification() (*rpc.Notification, error)
+}
+
+// NotificationStreamOptions controls how a NotificationStream subscribes and reconnects
+type NotificationStreamOptions struct {
+	Stream        string        // Defaults to DefaultNotificationStream
+	Replay        bool          // Resubscribe from the last event time seen, so no events are lost across a reconnect
+	RetryInterval time.Duration // Wait between reconnect attempts, defaults to 5 seconds
+
+	// StartTime replays the events logged since then in the first subscription, the zero time subscribes
+	// to new events only. Without Replay later subscriptions are live.
+	StartTime time.Time
+}
+
+// NotificationStream delivers notifications from a subscription that survives the session dropping,
+// for example across a device reboot. Both channels are closed once the context is cancelled.
+type NotificationStream struct {
+	Notifications <-chan *rpc.Notification
+	Reconnects    <-chan error // Receives the error behind each reconnect, dropped if not read
+
+	g             *GoNCClient
+	n             notifier
+	opts          NotificationStreamOptions
+	notifications chan *rpc.Notification
+	reconnects    chan error
+}
+
+// SubscribeNotifications starts a NotificationStream that runs until ctx is cancelled.
+// A subscribed session can not carry other RPCs, so the client is held for the lifetime of the stream
+// and other operations block until it ends. Use a separate client for configuration. The stream dials
+// sessions of its own, so a client holding a session opened by Dial is refused.
+func (g *GoNCClient) SubscribeNotifications(ctx context.Context, opts NotificationStreamOptions) (*NotificationStream, error) {
+	g.Lock.Lock()
+
+	if g.Driver == nil {
+		g.Lock.Unlock()
+		return nil, helpers.ErrSessionClosed
+	}
+
+	if g.sessionOpen {
+		g.Lock.Unlock()
+		return nil, fmt.Errorf("client holds a session opened by Dial, subscribe with a client that has not been dialed")
+	}
+
+	n, ok := g.Driver.(notifier)
+	if !ok {
+		g.Lock.Unlock()
+		return nil, fmt.Errorf("driver %T does not support notifications", g.Driver)
+	}
+
+	if opts.Stream == "" {
+		opts.Stream = DefaultNotificationStream
+	}
+
+	if opts.RetryInterval == 0 {
+		opts.RetryInterval = 5 * time.Second
+	}
+
+	s := &NotificationStream{
+		g:             g,
+		n:             n,
+		opts:          opts,
+		notifications: make(chan *rpc.Notification),
+		reconnects:    make(chan error, 1),
+	}
+	s.Notifications = s.notifications
+	s.Reconnects = s.reconnects
+
+	go s.run(ctx)
+
+	return s, nil
+}
+
+// run keeps a subscription alive until ctx is cancelled
+func (s *NotificationStream) run(ctx context.Context) {
+	defer s.g.Lock.Unlock()
+	defer close(s.reconnects)
+	defer close(s.notifications)
+
+	startTime := s.opts.StartTime
+
+	for {
+		lastEvent, err := s.subscribe(ctx, startTime)
+		if ctx.Err() != nil {
+			return
+		}
+
+		select {
+		case s.reconnects <- err:
+		default:
+		}
+
+		// The replay includes events at startTime, so start just after the last one already delivered
+		switch {
+		case s.opts.Replay && !lastEvent.IsZero():
+			startTime = lastEvent.Add(time.Nanosecond)
+		case !s.opts.Replay:
+			startTime = time.Time{}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.opts.RetryInterval):
+		}
+	}
+}
+
+// subscribe dials, subscribes and forwards notifications until the session fails or ctx is cancelled.
+// It returns the time of the last event forwarded, or the zero time if there was none, and closes the
+// session exactly once.
+func (s *NotificationStream) subscribe(ctx context.Context, startTime time.Time) (time.Time, error) {
+	var lastEvent time.Time
+
+	err := s.g.Driver.DialContext(ctx)
+	if err != nil {
+		return lastEvent, err
+	}
+
+	var closeOnce sync.Once
+	closeDriver := func() {
+		closeOnce.Do(func() { s.g.Driver.Close() })
+	}
+	defer closeDriver()
+
+	// Closing the driver is the only way to unblock a pending ReceiveNotification
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			closeDriver()
+		case <-done:
+		}
+	}()
+
+	err = s.n.Subscribe(s.opts.Stream, startTime)
+	if err != nil {
+		return lastEvent, err
+	}
+
+	for {
+		notification, err := s.n.ReceiveNotification()
+		if err != nil {
+			return lastEvent, err
+		}
+
+		eventTime, err := time.Parse(time.RFC3339, notification.EventTime)
+		if err == nil {
+			lastEvent = eventTime
+		}
+
+		select {
+		case s.notifications <- notification:
+		case <-ctx.Done():
+			return lastEvent, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package junos_helpers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	testdriver "github.com/davedotdev/go-netconf/helpers/internal/testdriver"
+	rpc "github.com/davedotdev/go-netconf/rpc"
+)
+
+// fakeNotifier replays a list of event times per session. Every session but the last ends with EOF,
+// the last blocks until the driver is closed.
+type fakeNotifier struct {
+	*testdriver.Driver
+
+	mu         sync.Mutex
+	sessions   [][]string
+	current    []string
+	closed     chan struct{}
+	subscribes []time.Time
+}
+
+func (f *fakeNotifier) DialContext(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.Dials++
+	f.current, f.sessions = f.sessions[0], f.sessions[1:]
+	f.closed = make(chan struct{})
+
+	return nil
+}
+
+func (f *fakeNotifier) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.Closes++
+	select {
+	case <-f.closed:
+	default:
+		close(f.closed)
+	}
+
+	return nil
+}
+
+func (f *fakeNotifier) Subscribe(stream string, startTime time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.subscribes = append(f.subscribes, startTime)
+	return nil
+}
+
+func (f *fakeNotifier) ReceiveNotification() (*rpc.Notification, error) {
+	f.mu.Lock()
+	if len(f.current) == 0 {
+		last := len(f.sessions) == 0
+		closed := f.closed
+		f.mu.Unlock()
+
+		if last {
+			<-closed
+		}
+		return nil, io.EOF
+	}
+
+	eventTime := f.current[0]
+	f.current = f.current[1:]
+	f.mu.Unlock()
+
+	return rpc.NewNotification([]byte(fmt.Sprintf(
+		`<notification xmlns="urn:ietf:params:xml:ns:netconf:notification:1.0"><eventTime>%s</eventTime><event/></notification>`,
+		eventTime)))
+}
+
+func TestNotificationStreamReconnects(t *testing.T) {
+	fn := &fakeNotifier{
+		Driver: testdriver.New(),
+		sessions: [][]string{
+			{"2020-06-01T10:00:00Z", "2020-06-01T10:00:05Z"},
+			{"2020-06-01T10:01:00Z"},
+		},
+	}
+	g := &GoNCClient{Driver: fn}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := g.SubscribeNotifications(ctx, NotificationStreamOptions{Replay: true, RetryInterval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var events []string
+	for len(events) < 3 {
+		select {
+		case n := <-stream.Notifications:
+			events = append(events, n.EventTime)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for notifications, got %v", events)
+		}
+	}
+
+	select {
+	case err := <-stream.Reconnects:
+		if err != io.EOF {
+			t.Errorf("got reconnect error %v, expected %v", err, io.EOF)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the reconnect signal")
+	}
+
+	cancel()
+
+	// The stream closes its channels once it has shut down
+	for range stream.Notifications {
+	}
+
+	expected := []string{"2020-06-01T10:00:00Z", "2020-06-01T10:00:05Z", "2020-06-01T10:01:00Z"}
+	for i := range expected {
+		if events[i] != expected[i] {
+			t.Errorf("got events %v, expected %v", events, expected)
+			break
+		}
+	}
+
+	fn.mu.Lock()
+	defer fn.mu.Unlock()
+
+	if fn.Dials != 2 || len(fn.subscribes) != 2 {
+		t.Fatalf("got %d dials and %d subscribes, expected 2 of each", fn.Dials, len(fn.subscribes))
+	}
+
+	if !fn.subscribes[0].IsZero() {
+		t.Errorf("got initial start time %v, expected a live subscription", fn.subscribes[0])
+	}
+
+	// Strictly after the last event delivered, so it is not delivered twice
+	replayFrom := time.Date(2020, 6, 1, 10, 0, 5, 1, time.UTC)
+	if !fn.subscribes[1].Equal(replayFrom) {
+		t.Errorf("got replay start time %v, expected %v", fn.subscribes[1], replayFrom)
+	}
+
+	if fn.Closes != fn.Dials {
+		t.Errorf("got %d closes for %d dials, expected every session closed once", fn.Closes, fn.Dials)
+	}
+}
+
+func TestNotificationStreamStartTime(t *testing.T) {
+	fn := &fakeNotifier{
+		Driver:   testdriver.New(),
+		sessions: [][]string{{}, {"2020-06-01T10:01:00Z"}},
+	}
+	g := &GoNCClient{Driver: fn}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	startTime := time.Date(2020, 6, 1, 9, 0, 0, 0, time.UTC)
+
+	stream, err := g.SubscribeNotifications(ctx, NotificationStreamOptions{StartTime: startTime, RetryInterval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	select {
+	case <-stream.Notifications:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a notification")
+	}
+
+	cancel()
+
+	for range stream.Notifications {
+	}
+
+	fn.mu.Lock()
+	defer fn.mu.Unlock()
+
+	if len(fn.subscribes) != 2 {
+		t.Fatalf("got %d subscribes, expected 2", len(fn.subscribes))
+	}
+
+	if !fn.subscribes[0].Equal(startTime) {
+		t.Errorf("got initial start time %v, expected %v", fn.subscribes[0], startTime)
+	}
+
+	// Without Replay the reconnect subscribes to new events only
+	if !fn.subscribes[1].IsZero() {
+		t.Errorf("got start time %v after the reconnect, expected a live subscription", fn.subscribes[1])
+	}
+}
+
+func TestSubscribeNotificationsDialed(t *testing.T) {
+	fn := &fakeNotifier{Driver: testdriver.New()}
+	g := &GoNCClient{Driver: fn, sessionOpen: true}
+
+	_, err := g.SubscribeNotifications(context.Background(), NotificationStreamOptions{})
+	if err == nil {
+		t.Fatal("expected an error for a client holding a session opened by Dial")
+	}
+
+	if fn.Dials != 0 || fn.Closes != 0 {
+		t.Errorf("got %d dials and %d closes, expected the held session untouched", fn.Dials, fn.Closes)
+	}
+
+	// The client was released
+	g.Lock.Lock()
+	g.Lock.Unlock()
+}
+
+func TestSubscribeNotificationsUnsupported(t *testing.T) {
+	g, _ := newTestClient()
+
+	_, err := g.SubscribeNotifications(context.Background(), NotificationStreamOptions{})
+	if err == nil {
+		t.Fatal("expected an error for a driver without notification support")
+	}
+}
@@ -0,0 +1,271 @@
+package junos_helpers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	xmlnode "github.com/davedotdev/go-netconf/helpers/xmlnode"
+	rpc "github.com/davedotdev/go-netconf/rpc"
+)
+
+const opScriptStr = `<op-script>
+  <script>%s</script>
+  <op-script-input>
+%s  </op-script-input>
+</op-script>
+`
+
+const opScriptArgStr = `    <argument><name>%s</name><value>%s</value></argument>
+`
+
+const getInterfaceInformationStr = `<get-interface-information>
+%s</get-interface-information>
+`
+
+// Interface holds the status and traffic counters of a physical interface.
+// Counters the device does not report (terse replies carry none, only extensive replies carry bytes and errors) are zero.
+type Interface struct {
+	Name          string
+	Description   string
+	AdminStatus   string
+	OperStatus    string
+	InputPackets  uint64
+	OutputPackets uint64
+	InputBytes    uint64
+	OutputBytes   uint64
+	InputErrors   uint64
+	OutputErrors  uint64
+}
+
+// physicalInterface mirrors a physical-interface element of a get-interface-information reply
+type physicalInterface struct {
+	Name          string `xml:"name"`
+	Description   string `xml:"description"`
+	AdminStatus   string `xml:"admin-status"`
+	OperStatus    string `xml:"oper-status"`
+	InputPackets  string `xml:"traffic-statistics>input-packets"`
+	OutputPackets string `xml:"traffic-statistics>output-packets"`
+	InputBytes    string `xml:"traffic-statistics>input-bytes"`
+	OutputBytes   string `xml:"traffic-statistics>output-bytes"`
+	InputErrors   string `xml:"input-error-list>input-errors"`
+	OutputErrors  string `xml:"output-error-list>output-errors"`
+}
+
+// buildInterfaceInformation renders the get-interface-information RPC
+func buildInterfaceInformation(name string, extensive bool) string {
+	var args strings.Builder
+	if name != "" {
+		args.WriteString(fmt.Sprintf("  <interface-name>%s</interface-name>\n", xmlEscape(name)))
+	}
+	if extensive {
+		args.WriteString("  <extensive/>\n")
+	}
+
+	return fmt.Sprintf(getInterfaceInformationStr, args.String())
+}
+
+// parseCounter converts a counter that Junos pads with newlines, treating a missing counter as zero
+func parseCounter(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+// parseInterfaceInformation extracts the physical interfaces from the data of a get-interface-information reply
+func parseInterfaceInformation(data string) ([]Interface, error) {
+	wrapper := struct {
+		Interfaces []physicalInterface `xml:"interface-information>physical-interface"`
+	}{}
+
+	err := xml.Unmarshal([]byte("<rpc-reply>"+data+"</rpc-reply>"), &wrapper)
+	if err != nil {
+		return nil, err
+	}
+
+	interfaces := make([]Interface, 0, len(wrapper.Interfaces))
+	for _, p := range wrapper.Interfaces {
+		i := Interface{
+			Name:        strings.TrimSpace(p.Name),
+			Description: strings.TrimSpace(p.Description),
+			AdminStatus: strings.TrimSpace(p.AdminStatus),
+			OperStatus:  strings.TrimSpace(p.OperStatus),
+		}
+
+		counters := []struct {
+			value string
+			dst   *uint64
+		}{
+			{p.InputPackets, &i.InputPackets},
+			{p.OutputPackets, &i.OutputPackets},
+			{p.InputBytes, &i.InputBytes},
+			{p.OutputBytes, &i.OutputBytes},
+			{p.InputErrors, &i.InputErrors},
+			{p.OutputErrors, &i.OutputErrors},
+		}
+		for _, c := range counters {
+			*c.dst, err = parseCounter(c.value)
+			if err != nil {
+				return nil, fmt.Errorf("interface %s: %w", i.Name, err)
+			}
+		}
+
+		interfaces = append(interfaces, i)
+	}
+
+	return interfaces, nil
+}
+
+// GetInterfaceInformation returns the status and counters of the named interface, or of every interface when name is empty.
+// extensive asks the device for the byte and error counters as well.
+func (g *GoNCClient) GetInterfaceInformation(name string, extensive bool) ([]Interface, error) {
+	reply, err := g.sendRaw(buildInterfaceInformation(name, extensive))
+	if err != nil {
+		return nil, err
+	}
+
+	return parseInterfaceInformation(reply.Data)
+}
+
+// buildOpScript renders the op-script RPC, escaping every value and ordering the arguments by name
+func buildOpScript(name string, args map[string]string) string {
+	keys := make([]string, 0, len(args))
+	for k := range args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var argStr strings.Builder
+	for _, k := range keys {
+		argStr.WriteString(fmt.Sprintf(opScriptArgStr, xmlEscape(k), xmlEscape(args[k])))
+	}
+
+	return fmt.Sprintf(opScriptStr, xmlEscape(name), argStr.String())
+}
+
+// RunOpScript invokes the named op script with the key/value arguments and returns its output
+func (g *GoNCClient) RunOpScript(name string, args map[string]string) (string, error) {
+	reply, err := g.sendRaw(buildOpScript(name, args))
+	if err != nil {
+		return "", err
+	}
+
+	return reply.Data, nil
+}
+
+// Get reads configuration and operational state with the standard NETCONF <get> operation and returns the data.
+// filter selects what is returned: empty for everything, a subtree filter when it starts with "<",
+// otherwise an XPath expression.
+func (g *GoNCClient) Get(filter string) (string, error) {
+	reply, err := g.sendRaw(rpc.MethodGet(filter).MarshalMethod())
+	if err != nil {
+		return "", err
+	}
+
+	return reply.Data, nil
+}
+
+// SendRPCTree sends any RPC and returns the reply parsed into a generic tree rooted at the rpc-reply
+// element, for exploratory tooling that would rather walk the reply than declare structs for it
+func (g *GoNCClient) SendRPCTree(rpcString string) (*xmlnode.Node, error) {
+	reply, err := g.sendRaw(rpcString)
+	if err != nil {
+		return nil, err
+	}
+
+	return xmlnode.Parse("<rpc-reply>" + reply.Data + "</rpc-reply>")
+}
+
+// StreamRPC sends any RPC and calls fn with each element named entry in the reply, e.g. rt for the routes
+// of get-route-information, as the reply is read from the device, building the tree of one entry at a time
+// so the whole reply is never held in memory. Drivers unable to stream, such as test fakes, buffer the reply.
+// The session is held while fn runs, so fn must not use the client. An error returned by fn stops the
+// callbacks and is returned, as are the rpc-errors in the reply.
+func (g *GoNCClient) StreamRPC(rpcString string, entry string, fn func(*xmlnode.Node) error) error {
+	return g.withSession(func() error {
+		r, err := g.sendStream(rpcString)
+		if err != nil {
+			return err
+		}
+
+		return streamEntries(r, entry, fn)
+	})
+}
+
+// ReadPaged reads a large table a page of pageSize entries at a time, for RPCs taking the range to return.
+// rpcFormat is the RPC with two %d verbs, given the index of the first entry of the page, counting from 0,
+// and pageSize. fn is called with the elements named entry in each page, until a page comes back short.
+// Reading stops as well once a page holds more than pageSize entries or repeats the previous page, as an
+// RPC ignoring the range returns the whole table every time, which is then passed to fn only once.
+// Every page is read on one session, which is held while fn runs, so fn must not use the client.
+// An error returned by fn stops the read and is returned.
+func (g *GoNCClient) ReadPaged(rpcFormat string, entry string, pageSize int, fn func(page []*xmlnode.Node) error) error {
+	if pageSize <= 0 {
+		return fmt.Errorf("page size %d out of range, expected at least 1", pageSize)
+	}
+
+	return g.withSession(func() error {
+		var previous string
+
+		for start := 0; ; start += pageSize {
+			r, err := g.sendStream(fmt.Sprintf(rpcFormat, start, pageSize))
+			if err != nil {
+				return err
+			}
+
+			var page []*xmlnode.Node
+			err = streamEntries(r, entry, func(n *xmlnode.Node) error {
+				page = append(page, n)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			key := pageKey(page)
+			if start > 0 && key == previous {
+				return nil
+			}
+			previous = key
+
+			if len(page) > 0 {
+				err = fn(page)
+				if err != nil {
+					return err
+				}
+			}
+
+			if len(page) != pageSize {
+				return nil
+			}
+		}
+	})
+}
+
+// pageKey renders the entries of a page to a string identifying their content, to detect a repeated page
+func pageKey(page []*xmlnode.Node) string {
+	var b strings.Builder
+
+	var write func(n *xmlnode.Node)
+	write = func(n *xmlnode.Node) {
+		b.WriteString("<" + n.Name.Space + " " + n.Name.Local)
+		for _, a := range n.Attrs {
+			b.WriteString(" " + a.Name.Local + "=" + strconv.Quote(a.Value))
+		}
+		b.WriteString(">" + strconv.Quote(n.Text))
+		for _, c := range n.Children {
+			write(c)
+		}
+		b.WriteString("</>")
+	}
+
+	for _, n := range page {
+		write(n)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,422 @@
+package junos_helpers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	testdriver "github.com/davedotdev/go-netconf/helpers/internal/testdriver"
+	xmlnode "github.com/davedotdev/go-netconf/helpers/xmlnode"
+	rpc "github.com/davedotdev/go-netconf/rpc"
+)
+
+func TestRunOpScript(t *testing.T) {
+	g, fd := newTestClient(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><output>done</output></rpc-reply>`)
+
+	output, err := g.RunOpScript("provision.slax", map[string]string{
+		"interface":   "ge-0/0/0",
+		"description": `core <uplink> & "backup"`,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if output != "<output>done</output>" {
+		t.Errorf("got output %q, expected <output>done</output>", output)
+	}
+
+	expected := `<op-script>
+  <script>provision.slax</script>
+  <op-script-input>
+    <argument><name>description</name><value>core &lt;uplink&gt; &amp; &#34;backup&#34;</value></argument>
+    <argument><name>interface</name><value>ge-0/0/0</value></argument>
+  </op-script-input>
+</op-script>
+`
+	if len(fd.Sent) != 1 || fd.Sent[0] != expected {
+		t.Errorf("got RPC %q, expected %q", fd.Sent, expected)
+	}
+}
+
+const extensiveInterfaceReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:junos="http://xml.juniper.net/junos/18.4R1/junos">
+<interface-information xmlns="http://xml.juniper.net/junos/18.4R1/junos-interface" junos:style="extensive">
+<physical-interface>
+<name>
+ge-0/0/0
+</name>
+<admin-status junos:format="Enabled">
+up
+</admin-status>
+<oper-status>
+up
+</oper-status>
+<description>
+core uplink
+</description>
+<traffic-statistics junos:style="verbose">
+<input-bytes>
+123456789
+</input-bytes>
+<output-bytes>
+987654321
+</output-bytes>
+<input-packets>
+1000
+</input-packets>
+<output-packets>
+2000
+</output-packets>
+</traffic-statistics>
+<input-error-list>
+<input-errors>
+3
+</input-errors>
+</input-error-list>
+<output-error-list>
+<output-errors>
+4
+</output-errors>
+</output-error-list>
+<logical-interface>
+<name>
+ge-0/0/0.0
+</name>
+</logical-interface>
+</physical-interface>
+</interface-information>
+</rpc-reply>`
+
+const terseInterfaceReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:junos="http://xml.juniper.net/junos/18.4R1/junos">
+<interface-information xmlns="http://xml.juniper.net/junos/18.4R1/junos-interface" junos:style="normal">
+<physical-interface>
+<name>
+ge-0/0/0
+</name>
+<admin-status>
+up
+</admin-status>
+<oper-status>
+up
+</oper-status>
+<traffic-statistics junos:style="brief">
+<input-packets>
+1000
+</input-packets>
+<output-packets>
+2000
+</output-packets>
+</traffic-statistics>
+</physical-interface>
+<physical-interface>
+<name>
+ge-0/0/1
+</name>
+<admin-status>
+down
+</admin-status>
+<oper-status>
+down
+</oper-status>
+</physical-interface>
+</interface-information>
+</rpc-reply>`
+
+func TestGetInterfaceInformationSingle(t *testing.T) {
+	g, fd := newTestClient(extensiveInterfaceReply)
+
+	interfaces, err := g.GetInterfaceInformation("ge-0/0/0", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedRPC := `<get-interface-information>
+  <interface-name>ge-0/0/0</interface-name>
+  <extensive/>
+</get-interface-information>
+`
+	if len(fd.Sent) != 1 || fd.Sent[0] != expectedRPC {
+		t.Errorf("got RPC %q, expected %q", fd.Sent, expectedRPC)
+	}
+
+	expected := Interface{
+		Name:          "ge-0/0/0",
+		Description:   "core uplink",
+		AdminStatus:   "up",
+		OperStatus:    "up",
+		InputPackets:  1000,
+		OutputPackets: 2000,
+		InputBytes:    123456789,
+		OutputBytes:   987654321,
+		InputErrors:   3,
+		OutputErrors:  4,
+	}
+	if len(interfaces) != 1 || interfaces[0] != expected {
+		t.Errorf("got %+v, expected %+v", interfaces, expected)
+	}
+}
+
+func TestGetInterfaceInformationAll(t *testing.T) {
+	g, fd := newTestClient(terseInterfaceReply)
+
+	interfaces, err := g.GetInterfaceInformation("", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedRPC := "<get-interface-information>\n</get-interface-information>\n"
+	if len(fd.Sent) != 1 || fd.Sent[0] != expectedRPC {
+		t.Errorf("got RPC %q, expected %q", fd.Sent, expectedRPC)
+	}
+
+	expected := []Interface{
+		{Name: "ge-0/0/0", AdminStatus: "up", OperStatus: "up", InputPackets: 1000, OutputPackets: 2000},
+		{Name: "ge-0/0/1", AdminStatus: "down", OperStatus: "down"},
+	}
+	if len(interfaces) != len(expected) {
+		t.Fatalf("got %d interfaces, expected %d", len(interfaces), len(expected))
+	}
+	for i := range expected {
+		if interfaces[i] != expected[i] {
+			t.Errorf("got %+v, expected %+v", interfaces[i], expected[i])
+		}
+	}
+}
+
+const getStateReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><data><interfaces-state><interface><name>ge-0/0/0</name><oper-status>up</oper-status></interface></interfaces-state></data></rpc-reply>`
+
+func TestGetFiltered(t *testing.T) {
+	g, fd := newTestClient(getStateReply)
+
+	data, err := g.Get("<interfaces-state/>")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedRPC := `<get><filter type="subtree"><interfaces-state/></filter></get>`
+	if len(fd.Sent) != 1 || fd.Sent[0] != expectedRPC {
+		t.Errorf("got RPC %q, expected %q", fd.Sent, expectedRPC)
+	}
+
+	expected := "<data><interfaces-state><interface><name>ge-0/0/0</name><oper-status>up</oper-status></interface></interfaces-state></data>"
+	if data != expected {
+		t.Errorf("got data %q, expected %q", data, expected)
+	}
+
+	if fd.Dials != 1 || fd.Closes != 1 {
+		t.Errorf("got %d dials and %d closes, expected 1 of each", fd.Dials, fd.Closes)
+	}
+}
+
+func TestGetUnfiltered(t *testing.T) {
+	g, fd := newTestClient(getStateReply)
+
+	_, err := g.Get("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(fd.Sent) != 1 || fd.Sent[0] != "<get/>" {
+		t.Errorf("got RPC %q, expected <get/>", fd.Sent)
+	}
+}
+
+const softwareInformationReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:junos="http://xml.juniper.net/junos/18.4R1/junos">
+<software-information>
+<host-name>r1</host-name>
+<product-model>mx960</product-model>
+<junos-version>18.4R1.8</junos-version>
+</software-information>
+</rpc-reply>`
+
+func TestSendRPCTree(t *testing.T) {
+	g, fd := newTestClient(softwareInformationReply)
+
+	root, err := g.SendRPCTree("<get-software-information/>")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(fd.Sent) != 1 || fd.Sent[0] != "<get-software-information/>" {
+		t.Errorf("got RPCs %q, expected the RPC unchanged", fd.Sent)
+	}
+
+	version := root.Find("software-information/junos-version")
+	if version == nil || version.Value() != "18.4R1.8" {
+		t.Errorf("got %+v, expected the junos-version element", version)
+	}
+}
+
+const routeTableReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<route-information>
+<route-table>
+<table-name>inet.0</table-name>
+<rt><rt-destination>0.0.0.0/0</rt-destination></rt>
+<rt><rt-destination>10.0.0.0/8</rt-destination></rt>
+<rt><rt-destination>192.0.2.0/24</rt-destination></rt>
+</route-table>
+</route-information>
+</rpc-reply>`
+
+func TestStreamRPC(t *testing.T) {
+	g, _ := newTestClient(routeTableReply)
+
+	var destinations []string
+	err := g.StreamRPC("<get-route-information/>", "rt", func(n *xmlnode.Node) error {
+		destinations = append(destinations, n.Find("rt-destination").Value())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"0.0.0.0/0", "10.0.0.0/8", "192.0.2.0/24"}
+	if strings.Join(destinations, ",") != strings.Join(expected, ",") {
+		t.Errorf("got %q, expected one callback per route %q", destinations, expected)
+	}
+}
+
+func TestReadPaged(t *testing.T) {
+	page := func(names ...string) string {
+		var entries strings.Builder
+		for _, name := range names {
+			entries.WriteString("<entry><name>" + name + "</name></entry>")
+		}
+		return `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><table>` + entries.String() + `</table></rpc-reply>`
+	}
+
+	g, fd := newTestClient(page("a", "b"), page("c", "d"), page("e"))
+
+	var pages []string
+	err := g.ReadPaged(`<get-table><start>%d</start><count>%d</count></get-table>`, "entry", 2, func(p []*xmlnode.Node) error {
+		var names []string
+		for _, n := range p {
+			names = append(names, n.Find("name").Value())
+		}
+		pages = append(pages, strings.Join(names, ","))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if strings.Join(pages, "|") != "a,b|c,d|e" {
+		t.Errorf("got pages %q, expected a,b|c,d|e", pages)
+	}
+
+	expected := []string{
+		"<get-table><start>0</start><count>2</count></get-table>",
+		"<get-table><start>2</start><count>2</count></get-table>",
+		"<get-table><start>4</start><count>2</count></get-table>",
+	}
+	if strings.Join(fd.Sent, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("got RPCs %q, expected %q", fd.Sent, expected)
+	}
+
+	if fd.Closes != 1 {
+		t.Errorf("got %d closes, expected every page read on one session", fd.Closes)
+	}
+}
+
+// tablePage renders a reply holding an entry for each name
+func tablePage(names ...string) string {
+	var entries strings.Builder
+	for _, name := range names {
+		entries.WriteString("<entry><name>" + name + "</name></entry>")
+	}
+	return `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><table>` + entries.String() + `</table></rpc-reply>`
+}
+
+func TestReadPagedRangeIgnored(t *testing.T) {
+	tt := []struct {
+		name    string
+		replies []string
+		rpcs    int
+		pages   []int
+	}{
+		{name: "more than a page", replies: []string{tablePage("a", "b", "c"), tablePage("a", "b", "c")}, rpcs: 1, pages: []int{3}},
+		{name: "repeated page", replies: []string{tablePage("a", "b"), tablePage("a", "b"), tablePage("a", "b")}, rpcs: 2, pages: []int{2}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g, fd := newTestClient(tc.replies...)
+
+			var pages []int
+			err := g.ReadPaged(`<get-table><start>%d</start><count>%d</count></get-table>`, "entry", 2, func(p []*xmlnode.Node) error {
+				pages = append(pages, len(p))
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(fd.Sent) != tc.rpcs {
+				t.Errorf("got RPCs %q, expected %d", fd.Sent, tc.rpcs)
+			}
+
+			if fmt.Sprint(pages) != fmt.Sprint(tc.pages) {
+				t.Errorf("got pages of %v entries, expected %v", pages, tc.pages)
+			}
+		})
+	}
+}
+
+// streamingDriver returns its replies from SendRawStream, as drivers reading from the transport do
+type streamingDriver struct {
+	*testdriver.Driver
+	streamed int
+}
+
+func (s *streamingDriver) SendRawStream(rawxml string) (io.Reader, error) {
+	s.streamed++
+
+	reply, err := s.Driver.SendRaw(rawxml)
+	if reply == nil {
+		return nil, err
+	}
+
+	// Read a byte at a time, and with the separator gone, as from the transport
+	return iotest.OneByteReader(strings.NewReader(reply.RawReply)), nil
+}
+
+func TestStreamRPCStreamed(t *testing.T) {
+	sd := &streamingDriver{Driver: testdriver.New(routeTableReply)}
+	g := &GoNCClient{Driver: sd}
+
+	var count int
+	err := g.StreamRPC("<get-route-information/>", "rt", func(n *xmlnode.Node) error {
+		count++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if count != 3 || sd.streamed != 1 {
+		t.Errorf("got %d routes from %d streamed replies, expected 3 from 1", count, sd.streamed)
+	}
+
+	stop := errors.New("stop")
+	sd.Replies = []string{routeTableReply}
+
+	err = g.StreamRPC("<get-route-information/>", "rt", func(n *xmlnode.Node) error { return stop })
+	if err != stop {
+		t.Errorf("got error %v, expected the callback's error", err)
+	}
+}
+
+func TestStreamRPCError(t *testing.T) {
+	sd := &streamingDriver{Driver: testdriver.New(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">` +
+		`<rpc-error><error-severity>error</error-severity><error-message>syntax error</error-message></rpc-error></rpc-reply>`)}
+	g := &GoNCClient{Driver: sd}
+
+	err := g.StreamRPC("<get-route-informaton/>", "rt", func(n *xmlnode.Node) error { return nil })
+
+	var rpcErr *rpc.RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Message != "syntax error" {
+		t.Errorf("got error %v, expected the rpc-error of the reply", err)
+	}
+}
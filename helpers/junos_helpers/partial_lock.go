@@ -0,0 +1,98 @@
+package junos_helpers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+
+	helpers "github.com/davedotdev/go-netconf/helpers"
+	rpc "github.com/davedotdev/go-netconf/rpc"
+)
+
+// capabilityPartialLock is advertised by devices supporting RFC 5717 partial locks
+const capabilityPartialLock = "urn:ietf:params:netconf:capability:partial-lock:1.0"
+
+// parsePartialLock extracts the lock-id from the data of a partial-lock reply
+func parsePartialLock(data string) (uint32, error) {
+	wrapper := struct {
+		LockID *string `xml:"lock-id"`
+	}{}
+
+	err := xml.Unmarshal([]byte("<rpc-reply>"+data+"</rpc-reply>"), &wrapper)
+	if err != nil {
+		return 0, err
+	}
+
+	if wrapper.LockID == nil {
+		return 0, fmt.Errorf("partial-lock reply carries no lock-id")
+	}
+
+	lockID, err := strconv.ParseUint(strings.TrimSpace(*wrapper.LockID), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid lock-id %q: %v", *wrapper.LockID, err)
+	}
+
+	return uint32(lockID), nil
+}
+
+// PartialLock locks the configuration nodes matched by the XPath selects, leaving the rest of the datastore
+// to other sessions. A partial lock only lives as long as its session, so the client must have been
+// opened with Dial. Devices that do not advertise :partial-lock return ErrCapabilityNotSupported.
+func (g *GoNCClient) PartialLock(selects []string) (uint32, error) {
+	g.Lock.Lock()
+	defer g.Lock.Unlock()
+
+	err := g.requirePartialLock()
+	if err != nil {
+		return 0, err
+	}
+
+	reply, err := g.Driver.SendRaw(rpc.MethodPartialLock(selects).MarshalMethod())
+	if err != nil {
+		return 0, lockError(err)
+	}
+
+	return parsePartialLock(reply.Data)
+}
+
+// PartialUnlock releases a lock taken by PartialLock
+func (g *GoNCClient) PartialUnlock(lockID uint32) error {
+	g.Lock.Lock()
+	defer g.Lock.Unlock()
+
+	err := g.requirePartialLock()
+	if err != nil {
+		return err
+	}
+
+	_, err = g.Driver.SendRaw(rpc.MethodPartialUnlock(lockID).MarshalMethod())
+	if err != nil {
+		return fmt.Errorf("driver error: %+v", err)
+	}
+
+	return nil
+}
+
+// requirePartialLock checks the client holds a session opened by Dial that supports partial locks
+func (g *GoNCClient) requirePartialLock() error {
+	err := g.requireDialedSession()
+	if err != nil {
+		return err
+	}
+
+	return g.requireCapability(capabilityPartialLock)
+}
+
+// requireDialedSession checks the client holds a session opened by Dial, which locks need to outlive an operation
+func (g *GoNCClient) requireDialedSession() error {
+	if g.Driver == nil {
+		return helpers.ErrSessionClosed
+	}
+
+	if !g.sessionOpen {
+		return fmt.Errorf("locks are released with their session, open one with Dial first")
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package junos_helpers
+
+import (
+	"errors"
+	"testing"
+
+	rpc "github.com/davedotdev/go-netconf/rpc"
+)
+
+const partialLockReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<lock-id>127</lock-id>
+<locked-node xmlns:nc="urn:ietf:params:xml:ns:netconf:base:1.0">/configuration/system</locked-node>
+</rpc-reply>`
+
+func TestPartialLock(t *testing.T) {
+	g, fd := newTestClient(partialLockReply)
+	fd.Capabilities = []string{capabilityPartialLock}
+
+	err := g.Dial()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	selects := []string{"/configuration/system"}
+
+	lockID, err := g.PartialLock(selects)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lockID != 127 {
+		t.Errorf("got lock-id %d, expected 127", lockID)
+	}
+
+	err = g.PartialUnlock(lockID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		rpc.MethodPartialLock(selects).MarshalMethod(),
+		rpc.MethodPartialUnlock(127).MarshalMethod(),
+	}
+	if len(fd.Sent) != len(expected) || fd.Sent[0] != expected[0] || fd.Sent[1] != expected[1] {
+		t.Errorf("got RPCs %q, expected %q", fd.Sent, expected)
+	}
+}
+
+func TestPartialLockUnsupported(t *testing.T) {
+	g, fd := newTestClient()
+
+	_, err := g.PartialLock([]string{"/configuration/system"})
+	if err == nil {
+		t.Errorf("expected an error without a session opened by Dial")
+	}
+
+	err = g.Dial()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = g.PartialLock([]string{"/configuration/system"})
+	if !errors.Is(err, ErrCapabilityNotSupported) {
+		t.Errorf("got error %v, expected %v", err, ErrCapabilityNotSupported)
+	}
+
+	if len(fd.Sent) != 0 {
+		t.Errorf("got RPCs %q, expected none", fd.Sent)
+	}
+}
@@ -0,0 +1,311 @@
+package junos_helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	xmlnode "github.com/davedotdev/go-netconf/helpers/xmlnode"
+)
+
+const getConfigurationStr = `<get-configuration format="%s"%s>
+  <configuration>
+  %s
+  </configuration>
+</get-configuration>
+`
+
+const getChangedConfigurationStr = `<get-configuration changed="changed" database="candidate" format="xml">
+  <configuration>
+  %s
+  </configuration>
+</get-configuration>
+`
+
+const getFullConfigStr = `<get-configuration database="committed" format="%s"/>
+`
+
+const getRollbackStr = `<get-configuration rollback="%d" format="%s"/>
+`
+
+// maxRollback is the oldest configuration generation Junos keeps, rollback 0 being the active configuration
+const maxRollback = 49
+
+// Formats accepted by get-configuration
+const (
+	FormatXML  = "xml"
+	FormatText = "text"
+	FormatSet  = "set"
+	FormatJSON = "json"
+)
+
+// Values of the get-configuration inherit attribute, reading the configuration with apply-groups expanded
+const (
+	InheritGroups    = "inherit"   // Statements inherited from groups are shown where they take effect
+	InheritInherited = "inherited" // As InheritGroups
+	InheritDefaults  = "defaults"  // As InheritGroups, also expanding the junos-defaults group
+)
+
+// validateInherit checks inherit is empty or a value of the get-configuration inherit attribute
+func validateInherit(inherit string) error {
+	switch inherit {
+	case "", InheritGroups, InheritInherited, InheritDefaults:
+		return nil
+	}
+
+	return fmt.Errorf("unsupported inherit %q, expected one of inherit, inherited or defaults", inherit)
+}
+
+// validateFormat checks the format is one Junos can render the configuration in
+func validateFormat(format string) error {
+	switch format {
+	case FormatXML, FormatText, FormatSet, FormatJSON:
+		return nil
+	}
+
+	return fmt.Errorf("unsupported configuration format %q, expected one of xml, text, set or json", format)
+}
+
+// ReadConfiguration reads the configuration below the subtree filter (e.g. <interfaces/>) in the given format
+func (g *GoNCClient) ReadConfiguration(subtree string, format string) (string, error) {
+	return g.ReadConfigurationInherit(subtree, format, "")
+}
+
+// ReadConfigurationInherit is ReadConfiguration returning the effective configuration, with the statements
+// of apply-groups expanded where they take effect, when inherit is set to InheritGroups, InheritInherited or
+// InheritDefaults. An empty inherit reads the configuration as written.
+func (g *GoNCClient) ReadConfigurationInherit(subtree string, format string, inherit string) (string, error) {
+	err := validateFormat(format)
+	if err != nil {
+		return "", err
+	}
+
+	err = validateInherit(inherit)
+	if err != nil {
+		return "", err
+	}
+
+	var inheritAttr string
+	if inherit != "" {
+		inheritAttr = fmt.Sprintf(` inherit="%s"`, inherit)
+	}
+
+	reply, err := g.sendRaw(fmt.Sprintf(getConfigurationStr, format, inheritAttr, escapePayload(subtree)))
+	if err != nil {
+		return "", err
+	}
+
+	return reply.Data, nil
+}
+
+// GetChangedConfig returns the statements of the candidate below the subtree filter that differ from the
+// committed configuration, a cheaper "is anything pending?" check than a full compare. The device marks
+// changed elements and their ancestors with junos:changed, every other element is pruned except the name
+// identifying a changed element's parent. It returns nil when nothing is pending.
+func (g *GoNCClient) GetChangedConfig(subtree string) (*xmlnode.Node, error) {
+	reply, err := g.sendRaw(fmt.Sprintf(getChangedConfigurationStr, escapePayload(subtree)))
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := xmlnode.Parse("<rpc-reply>" + reply.Data + "</rpc-reply>")
+	if err != nil {
+		return nil, err
+	}
+
+	config := root.Find("configuration")
+	if config == nil {
+		return nil, fmt.Errorf("no configuration in reply")
+	}
+
+	return pruneUnchanged(config), nil
+}
+
+// pruneUnchanged returns a copy of n keeping only the elements marked changed and the names identifying
+// them, or nil if n is not marked changed
+func pruneUnchanged(n *xmlnode.Node) *xmlnode.Node {
+	if n.Attr("changed") == "" {
+		return nil
+	}
+
+	pruned := &xmlnode.Node{Name: n.Name, Attrs: n.Attrs, Text: n.Text}
+	for _, child := range n.Children {
+		if c := pruneUnchanged(child); c != nil {
+			pruned.Children = append(pruned.Children, c)
+		} else if child.Name.Local == "name" {
+			pruned.Children = append(pruned.Children, child)
+		}
+	}
+
+	return pruned
+}
+
+// GetFullConfig returns the whole committed configuration in the given format, e.g. for backups. The reply
+// is buffered in full, WriteFullConfig streams a large configuration instead.
+func (g *GoNCClient) GetFullConfig(format string) (string, error) {
+	err := validateFormat(format)
+	if err != nil {
+		return "", err
+	}
+
+	reply, err := g.sendRaw(fmt.Sprintf(getFullConfigStr, format))
+	if err != nil {
+		return "", err
+	}
+
+	return reply.Data, nil
+}
+
+// WriteFullConfig writes the whole committed configuration in the given format to w as it is read from the
+// device, without buffering it, e.g. straight into a backup file. It writes what GetFullConfig returns, the
+// data of the reply. Should w fail, the rest of the reply is still read so the session can be reused.
+func (g *GoNCClient) WriteFullConfig(w io.Writer, format string) error {
+	err := validateFormat(format)
+	if err != nil {
+		return err
+	}
+
+	return g.withSession(func() error {
+		r, err := g.sendStream(fmt.Sprintf(getFullConfigStr, format))
+		if err != nil {
+			return err
+		}
+
+		return copyReplyData(w, r)
+	})
+}
+
+// GetRollbackConfig returns the configuration as it was at rollback generation n (0 being the active
+// configuration and 49 the oldest) in the given format, without rolling the device back
+func (g *GoNCClient) GetRollbackConfig(n int, format string) (string, error) {
+	if n < 0 || n > maxRollback {
+		return "", fmt.Errorf("rollback %d out of range, expected 0 to %d", n, maxRollback)
+	}
+
+	err := validateFormat(format)
+	if err != nil {
+		return "", err
+	}
+
+	reply, err := g.sendRaw(fmt.Sprintf(getRollbackStr, n, format))
+	if err != nil {
+		return "", err
+	}
+
+	return reply.Data, nil
+}
+
+// GetConfigJSON reads the configuration below the subtree filter in JSON and decodes it into a generic map
+func (g *GoNCClient) GetConfigJSON(subtree string) (map[string]interface{}, error) {
+	cfg := map[string]interface{}{}
+
+	err := g.GetConfigJSONInto(subtree, &cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// GetConfigJSONWithNS is GetConfigJSON with every key qualified with its namespace, as "{namespace}name",
+// for pipelines validating the configuration against schemas. Keys prefixed with a module as in RFC 7951,
+// e.g. "ietf-interfaces:interfaces", take the namespace JSONNamespaces maps the module to, other keys that
+// of their parent. The namespace mapped to "" applies to unprefixed keys at the top, as Junos leaves its
+// native configuration unprefixed. Keys of metadata starting with "@" are left as they are.
+func (g *GoNCClient) GetConfigJSONWithNS(subtree string) (map[string]interface{}, error) {
+	cfg, err := g.GetConfigJSON(subtree)
+	if err != nil {
+		return nil, err
+	}
+
+	qualified, err := qualifyJSON(cfg, g.JSONNamespaces[""], g.JSONNamespaces)
+	if err != nil {
+		return nil, err
+	}
+
+	return qualified.(map[string]interface{}), nil
+}
+
+// qualifyJSON returns a copy of v with the keys of every object qualified with their namespace, namespace
+// being that of the object holding v
+func qualifyJSON(v interface{}, namespace string, namespaces map[string]string) (interface{}, error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		qualified := make(map[string]interface{}, len(v))
+
+		for key, value := range v {
+			if strings.HasPrefix(key, "@") {
+				qualified[key] = value
+				continue
+			}
+
+			ns, name := namespace, key
+			if i := strings.Index(key, ":"); i != -1 {
+				var ok bool
+				ns, ok = namespaces[key[:i]]
+				if !ok {
+					return nil, fmt.Errorf("no namespace for module %s of key %s", key[:i], key)
+				}
+				name = key[i+1:]
+			}
+
+			child, err := qualifyJSON(value, ns, namespaces)
+			if err != nil {
+				return nil, err
+			}
+
+			if ns != "" {
+				name = "{" + ns + "}" + name
+			}
+			qualified[name] = child
+		}
+
+		return qualified, nil
+	case []interface{}:
+		qualified := make([]interface{}, len(v))
+
+		for i, value := range v {
+			child, err := qualifyJSON(value, namespace, namespaces)
+			if err != nil {
+				return nil, err
+			}
+			qualified[i] = child
+		}
+
+		return qualified, nil
+	}
+
+	return v, nil
+}
+
+// GetConfigJSONInto reads the configuration below the subtree filter in JSON and decodes it onto v
+func (g *GoNCClient) GetConfigJSONInto(subtree string, v interface{}) error {
+	reply, err := g.ReadConfiguration(subtree, FormatJSON)
+	if err != nil {
+		return err
+	}
+
+	jsonData, err := extractJSON(reply)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal([]byte(jsonData), v)
+}
+
+// extractJSON returns the JSON document carried as character data in the reply,
+// unescaping it and dropping any element the device wrapped it in
+func extractJSON(data string) (string, error) {
+	text, err := replyText(data)
+	if err != nil {
+		return "", err
+	}
+
+	jsonData := strings.TrimSpace(text)
+	if jsonData == "" {
+		return "", fmt.Errorf("no JSON found in reply")
+	}
+
+	return jsonData, nil
+}
@@ -0,0 +1,525 @@
+package junos_helpers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	testdriver "github.com/davedotdev/go-netconf/helpers/internal/testdriver"
+	rpc "github.com/davedotdev/go-netconf/rpc"
+)
+
+func TestReadConfiguration(t *testing.T) {
+	tt := []struct {
+		name     string
+		format   string
+		reply    string
+		expected string
+	}{
+		{
+			name:   "xml",
+			format: FormatXML,
+			reply: `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">` +
+				`<configuration><interfaces><interface><name>ge-0/0/0</name></interface></interfaces></configuration>` +
+				`</rpc-reply>`,
+			expected: `<configuration><interfaces><interface><name>ge-0/0/0</name></interface></interfaces></configuration>`,
+		},
+		{
+			name:   "set",
+			format: FormatSet,
+			reply: `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">` +
+				`<configuration-set>set interfaces ge-0/0/0 unit 0 family inet</configuration-set>` +
+				`</rpc-reply>`,
+			expected: `<configuration-set>set interfaces ge-0/0/0 unit 0 family inet</configuration-set>`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g, fd := newTestClient(tc.reply)
+
+			output, err := g.ReadConfiguration("<interfaces/>", tc.format)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if output != tc.expected {
+				t.Errorf("got %q, expected %q", output, tc.expected)
+			}
+
+			if len(fd.Sent) != 1 {
+				t.Fatalf("got %d RPCs sent, expected 1", len(fd.Sent))
+			}
+
+			if !strings.Contains(fd.Sent[0], `format="`+tc.format+`"`) {
+				t.Errorf("RPC %q does not request format %s", fd.Sent[0], tc.format)
+			}
+
+			if !strings.Contains(fd.Sent[0], "<configuration>\n  <interfaces/>\n  </configuration>") {
+				t.Errorf("RPC %q does not contain the subtree filter", fd.Sent[0])
+			}
+		})
+	}
+}
+
+func TestReadConfigurationInvalidFormat(t *testing.T) {
+	g, fd := newTestClient()
+
+	_, err := g.ReadConfiguration("<interfaces/>", "yaml")
+	if err == nil {
+		t.Fatal("expected an error for an unsupported format")
+	}
+
+	if fd.Dials != 0 {
+		t.Errorf("got %d dials, expected none for an invalid format", fd.Dials)
+	}
+}
+
+func TestReadConfigurationInherit(t *testing.T) {
+	tt := []struct {
+		inherit  string
+		expected string
+	}{
+		{inherit: "", expected: `<get-configuration format="xml">`},
+		{inherit: InheritGroups, expected: `<get-configuration format="xml" inherit="inherit">`},
+		{inherit: InheritDefaults, expected: `<get-configuration format="xml" inherit="defaults">`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.inherit, func(t *testing.T) {
+			g, fd := newTestClient()
+
+			_, err := g.ReadConfigurationInherit("<interfaces/>", FormatXML, tc.inherit)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(fd.Sent) != 1 || !strings.HasPrefix(fd.Sent[0], tc.expected) {
+				t.Errorf("got RPCs %q, expected %q", fd.Sent, tc.expected)
+			}
+		})
+	}
+
+	g, fd := newTestClient()
+
+	_, err := g.ReadConfigurationInherit("<interfaces/>", FormatXML, "expand")
+	if err == nil {
+		t.Fatal("expected an error for an unsupported inherit")
+	}
+
+	if fd.Dials != 0 {
+		t.Errorf("got %d dials, expected none for an invalid inherit", fd.Dials)
+	}
+}
+
+const changedConfigReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:junos="http://xml.juniper.net/junos/18.4R1/junos">
+<configuration junos:changed="changed">
+<system>
+<host-name>r1</host-name>
+</system>
+<interfaces junos:changed="changed">
+<interface junos:changed="changed">
+<name>ge-0/0/0</name>
+<description junos:changed="changed">uplink</description>
+<mtu>9192</mtu>
+</interface>
+<interface>
+<name>ge-0/0/1</name>
+</interface>
+</interfaces>
+</configuration>
+</rpc-reply>`
+
+const unchangedConfigReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<configuration>
+<system>
+<host-name>r1</host-name>
+</system>
+</configuration>
+</rpc-reply>`
+
+func TestGetChangedConfig(t *testing.T) {
+	g, fd := newTestClient(changedConfigReply, unchangedConfigReply)
+
+	changed, err := g.GetChangedConfig("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(fd.Sent) != 1 || !strings.HasPrefix(fd.Sent[0], `<get-configuration changed="changed" database="candidate" format="xml">`) {
+		t.Errorf("got RPCs %q, expected a get-configuration with the changed attribute", fd.Sent)
+	}
+
+	if changed == nil {
+		t.Fatal("expected the changed statements")
+	}
+
+	if changed.Find("system") != nil {
+		t.Error("expected the unchanged system statements to be pruned")
+	}
+
+	interfaces := changed.FindAll("interfaces/interface")
+	if len(interfaces) != 1 {
+		t.Fatalf("got %d interfaces, expected only the changed one", len(interfaces))
+	}
+	if name := interfaces[0].Find("name"); name == nil || name.Value() != "ge-0/0/0" {
+		t.Errorf("got name %+v, expected the changed interface to keep its name", name)
+	}
+	if description := interfaces[0].Find("description"); description == nil || description.Value() != "uplink" {
+		t.Errorf("got description %+v, expected the changed description", description)
+	}
+	if interfaces[0].Find("mtu") != nil {
+		t.Error("expected the unchanged mtu to be pruned")
+	}
+
+	changed, err = g.GetChangedConfig("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if changed != nil {
+		t.Errorf("got %+v, expected nil when nothing is pending", changed)
+	}
+}
+
+func TestGetFullConfig(t *testing.T) {
+	tt := []struct {
+		name   string
+		format string
+		reply  string
+		output string
+	}{
+		{
+			name:   "xml",
+			format: FormatXML,
+			reply:  `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><configuration><system><host-name>r1</host-name></system></configuration></rpc-reply>`,
+			output: "<configuration><system><host-name>r1</host-name></system></configuration>",
+		},
+		{
+			name:   "text",
+			format: FormatText,
+			reply:  `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><configuration-text>system { host-name r1; }</configuration-text></rpc-reply>`,
+			output: "<configuration-text>system { host-name r1; }</configuration-text>",
+		},
+		{
+			name:   "set",
+			format: FormatSet,
+			reply:  `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><configuration-set>set system host-name r1</configuration-set></rpc-reply>`,
+			output: "<configuration-set>set system host-name r1</configuration-set>",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g, fd := newTestClient(tc.reply)
+
+			output, err := g.GetFullConfig(tc.format)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if output != tc.output {
+				t.Errorf("got %q, expected %q", output, tc.output)
+			}
+
+			expected := `<get-configuration database="committed" format="` + tc.format + `"/>` + "\n"
+			if len(fd.Sent) != 1 || fd.Sent[0] != expected {
+				t.Errorf("got RPC %q, expected %q", fd.Sent, expected)
+			}
+		})
+	}
+}
+
+func TestWriteFullConfig(t *testing.T) {
+	// Larger than the tail held back while streaming
+	var config strings.Builder
+	config.WriteString("\n<configuration junos:commit-user=\"a>b\">")
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&config, "<interface><name>ge-0/0/%d</name></interface>", i)
+	}
+	config.WriteString("</configuration>\n")
+
+	reply := `<?xml version="1.0" encoding="UTF-8"?>
+<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:junos="http://xml.juniper.net/junos/18.4R1/junos" message-id="1">` +
+		config.String() + "</rpc-reply>\n"
+
+	tt := []struct {
+		name   string
+		driver func(replies ...string) *GoNCClient
+	}{
+		{name: "buffered", driver: func(replies ...string) *GoNCClient {
+			return &GoNCClient{Driver: testdriver.New(replies...)}
+		}},
+		{name: "streamed", driver: func(replies ...string) *GoNCClient {
+			return &GoNCClient{Driver: &streamingDriver{Driver: testdriver.New(replies...)}}
+		}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g := tc.driver(reply, reply)
+
+			var b bytes.Buffer
+			err := g.WriteFullConfig(&b, FormatXML)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// The same data GetFullConfig returns
+			expected, err := g.GetFullConfig(FormatXML)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if b.String() != expected || expected != config.String() {
+				t.Errorf("got %q, expected %q", b.String(), expected)
+			}
+
+			g = tc.driver(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<rpc-error><error-severity>error</error-severity><error-message>permission denied</error-message></rpc-error>
+</rpc-reply>`)
+
+			b.Reset()
+			err = g.WriteFullConfig(&b, FormatText)
+
+			var rpcErr *rpc.RPCError
+			if !errors.As(err, &rpcErr) || rpcErr.Message != "permission denied" {
+				t.Errorf("got error %v, expected the rpc-error of the reply", err)
+			}
+			if b.Len() != 0 {
+				t.Errorf("got %q written, expected nothing", b.String())
+			}
+		})
+	}
+}
+
+// failingWriter fails every write
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestWriteFullConfigWriterFails(t *testing.T) {
+	sd := &streamingDriver{Driver: testdriver.New(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><configuration-text>` +
+		strings.Repeat("system { host-name r1; }\n", 100) + `</configuration-text></rpc-reply>`)}
+	g := &GoNCClient{Driver: sd}
+
+	err := g.WriteFullConfig(failingWriter{}, FormatText)
+	if err == nil || err.Error() != "disk full" {
+		t.Errorf("got error %v, expected the writer's error", err)
+	}
+
+	if sd.Dials != 1 || sd.Closes != 1 {
+		t.Errorf("got %d dials and %d closes, expected 1 of each", sd.Dials, sd.Closes)
+	}
+}
+
+func TestGetFullConfigInvalidFormat(t *testing.T) {
+	g, fd := newTestClient()
+
+	_, err := g.GetFullConfig("yaml")
+	if err == nil {
+		t.Fatal("expected an error for an unsupported format")
+	}
+
+	if fd.Dials != 0 {
+		t.Errorf("got %d dials, expected none for an invalid format", fd.Dials)
+	}
+}
+
+func TestGetRollbackConfig(t *testing.T) {
+	tt := []struct {
+		name     string
+		n        int
+		format   string
+		expected string
+	}{
+		{name: "text", n: 3, format: FormatText, expected: "<get-configuration rollback=\"3\" format=\"text\"/>\n"},
+		{name: "set", n: 0, format: FormatSet, expected: "<get-configuration rollback=\"0\" format=\"set\"/>\n"},
+		{name: "oldest", n: 49, format: FormatXML, expected: "<get-configuration rollback=\"49\" format=\"xml\"/>\n"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g, fd := newTestClient(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><configuration-text>system { host-name r1; }</configuration-text></rpc-reply>`)
+
+			output, err := g.GetRollbackConfig(tc.n, tc.format)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if output != "<configuration-text>system { host-name r1; }</configuration-text>" {
+				t.Errorf("got %q, expected the configuration text", output)
+			}
+
+			if len(fd.Sent) != 1 || fd.Sent[0] != tc.expected {
+				t.Errorf("got RPC %q, expected %q", fd.Sent, tc.expected)
+			}
+		})
+	}
+}
+
+func TestGetRollbackConfigInvalid(t *testing.T) {
+	tt := []struct {
+		name   string
+		n      int
+		format string
+	}{
+		{name: "negative", n: -1, format: FormatText},
+		{name: "too old", n: 50, format: FormatText},
+		{name: "format", n: 1, format: "yaml"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g, fd := newTestClient()
+
+			_, err := g.GetRollbackConfig(tc.n, tc.format)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+
+			if fd.Dials != 0 {
+				t.Errorf("got %d dials, expected none", fd.Dials)
+			}
+		})
+	}
+}
+
+func TestGetConfigJSON(t *testing.T) {
+	tt := []struct {
+		name  string
+		reply string
+	}{
+		{
+			name: "bare",
+			reply: `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:junos="http://xml.juniper.net/junos/18.2R1/junos">
+{
+    "configuration" : {
+        "interfaces" : {
+            "interface" : [
+            {
+                "name" : "ge-0/0/0",
+                "description" : "uplink &amp; core",
+                "mtu" : 9192
+            }
+            ]
+        }
+    }
+}
+</rpc-reply>`,
+		},
+		{
+			name: "wrapped",
+			reply: `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<configuration-information><configuration-output>{"configuration" : {"interfaces" : {"interface" : [{"name" : "ge-0/0/0", "description" : "uplink &amp; core", "mtu" : 9192}]}}}</configuration-output></configuration-information>
+</rpc-reply>`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g, fd := newTestClient(tc.reply)
+
+			cfg, err := g.GetConfigJSON("<interfaces/>")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !strings.Contains(fd.Sent[0], `format="json"`) {
+				t.Errorf("RPC %q does not request json", fd.Sent[0])
+			}
+
+			configuration, ok := cfg["configuration"].(map[string]interface{})
+			if !ok {
+				t.Fatalf("configuration missing from %v", cfg)
+			}
+
+			ifaces := configuration["interfaces"].(map[string]interface{})["interface"].([]interface{})
+			iface := ifaces[0].(map[string]interface{})
+
+			if iface["name"] != "ge-0/0/0" {
+				t.Errorf("got name %v, expected ge-0/0/0", iface["name"])
+			}
+			if iface["description"] != "uplink & core" {
+				t.Errorf("got description %v, expected the unescaped text", iface["description"])
+			}
+			if iface["mtu"] != float64(9192) {
+				t.Errorf("got mtu %v, expected 9192", iface["mtu"])
+			}
+		})
+	}
+}
+
+func TestGetConfigJSONWithNS(t *testing.T) {
+	const reply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+{
+    "configuration" : {
+        "@" : {"junos:changed-seconds" : "1600000000"},
+        "system" : {"host-name" : "r1"},
+        "ietf-interfaces:interfaces" : {
+            "interface" : [{"name" : "ge-0/0/0", "ietf-ip:ipv4" : {"mtu" : 1500}}]
+        }
+    }
+}
+</rpc-reply>`
+
+	const (
+		junosNS = "http://yang.juniper.net/junos/conf/root"
+		ifNS    = "urn:ietf:params:xml:ns:yang:ietf-interfaces"
+		ipNS    = "urn:ietf:params:xml:ns:yang:ietf-ip"
+	)
+
+	g, _ := newTestClient(reply)
+	g.JSONNamespaces = map[string]string{"": junosNS, "ietf-interfaces": ifNS, "ietf-ip": ipNS}
+
+	cfg, err := g.GetConfigJSONWithNS("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	root, ok := cfg["{"+junosNS+"}configuration"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("got %v, expected a qualified configuration key", cfg)
+	}
+
+	if _, ok := root["@"]; !ok {
+		t.Errorf("got %v, expected the metadata key to be kept", root)
+	}
+
+	system := root["{"+junosNS+"}system"].(map[string]interface{})
+	if system["{"+junosNS+"}host-name"] != "r1" {
+		t.Errorf("got %v, expected host-name to inherit the Junos namespace", system)
+	}
+
+	interfaces := root["{"+ifNS+"}interfaces"].(map[string]interface{})
+	entry := interfaces["{"+ifNS+"}interface"].([]interface{})[0].(map[string]interface{})
+	if entry["{"+ifNS+"}name"] != "ge-0/0/0" {
+		t.Errorf("got %v, expected name to inherit the ietf-interfaces namespace", entry)
+	}
+
+	ipv4 := entry["{"+ipNS+"}ipv4"].(map[string]interface{})
+	if ipv4["{"+ipNS+"}mtu"] != float64(1500) {
+		t.Errorf("got %v, expected mtu in the ietf-ip namespace", ipv4)
+	}
+
+	// A module missing from the map is an error
+	g, _ = newTestClient(reply)
+	g.JSONNamespaces = map[string]string{"ietf-interfaces": ifNS}
+
+	_, err = g.GetConfigJSONWithNS("")
+	if err == nil || !strings.Contains(err.Error(), "ietf-ip") {
+		t.Errorf("got error %v, expected the unmapped module to be reported", err)
+	}
+}
+
+func TestGetConfigJSONNoData(t *testing.T) {
+	g, _ := newTestClient(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"></rpc-reply>`)
+
+	_, err := g.GetConfigJSON("<interfaces/>")
+	if err == nil {
+		t.Fatal("expected an error for a reply without JSON")
+	}
+}
@@ -0,0 +1,52 @@
+package junos_helpers
+
+import (
+	"fmt"
+)
+
+const saveRescueStr = `<request-save-rescue-configuration/>
+`
+
+const deleteRescueStr = `<request-delete-rescue-configuration/>
+`
+
+const loadRescueStr = `<load-configuration rescue="rescue"/>
+`
+
+// SaveRescue saves the active configuration as the rescue configuration, a known good configuration
+// to fall back to around risky changes (request system configuration rescue save)
+func (g *GoNCClient) SaveRescue() error {
+	_, err := g.sendRaw(saveRescueStr)
+	return err
+}
+
+// LoadRescue replaces the candidate with the rescue configuration and optionally commits it
+func (g *GoNCClient) LoadRescue(commit bool) error {
+	return g.withSession(func() error {
+		reply, err := g.Driver.SendRaw(loadRescueStr)
+		if err != nil {
+			return fmt.Errorf("driver error: %w", err)
+		}
+
+		// Never commit a partially loaded configuration
+		err = checkLoadResults(reply.Data)
+		if err != nil {
+			return err
+		}
+
+		if commit {
+			_, err = g.commit()
+			if err != nil {
+				return fmt.Errorf("driver error: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// DeleteRescue deletes the rescue configuration (request system configuration rescue delete)
+func (g *GoNCClient) DeleteRescue() error {
+	_, err := g.sendRaw(deleteRescueStr)
+	return err
+}
@@ -0,0 +1,76 @@
+package junos_helpers
+
+import (
+	"testing"
+)
+
+func TestRescue(t *testing.T) {
+	tt := []struct {
+		name     string
+		call     func(g *GoNCClient) error
+		expected []string
+	}{
+		{
+			name:     "save",
+			call:     func(g *GoNCClient) error { return g.SaveRescue() },
+			expected: []string{saveRescueStr},
+		},
+		{
+			name:     "load",
+			call:     func(g *GoNCClient) error { return g.LoadRescue(false) },
+			expected: []string{loadRescueStr},
+		},
+		{
+			name:     "load and commit",
+			call:     func(g *GoNCClient) error { return g.LoadRescue(true) },
+			expected: []string{loadRescueStr, commitStr},
+		},
+		{
+			name:     "delete",
+			call:     func(g *GoNCClient) error { return g.DeleteRescue() },
+			expected: []string{deleteRescueStr},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g, fd := newTestClient(loadSuccessReply)
+
+			err := tc.call(g)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(fd.Sent) != len(tc.expected) {
+				t.Fatalf("got RPCs %q, expected %q", fd.Sent, tc.expected)
+			}
+
+			for i := range tc.expected {
+				if fd.Sent[i] != tc.expected[i] {
+					t.Errorf("got RPC %q, expected %q", fd.Sent[i], tc.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLoadRescueMissing(t *testing.T) {
+	g, fd := newTestClient(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<load-configuration-results>
+<rpc-error>
+<error-severity>error</error-severity>
+<error-message>rescue configuration not found</error-message>
+</rpc-error>
+<load-error-count>1</load-error-count>
+</load-configuration-results>
+</rpc-reply>`)
+
+	err := g.LoadRescue(true)
+	if err == nil {
+		t.Fatal("expected an error for a missing rescue configuration")
+	}
+
+	if len(fd.Sent) != 1 {
+		t.Errorf("got RPCs %q, expected no commit after a failed load", fd.Sent)
+	}
+}
@@ -0,0 +1,265 @@
+package junos_helpers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	xmlnode "github.com/davedotdev/go-netconf/helpers/xmlnode"
+	rpc "github.com/davedotdev/go-netconf/rpc"
+)
+
+// streamer is implemented by drivers able to return a reply as it arrives rather than buffered
+type streamer interface {
+	SendRawStream(rawxml string) (io.Reader, error)
+}
+
+// sendStream sends rpcString on the open session and returns a reader over the raw rpc-reply, streamed
+// from the transport when the driver supports it and buffered otherwise. The reader must be read to
+// io.EOF, e.g. with drain, before the session is used again.
+func (g *GoNCClient) sendStream(rpcString string) (io.Reader, error) {
+	if s, ok := g.Driver.(streamer); ok {
+		r, err := s.SendRawStream(rpcString)
+		if err != nil {
+			return nil, fmt.Errorf("driver error: %w", err)
+		}
+		return r, nil
+	}
+
+	reply, err := g.Driver.SendRaw(rpcString)
+	if err != nil {
+		return nil, fmt.Errorf("driver error: %w", err)
+	}
+
+	return strings.NewReader(reply.RawReply), nil
+}
+
+// drain reads what is left of a streamed reply, so the session stays in step after a reply read in part
+func drain(r io.Reader) error {
+	_, err := io.Copy(ioutil.Discard, r)
+	return err
+}
+
+// streamEntries calls fn with each element named entry in the streamed reply r, then drains r. The
+// rpc-errors of error severity the reply carries are returned once it has been read, as Exec would.
+func streamEntries(r io.Reader, entry string, fn func(*xmlnode.Node) error) error {
+	var failed []rpc.RPCError
+
+	match := func(name xml.Name) bool {
+		return name.Local == entry || name.Local == "rpc-error"
+	}
+
+	err := xmlnode.StreamMatch(r, match, func(n *xmlnode.Node) error {
+		if n.Name.Local != "rpc-error" || entry == "rpc-error" {
+			return fn(n)
+		}
+
+		if rpcErr := nodeRPCError(n); rpcErr.Severity == "error" {
+			failed = append(failed, rpcErr)
+		}
+		return nil
+	})
+
+	errDrain := drain(r)
+	if err != nil {
+		return err
+	}
+	if errDrain != nil {
+		return fmt.Errorf("driver error: %w", errDrain)
+	}
+
+	switch len(failed) {
+	case 0:
+		return nil
+	case 1:
+		return &failed[0]
+	default:
+		return &rpc.RPCErrors{Errors: failed}
+	}
+}
+
+// nodeRPCError returns the rpc-error element n as an rpc.RPCError
+func nodeRPCError(n *xmlnode.Node) rpc.RPCError {
+	value := func(path string) string {
+		if c := n.Find(path); c != nil {
+			return c.Value()
+		}
+		return ""
+	}
+
+	return rpc.RPCError{
+		Type:     value("error-type"),
+		Tag:      value("error-tag"),
+		Severity: value("error-severity"),
+		Path:     value("error-path"),
+		Message:  value("error-message"),
+	}
+}
+
+// replyTail is how much of a streamed reply copyReplyData holds back, enough for the closing rpc-reply tag
+const replyTail = 256
+
+// copyReplyData copies the data of the streamed rpc-reply r, everything between its start and end tags,
+// to w as it arrives, then drains r. A reply opening with an rpc-error is read in full and returned as the
+// error instead, as Exec would.
+func copyReplyData(w io.Writer, r io.Reader) error {
+	br := bufio.NewReader(r)
+
+	var head bytes.Buffer // Kept to parse a reply holding rpc-errors
+
+	selfClosing, err := readReplyStart(br, &head)
+	if err != nil {
+		drain(br)
+		return err
+	}
+
+	if selfClosing {
+		return rpc.ErrEmptyReply
+	}
+
+	var lead []byte
+	for {
+		c, err := br.ReadByte()
+		if err != nil {
+			return fmt.Errorf("driver error: %w", io.ErrUnexpectedEOF)
+		}
+		if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+			br.UnreadByte()
+			break
+		}
+		lead = append(lead, c)
+	}
+
+	next, _ := br.Peek(64)
+	if isRPCErrorStart(next) {
+		rest, err := ioutil.ReadAll(br)
+		if err != nil {
+			return fmt.Errorf("driver error: %w", err)
+		}
+
+		reply, err := rpc.NewRPCReply(append(append(head.Bytes(), lead...), rest...), false)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.WriteString(w, reply.Data)
+		return err
+	}
+
+	tw := &tailWriter{w: w}
+
+	_, err = tw.Write(lead)
+	if err == nil {
+		_, err = io.Copy(tw, br)
+	}
+
+	errDrain := drain(br)
+	if err != nil {
+		return err
+	}
+	if errDrain != nil {
+		return fmt.Errorf("driver error: %w", errDrain)
+	}
+
+	return tw.finish()
+}
+
+// readReplyStart reads r up to the end of the start tag of the rpc-reply, skipping the XML declaration
+// and any comments ahead of it, recording what it read in head. It reports whether the tag closes itself.
+func readReplyStart(r *bufio.Reader, head *bytes.Buffer) (bool, error) {
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return false, fmt.Errorf("driver error: no rpc-reply: %w", io.ErrUnexpectedEOF)
+		}
+		head.WriteByte(c)
+
+		if c != '<' {
+			continue
+		}
+
+		next, err := r.Peek(1)
+		if err != nil {
+			return false, fmt.Errorf("driver error: no rpc-reply: %w", io.ErrUnexpectedEOF)
+		}
+
+		declaration := next[0] == '?' || next[0] == '!'
+
+		var quote, last byte
+		for {
+			c, err = r.ReadByte()
+			if err != nil {
+				return false, fmt.Errorf("driver error: unterminated rpc-reply: %w", io.ErrUnexpectedEOF)
+			}
+			head.WriteByte(c)
+
+			if quote == 0 && c == '>' {
+				if !declaration {
+					return last == '/', nil
+				}
+				break
+			}
+
+			switch {
+			case quote != 0 && c == quote:
+				quote = 0
+			case quote == 0 && (c == '"' || c == '\''):
+				quote = c
+			}
+			last = c
+		}
+	}
+}
+
+// isRPCErrorStart reports whether b starts with an rpc-error start tag, in any namespace prefix
+func isRPCErrorStart(b []byte) bool {
+	if len(b) == 0 || b[0] != '<' {
+		return false
+	}
+
+	name := b[1:]
+	if end := bytes.IndexAny(name, " \t\r\n/>"); end != -1 {
+		name = name[:end]
+	}
+	if colon := bytes.IndexByte(name, ':'); colon != -1 {
+		name = name[colon+1:]
+	}
+
+	return string(name) == "rpc-error"
+}
+
+// tailWriter writes to w all but the last replyTail bytes written to it, which finish writes up to the
+// closing tag they hold
+type tailWriter struct {
+	w    io.Writer
+	held []byte
+}
+
+func (t *tailWriter) Write(p []byte) (int, error) {
+	t.held = append(t.held, p...)
+
+	if n := len(t.held) - replyTail; n > 0 {
+		_, err := t.w.Write(t.held[:n])
+		if err != nil {
+			return 0, err
+		}
+		t.held = append(t.held[:0], t.held[n:]...)
+	}
+
+	return len(p), nil
+}
+
+// finish writes the held bytes up to the last end tag, the rpc-reply's own
+func (t *tailWriter) finish() error {
+	end := bytes.LastIndex(t.held, []byte("</"))
+	if end == -1 {
+		return fmt.Errorf("driver error: unterminated rpc-reply: %w", io.ErrUnexpectedEOF)
+	}
+
+	_, err := t.w.Write(t.held[:end])
+	return err
+}
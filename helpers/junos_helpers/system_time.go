@@ -0,0 +1,75 @@
+package junos_helpers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	xmlnode "github.com/davedotdev/go-netconf/helpers/xmlnode"
+)
+
+const getSystemUptimeStr = `<get-system-uptime-information/>
+`
+
+// ntpTimeSource is the time-source Junos reports while its clock is synchronized with NTP, otherwise
+// it reports LOCAL CLOCK
+const ntpTimeSource = "NTP CLOCK"
+
+// dateTimeLayout is the layout of the date-time text, used when the reply carries no junos:seconds
+const dateTimeLayout = "2006-01-02 15:04:05 MST"
+
+// parseSystemTime extracts the current time and whether it comes from NTP from the data of a
+// get-system-uptime-information reply, taking the first routing engine of a multi routing engine reply
+func parseSystemTime(data string) (time.Time, bool, error) {
+	root, err := xmlnode.Parse("<rpc-reply>" + data + "</rpc-reply>")
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	info := root.Find("system-uptime-information")
+	if info == nil {
+		info = root.Find("multi-routing-engine-results/multi-routing-engine-item/system-uptime-information")
+	}
+	if info == nil {
+		return time.Time{}, false, fmt.Errorf("no system-uptime-information in reply")
+	}
+
+	dateTime := info.Find("current-time/date-time")
+	if dateTime == nil {
+		return time.Time{}, false, fmt.Errorf("no current-time in reply")
+	}
+
+	var now time.Time
+	if seconds := dateTime.Attr("seconds"); seconds != "" {
+		s, err := strconv.ParseInt(seconds, 10, 64)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid current-time seconds %q: %w", seconds, err)
+		}
+		now = time.Unix(s, 0).UTC()
+	} else {
+		now, err = time.Parse(dateTimeLayout, dateTime.Value())
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid current-time: %w", err)
+		}
+	}
+
+	var synchronized bool
+	if source := info.Find("time-source"); source != nil {
+		synchronized = strings.EqualFold(source.Value(), ntpTimeSource)
+	}
+
+	return now, synchronized, nil
+}
+
+// GetSystemTime returns the device clock and whether it is synchronized with NTP, e.g. for zero-touch
+// provisioning waiting on a synchronized clock before proceeding. Systems with several routing engines
+// report the clock of the first.
+func (g *GoNCClient) GetSystemTime() (time.Time, bool, error) {
+	reply, err := g.sendRaw(getSystemUptimeStr)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	return parseSystemTime(reply.Data)
+}
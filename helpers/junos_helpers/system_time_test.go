@@ -0,0 +1,75 @@
+package junos_helpers
+
+import (
+	"testing"
+	"time"
+)
+
+const systemUptimeReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:junos="http://xml.juniper.net/junos/18.4R1/junos">
+<system-uptime-information xmlns="http://xml.juniper.net/junos/18.4R1/junos">
+<current-time>
+<date-time junos:seconds="1597670822">2020-08-17 13:27:02 UTC</date-time>
+</current-time>
+<time-source> NTP CLOCK </time-source>
+<system-booted-time>
+<date-time junos:seconds="1597060000">2020-08-10 11:46:40 UTC</date-time>
+</system-booted-time>
+</system-uptime-information>
+</rpc-reply>`
+
+const multiRESystemUptimeReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<multi-routing-engine-results>
+<multi-routing-engine-item>
+<re-name>re0</re-name>
+<system-uptime-information>
+<current-time>
+<date-time>2020-08-17 13:27:02 UTC</date-time>
+</current-time>
+<time-source> LOCAL CLOCK </time-source>
+</system-uptime-information>
+</multi-routing-engine-item>
+</multi-routing-engine-results>
+</rpc-reply>`
+
+func TestGetSystemTime(t *testing.T) {
+	expected := time.Date(2020, 8, 17, 13, 27, 2, 0, time.UTC)
+
+	tt := []struct {
+		name         string
+		reply        string
+		synchronized bool
+		fails        bool
+	}{
+		{name: "ntp", reply: systemUptimeReply, synchronized: true},
+		{name: "multiRE", reply: multiRESystemUptimeReply, synchronized: false},
+		{name: "noUptime", reply: okReply, fails: true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g, fd := newTestClient(tc.reply)
+
+			now, synchronized, err := g.GetSystemTime()
+			if tc.fails {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !now.Equal(expected) {
+				t.Errorf("got time %v, expected %v", now, expected)
+			}
+			if synchronized != tc.synchronized {
+				t.Errorf("got synchronized %v, expected %v", synchronized, tc.synchronized)
+			}
+
+			if len(fd.Sent) != 1 || fd.Sent[0] != getSystemUptimeStr {
+				t.Errorf("got RPCs %q, expected get-system-uptime-information", fd.Sent)
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package junos_helpers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ConfigNode is a statement of a Junos configuration in the curly brace text format, e.g. as read with
+// ReadConfiguration and FormatText. Containers hold the statements inside their braces as Children,
+// leaves ending in ";" have none.
+type ConfigNode struct {
+	Words    []string      // Words of the statement with quoted strings unquoted, e.g. group, ibgp
+	Children []*ConfigNode // Statements inside the braces, in configuration order
+}
+
+// Name returns the words of the statement separated by spaces, e.g. "group ibgp"
+func (n *ConfigNode) Name() string {
+	return strings.Join(n.Words, " ")
+}
+
+// Get returns the statement at path, given as the words of the nested statements separated by spaces,
+// e.g. "protocols bgp group ibgp" or "system host-name r1". The path may leave out the value of the last
+// statement, "system host-name" also returns the host-name statement. It returns nil when there is no such
+// statement. Quoted words containing spaces can not be addressed.
+func (n *ConfigNode) Get(path string) *ConfigNode {
+	return n.get(strings.Fields(path))
+}
+
+// get returns the statement at the path of words below n
+func (n *ConfigNode) get(path []string) *ConfigNode {
+	if len(path) == 0 {
+		return n
+	}
+
+	for _, child := range n.Children {
+		// The path may end within the statement, leaving out its value
+		if len(child.Words) > len(path) {
+			if hasWords(child.Words, path) {
+				return child
+			}
+			continue
+		}
+
+		if !hasWords(path, child.Words) {
+			continue
+		}
+
+		found := child.get(path[len(child.Words):])
+		if found != nil {
+			return found
+		}
+	}
+
+	return nil
+}
+
+// hasWords reports whether s starts with prefix
+func hasWords(s []string, prefix []string) bool {
+	for i := range prefix {
+		if s[i] != prefix[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ParseTextConfig parses a Junos configuration in the curly brace text format into a tree, whose root
+// holds the top level statements. Comments, both "#" lines and /* */ annotations, are dropped. The reply
+// of ReadConfiguration with FormatText may be passed as is, the configuration is then taken from its
+// <configuration-text> element. A list such as "members [ v10 v20 ];" becomes a statement per value,
+// as if written "members v10; members v20;".
+func ParseTextConfig(text string) (*ConfigNode, error) {
+	text, err := unwrapTextConfig(text)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := tokenizeTextConfig(text)
+	if err != nil {
+		return nil, err
+	}
+
+	root := &ConfigNode{}
+	stack := []*ConfigNode{root}
+	var words []string
+	var list []string // Values of the [ ] list being read, nil outside one
+	listEnded := false
+
+	for _, tok := range tokens {
+		parent := stack[len(stack)-1]
+
+		if list != nil && tok != "]" {
+			if tok == "{" || tok == "}" || tok == ";" || tok == "[" {
+				return nil, fmt.Errorf("list of %q is missing its closing ]", strings.Join(words, " "))
+			}
+			list = append(list, strings.TrimPrefix(tok, "\x00"))
+			continue
+		}
+
+		if listEnded && tok != ";" {
+			return nil, fmt.Errorf("statement %q is missing its terminating ;", strings.Join(words, " "))
+		}
+
+		switch tok {
+		case "[":
+			if len(words) == 0 {
+				return nil, fmt.Errorf("list without a statement")
+			}
+			list = []string{}
+		case "]":
+			if list == nil {
+				return nil, fmt.Errorf("unbalanced ]")
+			}
+			for _, value := range list {
+				statement := append(append([]string{}, words...), value)
+				parent.Children = append(parent.Children, &ConfigNode{Words: statement})
+			}
+			list = nil
+			listEnded = true
+		case "{":
+			node := &ConfigNode{Words: words, Children: []*ConfigNode{}}
+			parent.Children = append(parent.Children, node)
+			stack = append(stack, node)
+			words = nil
+		case ";":
+			if len(words) > 0 && !listEnded {
+				parent.Children = append(parent.Children, &ConfigNode{Words: words})
+			}
+			words = nil
+			listEnded = false
+		case "}":
+			if len(words) > 0 {
+				return nil, fmt.Errorf("statement %q is missing its terminating ;", strings.Join(words, " "))
+			}
+			if len(stack) == 1 {
+				return nil, fmt.Errorf("unbalanced }")
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			words = append(words, strings.TrimPrefix(tok, "\x00"))
+		}
+	}
+
+	if list != nil {
+		return nil, fmt.Errorf("list of %q is missing its closing ]", strings.Join(words, " "))
+	}
+
+	if len(words) > 0 {
+		return nil, fmt.Errorf("statement %q is missing its terminating ;", strings.Join(words, " "))
+	}
+
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("%d unclosed {", len(stack)-1)
+	}
+
+	return root, nil
+}
+
+// unwrapTextConfig returns the unescaped content of the <configuration-text> element when text is an
+// XML reply holding one, and text unchanged when it is not XML
+func unwrapTextConfig(text string) (string, error) {
+	if !strings.HasPrefix(strings.TrimSpace(text), "<") {
+		return text, nil
+	}
+
+	d := xml.NewDecoder(strings.NewReader(text))
+
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return "", fmt.Errorf("no configuration-text in reply")
+		}
+		if err != nil {
+			return "", err
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "configuration-text" {
+			continue
+		}
+
+		var config string
+		err = d.DecodeElement(&config, &start)
+		if err != nil {
+			return "", err
+		}
+
+		return config, nil
+	}
+}
+
+// tokenizeTextConfig splits text into words and the "{", "}", ";", "[" and "]" delimiters, dropping comments.
+// Quoted strings are unquoted and prefixed with a NUL so a quoted delimiter is not mistaken for one.
+func tokenizeTextConfig(text string) ([]string, error) {
+	var tokens []string
+	var word strings.Builder
+
+	flush := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, word.String())
+			word.Reset()
+		}
+	}
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+
+		switch {
+		case c == '"':
+			flush()
+
+			var quoted strings.Builder
+			quoted.WriteByte(0)
+
+			i++
+			for ; i < len(text) && text[i] != '"'; i++ {
+				if text[i] == '\\' && i+1 < len(text) {
+					i++
+				}
+				quoted.WriteByte(text[i])
+			}
+			if i == len(text) {
+				return nil, fmt.Errorf("unterminated quoted string")
+			}
+
+			tokens = append(tokens, quoted.String())
+		case c == '#' && word.Len() == 0:
+			for i < len(text) && text[i] != '\n' {
+				i++
+			}
+		case c == '/' && strings.HasPrefix(text[i:], "/*"):
+			flush()
+
+			end := strings.Index(text[i+2:], "*/")
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated comment")
+			}
+			i += 2 + end + 1
+		case c == '{' || c == '}' || c == ';' || c == '[' || c == ']':
+			flush()
+			tokens = append(tokens, string(c))
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			flush()
+		default:
+			word.WriteByte(c)
+		}
+	}
+
+	flush()
+
+	return tokens, nil
+}
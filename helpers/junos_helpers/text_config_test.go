@@ -0,0 +1,177 @@
+package junos_helpers
+
+import (
+	"strings"
+	"testing"
+)
+
+const textConfig = `## Last changed: 2020-06-01 10:00:00 UTC
+version 18.4R1;
+system {
+    host-name r1;
+    /* Managed by automation */
+    login {
+        message "Authorized access only; all activity is logged";
+    }
+}
+protocols {
+    bgp {
+        group ibgp {
+            type internal;
+            local-address 192.0.2.1;
+            neighbor 192.0.2.2 {
+                description "route reflector {rr1}";
+            }
+        }
+        group ebgp {
+            type external;
+            peer-as 65001;
+        }
+    }
+}
+`
+
+func TestParseTextConfig(t *testing.T) {
+	root, err := ParseTextConfig(textConfig)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(root.Children) != 3 {
+		t.Fatalf("got %d top level statements, expected 3", len(root.Children))
+	}
+
+	tt := []struct {
+		path     string
+		name     string
+		children int
+	}{
+		{path: "version", name: "version 18.4R1"},
+		{path: "system host-name r1", name: "host-name r1"},
+		{path: "system login message", name: "message Authorized access only; all activity is logged"},
+		{path: "protocols bgp", name: "bgp", children: 2},
+		{path: "protocols bgp group ibgp", name: "group ibgp", children: 3},
+		{path: "protocols bgp group ebgp peer-as 65001", name: "peer-as 65001"},
+		{path: "protocols bgp group ibgp neighbor 192.0.2.2 description", name: "description route reflector {rr1}"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.path, func(t *testing.T) {
+			node := root.Get(tc.path)
+			if node == nil {
+				t.Fatalf("no statement at %q", tc.path)
+			}
+
+			if node.Name() != tc.name {
+				t.Errorf("got %q, expected %q", node.Name(), tc.name)
+			}
+
+			if len(node.Children) != tc.children {
+				t.Errorf("got %d children, expected %d", len(node.Children), tc.children)
+			}
+		})
+	}
+
+	for _, path := range []string{"protocols ospf", "protocols bgp group missing", "system host-name r2"} {
+		if node := root.Get(path); node != nil {
+			t.Errorf("got %q at %q, expected no statement", node.Name(), path)
+		}
+	}
+}
+
+func TestParseTextConfigInvalid(t *testing.T) {
+	tt := []struct {
+		name   string
+		config string
+	}{
+		{name: "unclosed brace", config: "system { host-name r1;"},
+		{name: "extra brace", config: "system { host-name r1; } }"},
+		{name: "missing semicolon", config: "system { host-name r1 }"},
+		{name: "unterminated string", config: `system { host-name "r1; }`},
+		{name: "unterminated comment", config: "/* system { host-name r1; }"},
+		{name: "unclosed list", config: "vlan { members [ users voice; }"},
+		{name: "extra bracket", config: "vlan { members users ]; }"},
+		{name: "list without semicolon", config: "vlan { members [ users ] }"},
+		{name: "no configuration-text", config: `<configuration><system/></configuration>`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseTextConfig(tc.config)
+			if err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+// textConfigReply is a get-configuration reply in the text format, entities escaped by the device
+const textConfigReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:junos="http://xml.juniper.net/junos/18.4R1/junos">
+<configuration-text xmlns="http://xml.juniper.net/xnm/1.1/xnm">
+## Last commit: 2020-06-01 10:00:00 UTC by admin
+version 18.4R1;
+interfaces {
+    ge-0/0/1 {
+        description "uplink &lt;core&gt; &amp; backup";
+        unit 0 {
+            family ethernet-switching {
+                vlan {
+                    members [ users voice ];
+                }
+            }
+        }
+    }
+}
+policy-options {
+    community blue members [ "65000:1" 65000:2 ];
+}
+</configuration-text>
+</rpc-reply>`
+
+func TestParseTextConfigReply(t *testing.T) {
+	g, fd := newTestClient(textConfigReply)
+
+	reply, err := g.ReadConfiguration("", FormatText)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.Contains(fd.Sent[0], `format="text"`) {
+		t.Errorf("got %q, expected the configuration to be read as text", fd.Sent[0])
+	}
+
+	root, err := ParseTextConfig(reply)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tt := []struct {
+		path string
+		name string
+	}{
+		{path: "version", name: "version 18.4R1"},
+		{path: "interfaces ge-0/0/1 description", name: "description uplink <core> & backup"},
+		{path: "interfaces ge-0/0/1 unit 0 family ethernet-switching vlan members users", name: "members users"},
+		{path: "interfaces ge-0/0/1 unit 0 family ethernet-switching vlan members voice", name: "members voice"},
+		{path: "policy-options community blue members 65000:1", name: "community blue members 65000:1"},
+		{path: "policy-options community blue members 65000:2", name: "community blue members 65000:2"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.path, func(t *testing.T) {
+			node := root.Get(tc.path)
+			if node == nil {
+				t.Fatalf("no statement at %q", tc.path)
+			}
+
+			if node.Name() != tc.name {
+				t.Errorf("got %q, expected %q", node.Name(), tc.name)
+			}
+		})
+	}
+
+	vlan := root.Get("interfaces ge-0/0/1 unit 0 family ethernet-switching vlan")
+	if vlan == nil || len(vlan.Children) != 2 {
+		t.Errorf("got %+v, expected a statement for each of the 2 members", vlan)
+	}
+}
@@ -0,0 +1,227 @@
+package junos_helpers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// UnmatchedElementsError is returned in strict mode and lists every reply element the target struct did not capture
+type UnmatchedElementsError struct {
+	Paths []string
+}
+
+// Error generates a string representation of the unmatched elements
+func (e *UnmatchedElementsError) Error() string {
+	return fmt.Sprintf("%d reply element(s) not captured by the struct: %s", len(e.Paths), strings.Join(e.Paths, ", "))
+}
+
+// MarshalGroupNS reads the group and unmarshals it onto obj, returning the path of every reply element
+// that obj has no field for. With strict set, any unmatched element is returned as an UnmatchedElementsError.
+//
+// encoding/xml matches a tag without a namespace (`xml:"interface"`) against an element in any namespace,
+// whereas a namespace qualified tag (`xml:"http://yang.juniper.net/junos/conf/root interface"`) only
+// matches elements in exactly that namespace. Junos decorates YANG derived replies with namespaces, so
+// structs should use local names only and qualify a tag only when it must discriminate between namespaces.
+// Nested paths (`xml:"unit>family>inet"`), `,any` and `,innerxml` fields are honoured when matching.
+func (g *GoNCClient) MarshalGroupNS(id string, obj interface{}, strict bool) ([]string, error) {
+	reply, err := g.ReadRawGroup(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return unmarshalChecked(reply, obj, strict)
+}
+
+// unmarshalChecked unmarshals data onto obj and reports the elements obj did not capture
+func unmarshalChecked(data string, obj interface{}, strict bool) ([]string, error) {
+	err := xml.Unmarshal([]byte(data), obj)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := buildElementTree(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var unmatched []string
+	if root != nil {
+		collectUnmatched(reflect.TypeOf(obj), root, root.name.Local, &unmatched)
+	}
+
+	if strict && len(unmatched) > 0 {
+		return unmatched, &UnmatchedElementsError{Paths: unmatched}
+	}
+
+	return unmatched, nil
+}
+
+// elementNode is the bare element structure of a reply, used to compare against struct tags
+type elementNode struct {
+	name     xml.Name
+	text     string
+	children []*elementNode
+}
+
+// buildElementTree returns the first root element of data and all of its descendants
+func buildElementTree(data string) (*elementNode, error) {
+	d := xml.NewDecoder(strings.NewReader(data))
+
+	var stack []*elementNode
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &elementNode{name: t.Name}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, n)
+			}
+			stack = append(stack, n)
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			}
+		case xml.EndElement:
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return n, nil
+			}
+		}
+	}
+}
+
+// fieldPath is a struct field's element path, split on '>', and the type it decodes into
+type fieldPath struct {
+	names []xml.Name
+	typ   reflect.Type
+}
+
+var unmarshalerType = reflect.TypeOf((*xml.Unmarshaler)(nil)).Elem()
+
+// derefType strips pointers and repeated element slices down to the type an element decodes into
+func derefType(t reflect.Type) reflect.Type {
+	for {
+		switch {
+		case t.Kind() == reflect.Ptr:
+			t = t.Elem()
+		case t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8:
+			t = t.Elem()
+		default:
+			return t
+		}
+	}
+}
+
+// structPaths returns the element paths of a struct's fields and whether it swallows unknown children
+func structPaths(t reflect.Type) (paths []fieldPath, catchAll bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("xml")
+
+		if tag == "-" || f.Name == "XMLName" {
+			continue
+		}
+
+		name, flags := tag, ""
+		if i := strings.Index(tag, ","); i >= 0 {
+			name, flags = tag[:i], tag[i+1:]
+		}
+
+		switch {
+		case strings.Contains(flags, "innerxml") || strings.Contains(flags, "any"):
+			catchAll = true
+			continue
+		case flags != "" && flags != "omitempty":
+			// attr, chardata, cdata and comment fields never match child elements
+			continue
+		}
+
+		// encoding/xml promotes the fields of untagged embedded structs
+		if f.Anonymous && name == "" && derefType(f.Type).Kind() == reflect.Struct {
+			embedded, all := structPaths(derefType(f.Type))
+			paths = append(paths, embedded...)
+			catchAll = catchAll || all
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		var ns string
+		if i := strings.LastIndex(name, " "); i >= 0 {
+			ns, name = name[:i], name[i+1:]
+		}
+
+		fp := fieldPath{typ: f.Type}
+		for _, local := range strings.Split(name, ">") {
+			fp.names = append(fp.names, xml.Name{Local: local})
+		}
+		fp.names[len(fp.names)-1].Space = ns
+
+		paths = append(paths, fp)
+	}
+
+	return paths, catchAll
+}
+
+// collectUnmatched walks the element tree alongside the type it is decoded into, recording unmatched element paths
+func collectUnmatched(t reflect.Type, n *elementNode, path string, out *[]string) {
+	if t.Implements(unmarshalerType) || reflect.PtrTo(t).Implements(unmarshalerType) {
+		return
+	}
+
+	t = derefType(t)
+	if t.Kind() != reflect.Struct {
+		return
+	}
+
+	paths, catchAll := structPaths(t)
+	matchChildren(paths, catchAll, n, path, out)
+}
+
+// matchChildren checks each child of n against the remaining field paths
+func matchChildren(paths []fieldPath, catchAll bool, n *elementNode, path string, out *[]string) {
+	for _, c := range n.children {
+		childPath := path + "/" + c.name.Local
+
+		var nested []fieldPath
+		matched := false
+
+		for _, fp := range paths {
+			want := fp.names[0]
+			if want.Local != c.name.Local || (want.Space != "" && want.Space != c.name.Space) {
+				continue
+			}
+
+			matched = true
+
+			if len(fp.names) == 1 {
+				collectUnmatched(fp.typ, c, childPath, out)
+				continue
+			}
+
+			nested = append(nested, fieldPath{names: fp.names[1:], typ: fp.typ})
+		}
+
+		if len(nested) > 0 {
+			matchChildren(nested, false, c, childPath, out)
+		}
+
+		if !matched && !catchAll {
+			*out = append(*out, childPath)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package junos_helpers
+
+import (
+	"encoding/xml"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const namespacedGroupReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<configuration xmlns="http://yang.juniper.net/junos/conf/root" junos:commit-seconds="1590000000">
+<groups>
+<name>test-group</name>
+<interfaces xmlns="http://yang.juniper.net/junos/conf/interfaces">
+<interface>
+<name>ge-0/0/0</name>
+<description>uplink</description>
+<mtu>9192</mtu>
+</interface>
+</interfaces>
+</groups>
+</configuration>
+</rpc-reply>`
+
+type nsInterface struct {
+	Name        string `xml:"name"`
+	Description string `xml:"description"`
+	MTU         int    `xml:"mtu"`
+}
+
+type nsGroup struct {
+	XMLName    xml.Name      `xml:"configuration"`
+	Name       string        `xml:"groups>name"`
+	Interfaces []nsInterface `xml:"groups>interfaces>interface"`
+}
+
+func TestMarshalGroupNS(t *testing.T) {
+	g, _ := newTestClient(namespacedGroupReply)
+
+	var cfg nsGroup
+	unmatched, err := g.MarshalGroupNS("test-group", &cfg, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(unmatched) != 0 {
+		t.Errorf("got unmatched elements %v, expected none", unmatched)
+	}
+
+	expected := nsGroup{
+		XMLName:    xml.Name{Space: "http://yang.juniper.net/junos/conf/root", Local: "configuration"},
+		Name:       "test-group",
+		Interfaces: []nsInterface{{Name: "ge-0/0/0", Description: "uplink", MTU: 9192}},
+	}
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("got %+v, expected %+v", cfg, expected)
+	}
+}
+
+// nsGroupWrongNS qualifies the mtu tag with a namespace the device does not use
+type nsGroupWrongNS struct {
+	Interfaces []struct {
+		Name string `xml:"name"`
+		MTU  int    `xml:"urn:example:wrong mtu"`
+	} `xml:"groups>interfaces>interface"`
+	Name string `xml:"groups>name"`
+}
+
+func TestMarshalGroupNSStrict(t *testing.T) {
+	g, _ := newTestClient(namespacedGroupReply)
+
+	var cfg nsGroupWrongNS
+	unmatched, err := g.MarshalGroupNS("test-group", &cfg, true)
+
+	var unmatchedErr *UnmatchedElementsError
+	if !errors.As(err, &unmatchedErr) {
+		t.Fatalf("got error %v, expected *UnmatchedElementsError", err)
+	}
+
+	expected := []string{
+		"configuration/groups/interfaces/interface/description",
+		"configuration/groups/interfaces/interface/mtu",
+	}
+	if !reflect.DeepEqual(unmatched, expected) || !reflect.DeepEqual(unmatchedErr.Paths, expected) {
+		t.Errorf("got unmatched %v, expected %v", unmatched, expected)
+	}
+
+	// Outside of strict mode the same elements are reported without failing
+	g, _ = newTestClient(namespacedGroupReply)
+
+	unmatched, err = g.MarshalGroupNS("test-group", &cfg, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(unmatched, expected) {
+		t.Errorf("got unmatched %v, expected %v", unmatched, expected)
+	}
+}
@@ -0,0 +1,131 @@
+package junos_helpers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	rpc "github.com/davedotdev/go-netconf/rpc"
+)
+
+// capabilityValidate11 is advertised by devices accepting an inline <config> as the source of <validate> (RFC 6241 8.6)
+const capabilityValidate11 = "urn:ietf:params:netconf:capability:validate:1.1"
+
+const validateConfigStr = `<validate>
+  <source>
+    <config>
+%s
+    </config>
+  </source>
+</validate>
+`
+
+const commitCheckStr = `<commit-configuration>
+  <check/>
+</commit-configuration>
+`
+
+const discardChangesStr = `<discard-changes/>`
+
+// ValidationError is returned when the device rejects a configuration during validation
+type ValidationError struct {
+	Errors []rpc.RPCError
+}
+
+// Error generates a string representation of every validation error reported by the device
+func (e *ValidationError) Error() string {
+	var details []string
+
+	for _, re := range e.Errors {
+		msg := strings.TrimSpace(re.Message)
+		if path := strings.TrimSpace(re.Path); path != "" {
+			msg = fmt.Sprintf("%s (%s)", msg, path)
+		}
+		details = append(details, msg)
+	}
+
+	return fmt.Sprintf("validation failed with %d error(s): %s", len(e.Errors), strings.Join(details, "; "))
+}
+
+// validationErrors collects the error severity rpc-errors of a reply, including those nested in commit-results
+func validationErrors(reply *rpc.RPCReply) ([]rpc.RPCError, error) {
+	var cr commitReply
+
+	err := xml.Unmarshal([]byte("<rpc-reply>"+reply.Data+"</rpc-reply>"), &cr)
+	if err != nil {
+		return nil, err
+	}
+
+	rpcErrors := reply.Errors
+	if cr.Results != nil {
+		rpcErrors = append(rpcErrors, cr.Results.Errors...)
+		for _, re := range cr.Results.RoutingEngines {
+			rpcErrors = append(rpcErrors, re.Errors...)
+		}
+	}
+
+	var errs []rpc.RPCError
+	for _, re := range rpcErrors {
+		if re.Severity == "error" {
+			errs = append(errs, re)
+		}
+	}
+
+	return errs, nil
+}
+
+// checkValidation turns the result of a validating RPC into a ValidationError when the device rejected the configuration
+func checkValidation(reply *rpc.RPCReply, err error) error {
+	if reply == nil {
+		return err
+	}
+
+	errs, errParse := validationErrors(reply)
+	if errParse != nil {
+		return errParse
+	}
+
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+
+	return err
+}
+
+// ValidateConfig checks config, the content of a <config> element, without committing it.
+// Devices advertising the validate:1.1 capability validate it inline, leaving the candidate untouched.
+// Otherwise it is loaded into the candidate, checked with a commit check and discarded again.
+// Rejected configurations are returned as a *ValidationError, or a *LoadConfigError if the load itself failed.
+func (g *GoNCClient) ValidateConfig(config string) error {
+	return g.withSession(func() error {
+		if hasCapability(g.serverCapabilities(), capabilityValidate11) {
+			return checkValidation(g.Driver.SendRaw(fmt.Sprintf(validateConfigStr, escapePayload(config))))
+		}
+
+		return g.validateInCandidate(config)
+	})
+}
+
+// validateInCandidate loads config into the candidate, runs a commit check and always discards the changes
+func (g *GoNCClient) validateInCandidate(config string) error {
+	reply, err := g.Driver.SendRaw(buildLoadConfiguration(LoadMerge, FormatXML, config))
+	if err == nil {
+		err = checkLoadResults(reply.Data)
+	}
+
+	if err == nil {
+		err = checkValidation(g.Driver.SendRaw(commitCheckStr))
+	}
+
+	_, errDiscard := g.Driver.SendRaw(discardChangesStr)
+
+	if err != nil {
+		return err
+	}
+
+	if errDiscard != nil {
+		return fmt.Errorf("discard changes error: %+v", errDiscard)
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package junos_helpers
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const validateConfig = `<configuration><system><host-name>r1</host-name></system></configuration>`
+
+const validateErrorReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<rpc-error>
+<error-type>application</error-type>
+<error-tag>invalid-value</error-tag>
+<error-severity>error</error-severity>
+<error-path>[edit system]</error-path>
+<error-message>host-name is invalid</error-message>
+</rpc-error>
+</rpc-reply>`
+
+const commitCheckErrorReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:junos="http://xml.juniper.net/junos/18.4R1/junos">
+<commit-results>
+<rpc-error>
+<error-type>protocol</error-type>
+<error-tag>operation-failed</error-tag>
+<error-severity>error</error-severity>
+<error-message>
+Missing mandatory statement: 'root-authentication'
+</error-message>
+</rpc-error>
+</commit-results>
+</rpc-reply>`
+
+const commitCheckOkReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<commit-results>
+<routing-engine>
+<name>re0</name>
+<commit-check-success/>
+</routing-engine>
+</commit-results>
+</rpc-reply>`
+
+func TestValidateConfigInline(t *testing.T) {
+	tt := []struct {
+		name    string
+		reply   string
+		message string
+	}{
+		{name: "valid", reply: okReply},
+		{name: "invalid", reply: validateErrorReply, message: "host-name is invalid ([edit system])"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g, fd := newTestClient(tc.reply)
+			fd.Capabilities = []string{capabilityValidate11 + "?module=ietf-netconf", "urn:ietf:params:netconf:base:1.0"}
+
+			err := g.ValidateConfig(validateConfig)
+			checkValidateError(t, err, tc.message)
+
+			if len(fd.Sent) != 1 || !strings.HasPrefix(fd.Sent[0], "<validate>") || !strings.Contains(fd.Sent[0], validateConfig) {
+				t.Errorf("got RPCs %q, expected a single inline validate", fd.Sent)
+			}
+		})
+	}
+}
+
+func TestValidateConfigFallback(t *testing.T) {
+	tt := []struct {
+		name    string
+		replies []string
+		message string
+	}{
+		{name: "valid", replies: []string{loadSuccessReply, commitCheckOkReply}},
+		{name: "invalid", replies: []string{loadSuccessReply, commitCheckErrorReply}, message: "Missing mandatory statement: 'root-authentication'"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g, fd := newTestClient(tc.replies...)
+
+			err := g.ValidateConfig(validateConfig)
+			checkValidateError(t, err, tc.message)
+
+			if len(fd.Sent) != 3 {
+				t.Fatalf("got %d RPCs sent, expected load, commit check and discard", len(fd.Sent))
+			}
+			if !strings.Contains(fd.Sent[0], "<load-configuration") || !strings.Contains(fd.Sent[0], validateConfig) {
+				t.Errorf("got %q, expected the configuration to be loaded", fd.Sent[0])
+			}
+			if !strings.Contains(fd.Sent[1], "<check/>") {
+				t.Errorf("got %q, expected a commit check", fd.Sent[1])
+			}
+			if fd.Sent[2] != discardChangesStr {
+				t.Errorf("got %q, expected %q", fd.Sent[2], discardChangesStr)
+			}
+		})
+	}
+}
+
+// checkValidateError asserts err is nil when message is empty, and otherwise a ValidationError carrying message
+func checkValidateError(t *testing.T, err error, message string) {
+	t.Helper()
+
+	if message == "" {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return
+	}
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("got error %v, expected a *ValidationError", err)
+	}
+	if len(verr.Errors) != 1 || !strings.Contains(verr.Error(), message) {
+		t.Errorf("got %s, expected %s", verr.Error(), message)
+	}
+}
@@ -0,0 +1,182 @@
+package junos_helpers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+const getCandidateStr = `<get-configuration database="candidate">
+%s
+</get-configuration>
+`
+
+// LoadMismatchError is returned when the configuration read back after a load is missing part of what was sent
+type LoadMismatchError struct {
+	Missing []string // Leaves of the sent configuration, as path=value, the candidate does not contain
+}
+
+// Error generates a string representation of the leaves the device dropped
+func (e *LoadMismatchError) Error() string {
+	return fmt.Sprintf("load verification failed, the candidate is missing %d element(s): %s", len(e.Missing), strings.Join(e.Missing, ", "))
+}
+
+// configLeaf is a leaf element of a configuration, keyed by its path from the configuration root
+type configLeaf struct {
+	path  string
+	value string
+}
+
+// configLeaves returns the leaf elements below the root element of a configuration.
+// Only local names are used, so the junos attributes and namespaces of a read back do not matter.
+func configLeaves(data string) ([]configLeaf, error) {
+	d := xml.NewDecoder(strings.NewReader(data))
+
+	var (
+		leaves   []configLeaf
+		path     []string
+		text     strings.Builder
+		hasChild []bool
+	)
+
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return leaves, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if len(hasChild) > 0 {
+				hasChild[len(hasChild)-1] = true
+			}
+			path = append(path, t.Name.Local)
+			hasChild = append(hasChild, false)
+			text.Reset()
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			// Skip the configuration root itself
+			if !hasChild[len(hasChild)-1] && len(path) > 1 {
+				leaves = append(leaves, configLeaf{
+					path:  strings.Join(path[1:], "/"),
+					value: strings.TrimSpace(text.String()),
+				})
+			}
+			path = path[:len(path)-1]
+			hasChild = hasChild[:len(hasChild)-1]
+			text.Reset()
+		}
+	}
+}
+
+// candidateFilter builds a get-configuration filter selecting the top level statements of a configuration payload
+func candidateFilter(config string) (string, error) {
+	d := xml.NewDecoder(strings.NewReader(config))
+
+	var (
+		root     string
+		children []string
+		seen     = map[string]bool{}
+		depth    int
+	)
+
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 1 {
+				root = t.Name.Local
+			}
+			if depth == 2 && !seen[t.Name.Local] {
+				seen[t.Name.Local] = true
+				children = append(children, t.Name.Local)
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+
+	if root != "configuration" {
+		return "", fmt.Errorf("unable to verify load, expected a <configuration> payload, got <%s>", root)
+	}
+
+	var filter strings.Builder
+	filter.WriteString("<configuration>")
+	for _, c := range children {
+		filter.WriteString(fmt.Sprintf("<%s/>", c))
+	}
+	filter.WriteString("</configuration>")
+
+	return filter.String(), nil
+}
+
+// missingLeaves returns the leaves of sent that do not appear in candidate, counting repeated leaves
+func missingLeaves(sent []configLeaf, candidate []configLeaf) []string {
+	present := map[configLeaf]int{}
+	for _, l := range candidate {
+		present[l]++
+	}
+
+	var missing []string
+	for _, l := range sent {
+		if present[l] > 0 {
+			present[l]--
+			continue
+		}
+		missing = append(missing, fmt.Sprintf("%s=%s", l.path, l.value))
+	}
+
+	sort.Strings(missing)
+
+	return missing
+}
+
+// verifyLoad reads the loaded statements back from the candidate on the open session and
+// returns a LoadMismatchError if any leaf of config did not make it into the candidate
+func (g *GoNCClient) verifyLoad(config string) error {
+	filter, err := candidateFilter(config)
+	if err != nil {
+		return err
+	}
+
+	sent, err := configLeaves(config)
+	if err != nil {
+		return err
+	}
+
+	reply, err := g.Driver.SendRaw(fmt.Sprintf(getCandidateStr, filter))
+	if err != nil {
+		return err
+	}
+
+	candidate, err := configLeaves("<rpc-reply>" + reply.Data + "</rpc-reply>")
+	if err != nil {
+		return err
+	}
+
+	// Strip the configuration element so both sides are relative to the configuration root
+	for i := range candidate {
+		candidate[i].path = strings.TrimPrefix(candidate[i].path, "configuration/")
+	}
+
+	missing := missingLeaves(sent, candidate)
+	if len(missing) > 0 {
+		return &LoadMismatchError{Missing: missing}
+	}
+
+	return nil
+}
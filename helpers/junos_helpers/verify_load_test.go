@@ -0,0 +1,90 @@
+package junos_helpers
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const verifyConfig = `<configuration>
+  <interfaces>
+    <interface><name>ge-0/0/0</name><description>uplink</description></interface>
+    <interface><name>ge-0/0/1</name><description>downlink</description></interface>
+  </interfaces>
+  <system><host-name>r1</host-name></system>
+</configuration>`
+
+func TestSendRawConfigVerifyLoad(t *testing.T) {
+	tt := []struct {
+		name     string
+		readBack string
+		missing  []string
+	}{
+		{
+			name: "complete",
+			readBack: `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:junos="http://xml.juniper.net/junos/18.4R1/junos">
+<configuration junos:changed-seconds="1591005600">
+<interfaces>
+<interface><name>ge-0/0/0</name><description>uplink</description></interface>
+<interface><name>ge-0/0/1</name><description>downlink</description></interface>
+<interface><name>lo0</name></interface>
+</interfaces>
+<system><host-name>r1</host-name></system>
+</configuration>
+</rpc-reply>`,
+		},
+		{
+			name: "truncated",
+			readBack: `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<configuration>
+<interfaces>
+<interface><name>ge-0/0/0</name><description>uplink</description></interface>
+<interface><name>ge-0/0/1</name></interface>
+</interfaces>
+</configuration>
+</rpc-reply>`,
+			missing: []string{"interfaces/interface/description=downlink", "system/host-name=r1"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			g, fd := newTestClient(loadSuccessReply, tc.readBack)
+			g.VerifyLoad = true
+
+			_, err := g.SendRawConfig(verifyConfig, true)
+
+			expectedFilter := "<get-configuration database=\"candidate\">\n<configuration><interfaces/><system/></configuration>\n</get-configuration>\n"
+			if len(fd.Sent) < 2 || fd.Sent[1] != expectedFilter {
+				t.Fatalf("got RPCs %q, expected a read back of %q", fd.Sent, expectedFilter)
+			}
+
+			if tc.missing == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if fd.Sent[len(fd.Sent)-1] != commitStr {
+					t.Errorf("got %q, expected a commit", fd.Sent[len(fd.Sent)-1])
+				}
+				return
+			}
+
+			var mismatch *LoadMismatchError
+			if !errors.As(err, &mismatch) {
+				t.Fatalf("got error %v, expected a *LoadMismatchError", err)
+			}
+			if !reflect.DeepEqual(mismatch.Missing, tc.missing) {
+				t.Errorf("got %v, expected %v", mismatch.Missing, tc.missing)
+			}
+
+			for _, sent := range fd.Sent {
+				if sent == commitStr {
+					t.Errorf("commit sent after a load mismatch")
+				}
+			}
+			if fd.Sent[len(fd.Sent)-1] != discardChangesStr {
+				t.Errorf("got %q, expected the candidate to be discarded", fd.Sent[len(fd.Sent)-1])
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package junos_helpers
+
+import (
+	"encoding/xml"
+	"errors"
+)
+
+const yangLibraryNamespace = "urn:ietf:params:xml:ns:yang:ietf-yang-library"
+
+const getModulesStateStr = `<get>
+  <filter type="subtree">
+    <modules-state xmlns="` + yangLibraryNamespace + `"/>
+  </filter>
+</get>
+`
+
+const getModuleSetIDStr = `<get>
+  <filter type="subtree">
+    <modules-state xmlns="` + yangLibraryNamespace + `"><module-set-id/></modules-state>
+  </filter>
+</get>
+`
+
+// ErrNoYANGLibrary is returned when the device does not implement ietf-yang-library
+var ErrNoYANGLibrary = errors.New("device does not implement ietf-yang-library modules-state")
+
+// YANGModule is a module (or submodule) implemented or imported by the device
+type YANGModule struct {
+	Name            string          `xml:"name"`
+	Revision        string          `xml:"revision"`
+	Namespace       string          `xml:"namespace"`
+	Features        []string        `xml:"feature"`
+	Deviations      []YANGDeviation `xml:"deviation"`
+	ConformanceType string          `xml:"conformance-type"`
+	Submodules      []YANGModule    `xml:"submodule"`
+}
+
+// YANGDeviation names a module that deviates a YANGModule
+type YANGDeviation struct {
+	Name     string `xml:"name"`
+	Revision string `xml:"revision"`
+}
+
+// YANGLibrary is the RFC 7895 modules-state of a device
+type YANGLibrary struct {
+	ModuleSetID string       `xml:"module-set-id"`
+	Modules     []YANGModule `xml:"module"`
+}
+
+// Module returns the named module, or nil if the device does not list it
+func (y *YANGLibrary) Module(name string) *YANGModule {
+	for i := range y.Modules {
+		if y.Modules[i].Name == name {
+			return &y.Modules[i]
+		}
+	}
+	return nil
+}
+
+// parseModulesState extracts the modules-state from the data of a get reply
+func parseModulesState(data string) (*YANGLibrary, error) {
+	wrapper := struct {
+		Library *YANGLibrary `xml:"data>modules-state"`
+	}{}
+
+	err := xml.Unmarshal([]byte("<rpc-reply>"+data+"</rpc-reply>"), &wrapper)
+	if err != nil {
+		return nil, err
+	}
+
+	if wrapper.Library == nil || wrapper.Library.ModuleSetID == "" {
+		return nil, ErrNoYANGLibrary
+	}
+
+	return wrapper.Library, nil
+}
+
+// GetYANGLibrary returns the modules, revisions, features and deviations the device supports.
+// The library is cached in memory and only fetched again when the device's module-set-id changes.
+func (g *GoNCClient) GetYANGLibrary() (*YANGLibrary, error) {
+	var library *YANGLibrary
+
+	err := g.withSession(func() error {
+		var err error
+
+		library, err = g.fetchYANGLibrary()
+		if err != nil {
+			return err
+		}
+
+		g.yangLibrary = library
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return library, nil
+}
+
+// fetchYANGLibrary returns the cached library if the module-set-id still matches, otherwise the full library
+func (g *GoNCClient) fetchYANGLibrary() (*YANGLibrary, error) {
+	if g.yangLibrary != nil {
+		reply, err := g.Driver.SendRaw(getModuleSetIDStr)
+		if err != nil {
+			return nil, err
+		}
+
+		current, err := parseModulesState(reply.Data)
+		if err != nil {
+			return nil, err
+		}
+
+		if current.ModuleSetID == g.yangLibrary.ModuleSetID {
+			return g.yangLibrary, nil
+		}
+	}
+
+	reply, err := g.Driver.SendRaw(getModulesStateStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseModulesState(reply.Data)
+}
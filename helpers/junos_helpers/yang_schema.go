@@ -0,0 +1,616 @@
+package junos_helpers
+
+import (
+	"fmt"
+	"strings"
+
+	xmlnode "github.com/davedotdev/go-netconf/helpers/xmlnode"
+)
+
+const getSchemaStr = `<get-schema xmlns="urn:ietf:params:xml:ns:yang:ietf-netconf-monitoring">
+  <identifier>%s</identifier>%s
+  <format>yang</format>
+</get-schema>
+`
+
+const getSchemaVersionStr = `
+  <version>%s</version>`
+
+// maxUsesDepth bounds the expansion of groupings using groupings, catching a grouping that uses itself
+const maxUsesDepth = 32
+
+// GetSchema returns the YANG source of the module identifier at revision version, or the device's
+// default revision when version is empty, with the RFC 6022 get-schema operation
+func (g *GoNCClient) GetSchema(identifier string, version string) (string, error) {
+	var versionElement string
+	if version != "" {
+		versionElement = fmt.Sprintf(getSchemaVersionStr, xmlEscape(version))
+	}
+
+	reply, err := g.sendRaw(fmt.Sprintf(getSchemaStr, xmlEscape(identifier), versionElement))
+	if err != nil {
+		return "", err
+	}
+
+	schema, err := replyText(reply.Data)
+	if err != nil {
+		return "", err
+	}
+
+	schema = strings.TrimSpace(schema)
+	if schema == "" {
+		return "", fmt.Errorf("no schema in reply for %s", identifier)
+	}
+
+	return schema, nil
+}
+
+// SchemaValidator checks the names and structure of configuration payloads against YANG modules before
+// they are sent, catching misspelt elements without a round trip to the device. Only the data tree is
+// checked: unknown elements, elements in the wrong namespace, leaves holding elements and list entries
+// missing their keys. Types, ranges, must, when and if-feature are left to the device.
+type SchemaValidator struct {
+	root *schemaNode
+}
+
+// SchemaError is a problem a SchemaValidator found in a payload
+type SchemaError struct {
+	Path    string // Element path, e.g. /configuration/system/host-name
+	Message string
+}
+
+func (e SchemaError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// SchemaErrors is every problem a SchemaValidator found in a payload
+type SchemaErrors []SchemaError
+
+func (e SchemaErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, se := range e {
+		msgs = append(msgs, se.Error())
+	}
+
+	return fmt.Sprintf("%d schema errors: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// schemaNode is a node of the data tree defined by a set of YANG modules
+type schemaNode struct {
+	name      string
+	kind      string // container, list, leaf, leaf-list, anydata, anyxml, choice or case, empty for the root
+	namespace string // Namespace of the module defining the node
+	keys      []string
+	children  map[string]*schemaNode
+}
+
+// dataChild returns the child element name may be, looking through choices and cases, which leave no
+// element of their own, or nil if there is none
+func (n *schemaNode) dataChild(name string) *schemaNode {
+	if c, ok := n.children[name]; ok && c.kind != "choice" && c.kind != "case" {
+		return c
+	}
+
+	for _, c := range n.children {
+		if c.kind != "choice" && c.kind != "case" {
+			continue
+		}
+
+		if found := c.dataChild(name); found != nil {
+			return found
+		}
+	}
+
+	return nil
+}
+
+// child returns the child node name, creating it as kind if it does not exist yet
+func (n *schemaNode) child(name string, kind string, namespace string) *schemaNode {
+	if c, ok := n.children[name]; ok {
+		return c
+	}
+
+	if n.children == nil {
+		n.children = make(map[string]*schemaNode)
+	}
+
+	c := &schemaNode{name: name, kind: kind, namespace: namespace}
+	n.children[name] = c
+	return c
+}
+
+// yangStatement is a statement of a YANG module, e.g. keyword container and argument system
+type yangStatement struct {
+	keyword  string
+	arg      string
+	children []*yangStatement
+}
+
+// substatement returns the argument of the first substatement keyword, or "" if there is none
+func (s *yangStatement) substatement(keyword string) string {
+	for _, c := range s.children {
+		if c.keyword == keyword {
+			return c.arg
+		}
+	}
+	return ""
+}
+
+// yangModule is a parsed module along with the submodules it includes
+type yangModule struct {
+	name      string
+	prefix    string
+	namespace string
+	imports   map[string]string // Module name by import prefix
+	body      []*yangStatement  // Statements of the module and its included submodules
+	groupings map[string]*yangStatement
+}
+
+// yangScope resolves the groupings visible where a uses statement appears
+type yangScope struct {
+	module    *yangModule
+	namespace string // Namespace of the nodes defined, that of the module using a grouping rather than defining it
+	groupings map[string]*yangStatement
+	parent    *yangScope
+}
+
+// grouping returns the grouping name visible in s, resolving a prefix through the module's imports
+func (s *yangScope) grouping(name string, modules map[string]*yangModule) (*yangStatement, *yangModule, error) {
+	if i := strings.Index(name, ":"); i != -1 {
+		prefix, local := name[:i], name[i+1:]
+		if prefix != s.module.prefix {
+			m, ok := modules[s.module.imports[prefix]]
+			if !ok {
+				return nil, nil, fmt.Errorf("grouping %s: module with prefix %s not loaded", name, prefix)
+			}
+
+			grouping, ok := m.groupings[local]
+			if !ok {
+				return nil, nil, fmt.Errorf("grouping %s not found in module %s", name, m.name)
+			}
+			return grouping, m, nil
+		}
+		name = local
+	}
+
+	for scope := s; scope != nil; scope = scope.parent {
+		if grouping, ok := scope.groupings[name]; ok {
+			return grouping, scope.module, nil
+		}
+	}
+
+	return nil, nil, fmt.Errorf("grouping %s not found in module %s", name, s.module.name)
+}
+
+// NewSchemaValidator returns a validator for the data tree defined by modules, the YANG sources of the
+// modules and submodules describing the configuration, e.g. as read with GetSchema. Modules imported for
+// their groupings must be included, augments of modules that are not given are ignored.
+func NewSchemaValidator(modules ...string) (*SchemaValidator, error) {
+	byName := make(map[string]*yangModule)
+	submodules := make(map[string]*yangStatement)
+	var order []*yangModule
+
+	for _, source := range modules {
+		stmt, err := parseYANG(source)
+		if err != nil {
+			return nil, err
+		}
+
+		switch stmt.keyword {
+		case "module":
+			m := &yangModule{
+				name:      stmt.arg,
+				prefix:    stmt.substatement("prefix"),
+				namespace: stmt.substatement("namespace"),
+				imports:   make(map[string]string),
+			}
+			byName[m.name] = m
+			order = append(order, m)
+
+			m.body = stmt.children
+		case "submodule":
+			submodules[stmt.arg] = stmt
+		default:
+			return nil, fmt.Errorf("expected a module or submodule, got %s", stmt.keyword)
+		}
+	}
+
+	for _, m := range order {
+		var body []*yangStatement
+		for _, s := range m.body {
+			body = append(body, s)
+
+			if s.keyword != "include" {
+				continue
+			}
+
+			sub, ok := submodules[s.arg]
+			if !ok {
+				return nil, fmt.Errorf("module %s includes submodule %s, which was not given", m.name, s.arg)
+			}
+			body = append(body, sub.children...)
+		}
+		m.body = body
+
+		m.groupings = make(map[string]*yangStatement)
+		for _, s := range m.body {
+			switch s.keyword {
+			case "import":
+				m.imports[s.substatement("prefix")] = s.arg
+			case "grouping":
+				m.groupings[s.arg] = s
+			}
+		}
+	}
+
+	v := &SchemaValidator{root: &schemaNode{}}
+
+	for _, m := range order {
+		scope := &yangScope{module: m, namespace: m.namespace, groupings: m.groupings}
+
+		err := v.addData(v.root, m.body, scope, byName, 0)
+		if err != nil {
+			return nil, fmt.Errorf("module %s: %v", m.name, err)
+		}
+	}
+
+	// Augments may target nodes added by other augments, so apply them until none is left or none applies
+	pending := make(map[*yangStatement]*yangModule)
+	for _, m := range order {
+		for _, s := range m.body {
+			if s.keyword == "augment" {
+				pending[s] = m
+			}
+		}
+	}
+
+	for len(pending) > 0 {
+		applied := 0
+
+		for s, m := range pending {
+			target := v.root.find(s.arg)
+			if target == nil {
+				continue
+			}
+
+			scope := &yangScope{module: m, namespace: m.namespace, groupings: m.groupings}
+			err := v.addData(target, s.children, scope, byName, 0)
+			if err != nil {
+				return nil, fmt.Errorf("module %s: augment %s: %v", m.name, s.arg, err)
+			}
+
+			delete(pending, s)
+			applied++
+		}
+
+		if applied == 0 {
+			break
+		}
+	}
+
+	return v, nil
+}
+
+// find returns the node at the schema node identifier path below n, e.g. /sys:system/sys:ntp, ignoring
+// prefixes, or nil if there is none
+func (n *schemaNode) find(path string) *schemaNode {
+	node := n
+	for _, part := range strings.Split(strings.Trim(path, "/"), "/") {
+		if i := strings.Index(part, ":"); i != -1 {
+			part = part[i+1:]
+		}
+
+		next, ok := node.children[part]
+		if !ok {
+			return nil
+		}
+		node = next
+	}
+
+	return node
+}
+
+// addData adds the data nodes defined by statements below parent
+func (v *SchemaValidator) addData(parent *schemaNode, statements []*yangStatement, scope *yangScope, modules map[string]*yangModule, depth int) error {
+	// Groupings defined here are visible to every statement alongside them
+	local := make(map[string]*yangStatement)
+	for _, s := range statements {
+		if s.keyword == "grouping" {
+			local[s.arg] = s
+		}
+	}
+	if len(local) > 0 {
+		scope = &yangScope{module: scope.module, namespace: scope.namespace, groupings: local, parent: scope}
+	}
+
+	for _, s := range statements {
+		switch s.keyword {
+		case "container", "list", "leaf", "leaf-list", "anydata", "anyxml":
+			node := parent.child(s.arg, s.keyword, scope.namespace)
+
+			if s.keyword == "list" {
+				node.keys = strings.Fields(s.substatement("key"))
+			}
+
+			if s.keyword == "container" || s.keyword == "list" {
+				err := v.addData(node, s.children, scope, modules, depth)
+				if err != nil {
+					return err
+				}
+			}
+		case "choice":
+			node := parent.child(s.arg, s.keyword, scope.namespace)
+
+			for _, c := range s.children {
+				// A data statement directly in a choice is shorthand for a case of the same name holding it
+				statements := []*yangStatement{c}
+				if c.keyword == "case" {
+					statements = c.children
+				} else if !isDataStatement(c.keyword) {
+					continue
+				}
+
+				err := v.addData(node.child(c.arg, "case", scope.namespace), statements, scope, modules, depth)
+				if err != nil {
+					return err
+				}
+			}
+		case "uses":
+			if depth == maxUsesDepth {
+				return fmt.Errorf("uses %s nested more than %d deep", s.arg, maxUsesDepth)
+			}
+
+			grouping, m, err := scope.grouping(s.arg, modules)
+			if err != nil {
+				return err
+			}
+
+			// The grouping's statements are resolved where it was defined, but the nodes take the namespace
+			// of the module using it
+			groupingScope := &yangScope{module: m, namespace: scope.namespace, groupings: m.groupings}
+			if m == scope.module {
+				groupingScope.groupings, groupingScope.parent = scope.groupings, scope.parent
+			}
+
+			err = v.addData(parent, grouping.children, groupingScope, modules, depth+1)
+			if err != nil {
+				return err
+			}
+
+			for _, augment := range s.children {
+				if augment.keyword != "augment" {
+					continue
+				}
+
+				target := parent.find(augment.arg)
+				if target == nil {
+					return fmt.Errorf("uses %s: augment target %s not found", s.arg, augment.arg)
+				}
+
+				err = v.addData(target, augment.children, scope, modules, depth+1)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// isDataStatement reports whether keyword defines a node of the data tree
+func isDataStatement(keyword string) bool {
+	switch keyword {
+	case "container", "list", "leaf", "leaf-list", "anydata", "anyxml", "choice":
+		return true
+	}
+	return false
+}
+
+// Validate checks config, a configuration payload such as <configuration> or the content of an edit-config
+// <config> element, against the modules. It returns SchemaErrors listing every problem found, or nil.
+func (v *SchemaValidator) Validate(config string) error {
+	root, err := xmlnode.Parse("<config>" + config + "</config>")
+	if err != nil {
+		return err
+	}
+
+	// The payload may already be wrapped in <config>
+	if len(root.Children) == 1 && root.Children[0].Name.Local == "config" && v.root.children["config"] == nil {
+		root = root.Children[0]
+	}
+
+	var errs SchemaErrors
+	for _, c := range root.Children {
+		v.validate(v.root, c, "", &errs)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// validate checks the element el against the children of the schema node parent
+func (v *SchemaValidator) validate(parent *schemaNode, el *xmlnode.Node, path string, errs *SchemaErrors) {
+	path += "/" + el.Name.Local
+
+	node := parent.dataChild(el.Name.Local)
+	if node == nil {
+		*errs = append(*errs, SchemaError{Path: path, Message: "unknown element"})
+		return
+	}
+
+	if el.Name.Space != "" && node.namespace != "" && el.Name.Space != node.namespace {
+		*errs = append(*errs, SchemaError{Path: path, Message: fmt.Sprintf("element is in namespace %s, expected %s", el.Name.Space, node.namespace)})
+		return
+	}
+
+	switch node.kind {
+	case "leaf", "leaf-list":
+		if len(el.Children) > 0 {
+			*errs = append(*errs, SchemaError{Path: path, Message: fmt.Sprintf("%s can not contain elements", node.kind)})
+		}
+		return
+	case "anydata", "anyxml":
+		return
+	case "list":
+		for _, key := range node.keys {
+			if el.Find(key) == nil {
+				*errs = append(*errs, SchemaError{Path: path, Message: fmt.Sprintf("list entry is missing its key %s", key)})
+			}
+		}
+	}
+
+	for _, c := range el.Children {
+		v.validate(node, c, path, errs)
+	}
+}
+
+// yangToken is a token of a YANG module, quoted strings being arguments even when they look like syntax
+type yangToken struct {
+	text   string
+	quoted bool
+}
+
+// parseYANG parses the YANG source of a module or submodule into its statement tree
+func parseYANG(source string) (*yangStatement, error) {
+	tokens, err := tokenizeYANG(source)
+	if err != nil {
+		return nil, err
+	}
+
+	stmts, rest, err := parseYANGStatements(tokens)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("unbalanced } in YANG module")
+	}
+	if len(stmts) != 1 {
+		return nil, fmt.Errorf("expected a single module or submodule statement, got %d statements", len(stmts))
+	}
+
+	return stmts[0], nil
+}
+
+// parseYANGStatements parses statements until a closing } or the end of tokens, returning the tokens left
+func parseYANGStatements(tokens []yangToken) ([]*yangStatement, []yangToken, error) {
+	var stmts []*yangStatement
+
+	for len(tokens) > 0 {
+		tok := tokens[0]
+		if !tok.quoted && tok.text == "}" {
+			return stmts, tokens, nil
+		}
+		if !tok.quoted && (tok.text == "{" || tok.text == ";") {
+			return nil, nil, fmt.Errorf("expected a keyword, got %s", tok.text)
+		}
+
+		stmt := &yangStatement{keyword: tok.text}
+		tokens = tokens[1:]
+
+		if len(tokens) > 0 && (tokens[0].quoted || (tokens[0].text != "{" && tokens[0].text != ";" && tokens[0].text != "}")) {
+			stmt.arg = tokens[0].text
+			tokens = tokens[1:]
+		}
+
+		if len(tokens) == 0 {
+			return nil, nil, fmt.Errorf("statement %s %s is not terminated", stmt.keyword, stmt.arg)
+		}
+
+		switch tokens[0].text {
+		case ";":
+			tokens = tokens[1:]
+		case "{":
+			children, rest, err := parseYANGStatements(tokens[1:])
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(rest) == 0 {
+				return nil, nil, fmt.Errorf("statement %s %s is missing its closing }", stmt.keyword, stmt.arg)
+			}
+			stmt.children = children
+			tokens = rest[1:]
+		default:
+			return nil, nil, fmt.Errorf("statement %s %s is not terminated", stmt.keyword, stmt.arg)
+		}
+
+		stmts = append(stmts, stmt)
+	}
+
+	return stmts, tokens, nil
+}
+
+// tokenizeYANG splits source into keywords, arguments and the "{", "}" and ";" delimiters, dropping
+// comments and joining quoted strings concatenated with +
+func tokenizeYANG(source string) ([]yangToken, error) {
+	var tokens []yangToken
+	concat := false // The last token was a + following a quoted string
+
+	for i := 0; i < len(source); {
+		c := source[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case strings.HasPrefix(source[i:], "//"):
+			for i < len(source) && source[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(source[i:], "/*"):
+			end := strings.Index(source[i+2:], "*/")
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated comment in YANG module")
+			}
+			i += 2 + end + 2
+		case c == '{' || c == '}' || c == ';':
+			tokens = append(tokens, yangToken{text: string(c)})
+			concat = false
+			i++
+		case c == '"' || c == '\'':
+			var s strings.Builder
+
+			i++
+			for ; i < len(source) && source[i] != c; i++ {
+				if c == '"' && source[i] == '\\' && i+1 < len(source) {
+					i++
+					switch source[i] {
+					case 'n':
+						s.WriteByte('\n')
+					case 't':
+						s.WriteByte('\t')
+					default:
+						s.WriteByte(source[i])
+					}
+					continue
+				}
+				s.WriteByte(source[i])
+			}
+			if i == len(source) {
+				return nil, fmt.Errorf("unterminated quoted string in YANG module")
+			}
+			i++
+
+			if concat {
+				tokens[len(tokens)-1].text += s.String()
+				concat = false
+			} else {
+				tokens = append(tokens, yangToken{text: s.String(), quoted: true})
+			}
+		case c == '+' && len(tokens) > 0 && tokens[len(tokens)-1].quoted:
+			concat = true
+			i++
+		default:
+			start := i
+			for i < len(source) && !strings.ContainsRune(" \t\n\r{};", rune(source[i])) {
+				i++
+			}
+			tokens = append(tokens, yangToken{text: source[start:i]})
+			concat = false
+		}
+	}
+
+	return tokens, nil
+}
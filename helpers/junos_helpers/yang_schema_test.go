@@ -0,0 +1,193 @@
+package junos_helpers
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testSystemModule = `module test-system {
+  yang-version 1.1;
+  namespace "urn:example:system";
+  prefix sys;
+
+  import test-types { prefix tt; }
+
+  /* Login accounts */
+  grouping user-entry {
+    leaf name { type string; }
+    leaf class {
+      type string;
+      description "The login class, e.g. " +
+                  'super-user';
+    }
+    uses tt:authentication;
+  }
+
+  container system {
+    leaf host-name { type string; }
+    list user {
+      key "name";
+      uses user-entry;
+    }
+    choice time-source {
+      case ntp {
+        container ntp { leaf-list server { type string; } }
+      }
+      leaf manual-time { type string; }
+    }
+  }
+}`
+
+const testTypesModule = `module test-types {
+  namespace "urn:example:types";
+  prefix tt;
+
+  grouping authentication {
+    container authentication {
+      leaf encrypted-password { type string; } // Hashed
+      anydata ssh-keys;
+    }
+  }
+}`
+
+const testSyslogModule = `module test-syslog {
+  namespace "urn:example:syslog";
+  prefix log;
+
+  import test-system { prefix sys; }
+
+  augment "/sys:system/sys:time-source/sys:ntp/sys:ntp" {
+    leaf boot-server { type string; }
+  }
+
+  augment "/sys:system" {
+    container syslog { leaf file { type string; } }
+  }
+}`
+
+const schemaReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<data xmlns="urn:ietf:params:xml:ns:yang:ietf-netconf-monitoring">module test-types {
+  namespace &quot;urn:example:types&quot;;
+  prefix tt;
+}</data>
+</rpc-reply>`
+
+func TestGetSchema(t *testing.T) {
+	g, fd := newTestClient(schemaReply)
+
+	schema, err := g.GetSchema("test-types", "2020-01-01")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.HasPrefix(schema, "module test-types {") || !strings.Contains(schema, `namespace "urn:example:types";`) {
+		t.Errorf("got schema %q, expected the unescaped module", schema)
+	}
+
+	for _, expected := range []string{"<identifier>test-types</identifier>", "<version>2020-01-01</version>", "<format>yang</format>"} {
+		if !strings.Contains(fd.Sent[0], expected) {
+			t.Errorf("got RPC %q, expected it to contain %s", fd.Sent[0], expected)
+		}
+	}
+}
+
+func TestSchemaValidator(t *testing.T) {
+	v, err := NewSchemaValidator(testSystemModule, testTypesModule, testSyslogModule)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tt := []struct {
+		name   string
+		config string
+		errors []string // Paths of the expected errors
+	}{
+		{
+			name: "valid",
+			config: `<system xmlns="urn:example:system">
+  <host-name>r1</host-name>
+  <user><name>admin</name><class>super-user</class>
+    <authentication xmlns="urn:example:system"><encrypted-password>$6$x</encrypted-password><ssh-keys><key>a</key></ssh-keys></authentication>
+  </user>
+  <ntp><server>192.0.2.1</server><boot-server xmlns="urn:example:syslog">192.0.2.2</boot-server></ntp>
+  <syslog xmlns="urn:example:syslog"><file>messages</file></syslog>
+</system>`,
+		},
+		{
+			name:   "valid without namespaces",
+			config: `<config><system><host-name>r1</host-name><manual-time>12:00</manual-time></system></config>`,
+		},
+		{
+			name:   "unknown element",
+			config: `<system><host-name>r1</host-name><hostname>r1</hostname></system>`,
+			errors: []string{"/system/hostname"},
+		},
+		{
+			name:   "unknown top level element",
+			config: `<interfaces/>`,
+			errors: []string{"/interfaces"},
+		},
+		{
+			name:   "wrong namespace",
+			config: `<system xmlns="urn:example:system"><syslog><file>messages</file></syslog></system>`,
+			errors: []string{"/system/syslog"},
+		},
+		{
+			name:   "leaf with elements",
+			config: `<system><host-name><name>r1</name></host-name></system>`,
+			errors: []string{"/system/host-name"},
+		},
+		{
+			name:   "missing key",
+			config: `<system><user><class>super-user</class></user><user><name>ops</name><clas>read-only</clas></user></system>`,
+			errors: []string{"/system/user", "/system/user/clas"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := v.Validate(tc.config)
+			if len(tc.errors) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var errs SchemaErrors
+			if !errors.As(err, &errs) {
+				t.Fatalf("got error %v, expected SchemaErrors", err)
+			}
+
+			var paths []string
+			for _, e := range errs {
+				paths = append(paths, e.Path)
+			}
+			if strings.Join(paths, ",") != strings.Join(tc.errors, ",") {
+				t.Errorf("got errors %v, expected errors at %q", err, tc.errors)
+			}
+		})
+	}
+}
+
+func TestNewSchemaValidatorErrors(t *testing.T) {
+	tt := []struct {
+		name    string
+		modules []string
+	}{
+		{name: "unbalanced", modules: []string{`module m { namespace "urn:m"; prefix m; container c {`}},
+		{name: "missing import", modules: []string{testSystemModule}},
+		{name: "unknown grouping", modules: []string{`module m { namespace "urn:m"; prefix m; container c { uses nope; } }`}},
+		{name: "recursive grouping", modules: []string{`module m { namespace "urn:m"; prefix m; grouping g { container c { uses g; } } uses g; }`}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewSchemaValidator(tc.modules...)
+			if err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
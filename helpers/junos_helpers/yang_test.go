@@ -0,0 +1,104 @@
+package junos_helpers
+
+import (
+	"errors"
+	"testing"
+)
+
+const modulesStateReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<data>
+<modules-state xmlns="urn:ietf:params:xml:ns:yang:ietf-yang-library">
+<module-set-id>a1b2c3</module-set-id>
+<module>
+<name>ietf-interfaces</name>
+<revision>2014-05-08</revision>
+<namespace>urn:ietf:params:xml:ns:yang:ietf-interfaces</namespace>
+<feature>arbitrary-names</feature>
+<feature>pre-provisioning</feature>
+<deviation>
+<name>junos-ietf-interfaces-deviations</name>
+<revision>2019-01-01</revision>
+</deviation>
+<conformance-type>implement</conformance-type>
+</module>
+<module>
+<name>ietf-yang-types</name>
+<revision>2013-07-15</revision>
+<namespace>urn:ietf:params:xml:ns:yang:ietf-yang-types</namespace>
+<conformance-type>import</conformance-type>
+</module>
+</modules-state>
+</data>
+</rpc-reply>`
+
+const moduleSetIDReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<data>
+<modules-state xmlns="urn:ietf:params:xml:ns:yang:ietf-yang-library">
+<module-set-id>a1b2c3</module-set-id>
+</modules-state>
+</data>
+</rpc-reply>`
+
+func TestGetYANGLibrary(t *testing.T) {
+	g, fd := newTestClient(modulesStateReply, moduleSetIDReply)
+
+	library, err := g.GetYANGLibrary()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if library.ModuleSetID != "a1b2c3" || len(library.Modules) != 2 {
+		t.Fatalf("got module-set-id %q with %d modules, expected a1b2c3 with 2", library.ModuleSetID, len(library.Modules))
+	}
+
+	m := library.Module("ietf-interfaces")
+	if m == nil {
+		t.Fatal("ietf-interfaces not found")
+	}
+	if m.Revision != "2014-05-08" || len(m.Features) != 2 || m.Features[1] != "pre-provisioning" {
+		t.Errorf("got %+v, expected revision 2014-05-08 with two features", m)
+	}
+	if len(m.Deviations) != 1 || m.Deviations[0].Name != "junos-ietf-interfaces-deviations" {
+		t.Errorf("got deviations %+v, expected junos-ietf-interfaces-deviations", m.Deviations)
+	}
+
+	// The module-set-id is unchanged, so the second call must only check it
+	cached, err := g.GetYANGLibrary()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cached != library {
+		t.Error("expected the cached library to be returned")
+	}
+
+	if len(fd.Sent) != 2 || fd.Sent[1] != getModuleSetIDStr {
+		t.Errorf("got RPCs %q, expected the full fetch followed by a module-set-id check", fd.Sent)
+	}
+}
+
+func TestGetYANGLibraryChanged(t *testing.T) {
+	g, fd := newTestClient(modulesStateReply, `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<data><modules-state xmlns="urn:ietf:params:xml:ns:yang:ietf-yang-library"><module-set-id>d4e5f6</module-set-id></modules-state></data>
+</rpc-reply>`, modulesStateReply)
+
+	for i := 0; i < 2; i++ {
+		_, err := g.GetYANGLibrary()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if len(fd.Sent) != 3 || fd.Sent[2] != getModulesStateStr {
+		t.Errorf("got RPCs %q, expected a refetch after the module-set-id changed", fd.Sent)
+	}
+}
+
+func TestGetYANGLibraryUnsupported(t *testing.T) {
+	g, _ := newTestClient(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><data/></rpc-reply>`)
+
+	_, err := g.GetYANGLibrary()
+	if !errors.Is(err, ErrNoYANGLibrary) {
+		t.Errorf("got error %v, expected %v", err, ErrNoYANGLibrary)
+	}
+}
@@ -0,0 +1,84 @@
+package helpers
+
+import (
+	"sync"
+
+	driver "github.com/davedotdev/go-netconf/drivers/driver"
+)
+
+// Session holds the driver of a vendor client along with the session opened by Dial. The vendor
+// packages embed it, so their clients share one implementation of Dial and Close.
+type Session struct {
+	Driver driver.Driver
+	Lock   sync.RWMutex
+
+	sessionOpen bool // A session opened by Dial is held until Close
+}
+
+// Dial opens a session that every operation reuses until Close is called.
+// Without it each operation dials and closes a session of its own.
+func (s *Session) Dial() error {
+	s.Lock.Lock()
+	defer s.Lock.Unlock()
+
+	if s.Driver == nil {
+		return ErrSessionClosed
+	}
+
+	if s.sessionOpen {
+		return nil
+	}
+
+	err := s.Driver.Dial()
+	if err != nil {
+		return err
+	}
+
+	s.sessionOpen = true
+
+	return nil
+}
+
+// Close is a functional thing to close the Driver, ending any session opened by Dial.
+// It waits for in-flight operations to finish and may be called more than once, operations
+// started after it return ErrSessionClosed.
+func (s *Session) Close() error {
+	s.Lock.Lock()
+	defer s.Lock.Unlock()
+
+	if s.Driver == nil {
+		return nil
+	}
+
+	var err error
+	if s.sessionOpen {
+		err = s.Driver.Close()
+		s.sessionOpen = false
+	}
+
+	s.Driver = nil
+	return err
+}
+
+// Begin opens a session for a single operation, unless one is held open by Dial. The caller holds Lock
+// and pairs it with End.
+func (s *Session) Begin() error {
+	if s.Driver == nil {
+		return ErrSessionClosed
+	}
+
+	if s.sessionOpen {
+		return nil
+	}
+
+	return s.Driver.Dial()
+}
+
+// End ends the session of a single operation, leaving a session opened by Dial untouched
+func (s *Session) End() error {
+	if s.sessionOpen {
+		return nil
+	}
+
+	return s.Driver.Close()
+}
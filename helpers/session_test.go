@@ -0,0 +1,65 @@
+package helpers
+
+import (
+	"errors"
+	"testing"
+
+	testdriver "github.com/davedotdev/go-netconf/helpers/internal/testdriver"
+)
+
+func TestSession(t *testing.T) {
+	fd := testdriver.New()
+	s := &Session{Driver: fd}
+
+	// Without Dial every operation has a session of its own
+	for i := 0; i < 2; i++ {
+		if err := s.Begin(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := s.End(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if fd.Dials != 2 || fd.Closes != 2 {
+		t.Errorf("got %d dials and %d closes, expected 2 of each", fd.Dials, fd.Closes)
+	}
+
+	// Dial holds a single session open until Close
+	fd = testdriver.New()
+	s = &Session{Driver: fd}
+
+	if err := s.Dial(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.Dial(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		s.Begin()
+		s.End()
+	}
+
+	if fd.Dials != 1 || fd.Closes != 0 {
+		t.Errorf("got %d dials and %d closes, expected a single dial held open", fd.Dials, fd.Closes)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("got error %v closing twice, expected none", err)
+	}
+
+	if fd.Closes != 1 {
+		t.Errorf("got %d closes, expected 1", fd.Closes)
+	}
+
+	if err := s.Begin(); !errors.Is(err, ErrSessionClosed) {
+		t.Errorf("got error %v, expected %v", err, ErrSessionClosed)
+	}
+	if err := s.Dial(); !errors.Is(err, ErrSessionClosed) {
+		t.Errorf("got error %v, expected %v", err, ErrSessionClosed)
+	}
+}
@@ -0,0 +1,144 @@
+// Package sros_helpers wraps a driver with the RFC 6241 operations Nokia SR OS expects in model-driven mode.
+//
+// SR OS edits a single global candidate shared by every NETCONF and MD-CLI session. Each change is
+// therefore made under a lock on the candidate: the candidate is edited, validated and committed, and
+// discarded again if any step fails. Payloads are rooted at <configure> in the nokia-conf namespace.
+package sros_helpers
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	driver "github.com/davedotdev/go-netconf/drivers/driver"
+	helpers "github.com/davedotdev/go-netconf/helpers"
+	rpc "github.com/davedotdev/go-netconf/rpc"
+)
+
+// ConfNamespace is the namespace of the SR OS nokia-conf model rooted at <configure>
+const ConfNamespace = "urn:nokia.com:sros:ns:yang:sr:conf"
+
+const configureStr = `<configure xmlns="%s">%s</configure>`
+
+// GoNCClient satisfies the vendor neutral client interface
+var _ helpers.NCClient = (*GoNCClient)(nil)
+
+// GoNCClient type for storing data and wrapping functions
+type GoNCClient struct {
+	helpers.Session
+}
+
+// NewClient returns a client using the supplied driver, which must not be dialed yet. The client is a
+// *GoNCClient, for the SR OS specific methods.
+func NewClient(d driver.Driver) helpers.NCClient {
+	g := &GoNCClient{}
+	g.Driver = d
+
+	return g
+}
+
+// editCandidate locks the candidate, loads config into it and optionally validates and commits it.
+// The candidate is discarded if any step fails so other sessions do not inherit the change.
+func (g *GoNCClient) editCandidate(config string, commit bool) (string, error) {
+	g.Lock.Lock()
+	defer g.Lock.Unlock()
+
+	err := g.Begin()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = g.Driver.Lock("candidate")
+	if err != nil {
+		errInternal := g.End()
+		return "", fmt.Errorf("driver error: %+v, driver close error: %+v", err, errInternal)
+	}
+
+	reply, err := g.Driver.SendRaw(rpc.MethodEditConfig("candidate", config).MarshalMethod())
+	if err == nil && commit {
+		err = g.validateAndCommit()
+	}
+
+	if err != nil {
+		_, errDiscard := g.Driver.SendRaw(rpc.MethodDiscardChanges().MarshalMethod())
+		_, errUnlock := g.Driver.Unlock("candidate")
+		errInternal := g.End()
+		return "", fmt.Errorf("driver error: %+v, discard error: %+v, unlock error: %+v, driver close error: %+v", err, errDiscard, errUnlock, errInternal)
+	}
+
+	_, err = g.Driver.Unlock("candidate")
+	if err != nil {
+		errInternal := g.End()
+		return "", fmt.Errorf("driver error: %+v, driver close error: %+v", err, errInternal)
+	}
+
+	err = g.End()
+	if err != nil {
+		return "", fmt.Errorf("driver close error: %+s", err)
+	}
+
+	return reply.Data, nil
+}
+
+// validateAndCommit validates the candidate before committing it, so a rejected change is reported
+// by validate rather than by a failed commit
+func (g *GoNCClient) validateAndCommit() error {
+	_, err := g.Driver.SendRaw(rpc.MethodValidate("candidate").MarshalMethod())
+	if err != nil {
+		return err
+	}
+
+	_, err = g.Driver.SendRaw(rpc.MethodCommit().MarshalMethod())
+
+	return err
+}
+
+// SendRawConfig merges the YANG modelled netconfcall, rooted at <configure>, into the candidate with edit-config
+func (g *GoNCClient) SendRawConfig(netconfcall string, commit bool) (string, error) {
+	return g.editCandidate(netconfcall, commit)
+}
+
+// SendConfigure wraps config in <configure> with the nokia-conf namespace and merges it into the candidate
+func (g *GoNCClient) SendConfigure(config string, commit bool) (string, error) {
+	return g.editCandidate(fmt.Sprintf(configureStr, ConfNamespace, config), commit)
+}
+
+// SendTransaction marshals obj and merges it into the candidate.
+// SR OS has no apply-groups, so id is not used to scope the change.
+func (g *GoNCClient) SendTransaction(id string, obj interface{}, commit bool) error {
+	config, err := xml.Marshal(obj)
+	if err != nil {
+		return err
+	}
+
+	_, err = g.editCandidate(string(config), commit)
+
+	return err
+}
+
+// SendCommit validates and commits the candidate datastore under a lock
+func (g *GoNCClient) SendCommit() error {
+	g.Lock.Lock()
+	defer g.Lock.Unlock()
+
+	err := g.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = g.Driver.Lock("candidate")
+	if err == nil {
+		err = g.validateAndCommit()
+
+		_, errUnlock := g.Driver.Unlock("candidate")
+		if err == nil {
+			err = errUnlock
+		}
+	}
+
+	if err != nil {
+		errInternal := g.End()
+		return fmt.Errorf("driver error: %+v, driver close error: %+v", err, errInternal)
+	}
+
+	return g.End()
+}
@@ -0,0 +1,100 @@
+package sros_helpers
+
+import (
+	"reflect"
+	"testing"
+
+	testdriver "github.com/davedotdev/go-netconf/helpers/internal/testdriver"
+)
+
+func TestSendConfigure(t *testing.T) {
+	fd := testdriver.New()
+	g := NewClient(fd).(*GoNCClient)
+
+	_, err := g.SendConfigure(`<system><name>sr-1</name></system>`, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		`<lock><target><candidate/></target></lock>`,
+		`<edit-config><target><candidate/></target><config><configure xmlns="urn:nokia.com:sros:ns:yang:sr:conf"><system><name>sr-1</name></system></configure></config></edit-config>`,
+		`<validate><source><candidate/></source></validate>`,
+		`<commit/>`,
+		`<unlock><target><candidate/></target></unlock>`,
+	}
+	if !reflect.DeepEqual(fd.Sent, expected) {
+		t.Errorf("got RPCs %q, expected %q", fd.Sent, expected)
+	}
+}
+
+func TestSendRawConfigNoCommit(t *testing.T) {
+	fd := testdriver.New()
+	g := NewClient(fd).(*GoNCClient)
+
+	_, err := g.SendRawConfig(`<configure xmlns="urn:nokia.com:sros:ns:yang:sr:conf"/>`, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		`<lock><target><candidate/></target></lock>`,
+		`<edit-config><target><candidate/></target><config><configure xmlns="urn:nokia.com:sros:ns:yang:sr:conf"/></config></edit-config>`,
+		`<unlock><target><candidate/></target></unlock>`,
+	}
+	if !reflect.DeepEqual(fd.Sent, expected) {
+		t.Errorf("got RPCs %q, expected %q", fd.Sent, expected)
+	}
+}
+
+func TestSendConfigureValidateError(t *testing.T) {
+	fd := testdriver.New(testdriver.OKReply, testdriver.OKReply, `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<rpc-error>
+<error-type>application</error-type>
+<error-tag>operation-failed</error-tag>
+<error-severity>error</error-severity>
+<error-message>MINOR: MGMT_CORE #224: configure port 1/1/1 - Entry does not exist</error-message>
+</rpc-error>
+</rpc-reply>`)
+	g := NewClient(fd).(*GoNCClient)
+
+	_, err := g.SendConfigure(`<port><port-id>1/1/1</port-id></port>`, true)
+	if err == nil {
+		t.Fatal("expected an error from the rejected validate")
+	}
+
+	expected := []string{
+		`<lock><target><candidate/></target></lock>`,
+		`<edit-config><target><candidate/></target><config><configure xmlns="urn:nokia.com:sros:ns:yang:sr:conf"><port><port-id>1/1/1</port-id></port></configure></config></edit-config>`,
+		`<validate><source><candidate/></source></validate>`,
+		`<discard-changes/>`,
+		`<unlock><target><candidate/></target></unlock>`,
+	}
+	if !reflect.DeepEqual(fd.Sent, expected) {
+		t.Errorf("got RPCs %q, expected %q", fd.Sent, expected)
+	}
+
+	if fd.Closes != 1 {
+		t.Errorf("got %d closes, expected 1", fd.Closes)
+	}
+}
+
+func TestSendCommit(t *testing.T) {
+	fd := testdriver.New()
+	g := NewClient(fd).(*GoNCClient)
+
+	err := g.SendCommit()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		`<lock><target><candidate/></target></lock>`,
+		`<validate><source><candidate/></source></validate>`,
+		`<commit/>`,
+		`<unlock><target><candidate/></target></unlock>`,
+	}
+	if !reflect.DeepEqual(fd.Sent, expected) {
+		t.Errorf("got RPCs %q, expected %q", fd.Sent, expected)
+	}
+}
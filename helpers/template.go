@@ -0,0 +1,45 @@
+package helpers
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"text/template"
+)
+
+// templateFuncs are the functions RenderConfig makes available to templates
+var templateFuncs = template.FuncMap{
+	"xml": xmlEscapeValue,
+}
+
+// xmlEscapeValue formats v as text and escapes it for use in XML character data or an attribute value
+func xmlEscapeValue(v interface{}) (string, error) {
+	var buf bytes.Buffer
+
+	err := xml.EscapeText(&buf, []byte(fmt.Sprint(v)))
+	if err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// RenderConfig renders the text/template tmpl with data into a configuration ready for SendRawConfig, rather
+// than building it with fmt.Sprintf. text/template does not escape anything itself, so values are piped
+// through the registered xml function, e.g. <description>{{ .Description | xml }}</description>, which
+// escapes them for character data and attribute values alike.
+func RenderConfig(tmpl string, data interface{}) (string, error) {
+	t, err := template.New("config").Funcs(templateFuncs).Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid config template: %w", err)
+	}
+
+	var buf bytes.Buffer
+
+	err = t.Execute(&buf, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to render config template: %w", err)
+	}
+
+	return buf.String(), nil
+}
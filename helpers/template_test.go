@@ -0,0 +1,95 @@
+package helpers
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+const interfaceTemplate = `<configuration>
+<interfaces>
+{{- range .Interfaces }}
+<interface>
+<name>{{ .Name | xml }}</name>
+<description>{{ .Description | xml }}</description>
+<unit><name>{{ .Unit }}</name></unit>
+</interface>
+{{- end }}
+</interfaces>
+<system><login><message>{{ xml .Banner }}</message></login></system>
+</configuration>`
+
+func TestRenderConfig(t *testing.T) {
+	type iface struct {
+		Name        string
+		Description string
+		Unit        int
+	}
+
+	data := struct {
+		Interfaces []iface
+		Banner     string
+	}{
+		Interfaces: []iface{
+			{Name: "ge-0/0/0", Description: `uplink to "core" <r1> & r2`, Unit: 0},
+			{Name: "ge-0/0/1", Description: "customer's </description> link", Unit: 100},
+		},
+		Banner: "authorised use only\n& monitored",
+	}
+
+	config, err := RenderConfig(interfaceTemplate, data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The output must be well-formed XML carrying the values unchanged
+	d := xml.NewDecoder(strings.NewReader(config))
+	var descriptions []string
+	var inDescription bool
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("got malformed XML %q: %v", config, err)
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			inDescription = tok.Name.Local == "description"
+		case xml.CharData:
+			if inDescription {
+				descriptions = append(descriptions, string(tok))
+			}
+		case xml.EndElement:
+			inDescription = false
+		}
+	}
+
+	if len(descriptions) != 2 || descriptions[0] != data.Interfaces[0].Description || descriptions[1] != data.Interfaces[1].Description {
+		t.Errorf("got descriptions %q, expected the values unchanged", descriptions)
+	}
+}
+
+func TestRenderConfigErrors(t *testing.T) {
+	tt := []struct {
+		name string
+		tmpl string
+		data interface{}
+	}{
+		{name: "parse", tmpl: "<name>{{ .Name </name>"},
+		{name: "missingKey", tmpl: "<name>{{ .Name | xml }}</name>", data: map[string]string{}},
+		{name: "missingField", tmpl: "<name>{{ .Name | xml }}</name>", data: struct{}{}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := RenderConfig(tc.tmpl, tc.data)
+			if err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+// Package xmlnode parses XML, such as an RPC reply, into a generic tree that can be walked without
+// declaring structs for it
+package xmlnode
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ErrNoElement is returned when the parsed data holds no element
+var ErrNoElement = errors.New("no element in XML data")
+
+// Node is an XML element with its attributes, text and child elements
+type Node struct {
+	Name     xml.Name
+	Attrs    []xml.Attr
+	Text     string // Character data directly inside the element, including whitespace
+	Children []*Node
+}
+
+// Parse returns the first root element of data and all of its descendants
+func Parse(data string) (*Node, error) {
+	d := xml.NewDecoder(strings.NewReader(data))
+
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil, ErrNoElement
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if start, ok := tok.(xml.StartElement); ok {
+			return decode(d, start)
+		}
+	}
+}
+
+// Stream calls fn with each element named local in the XML read from r, in document order, building only
+// that element's tree so a large document is never held as a whole. Elements named local within a match
+// are part of its tree rather than passed on their own. An error returned by fn stops the stream.
+func Stream(r io.Reader, local string, fn func(*Node) error) error {
+	return StreamMatch(r, func(name xml.Name) bool { return name.Local == local }, fn)
+}
+
+// StreamMatch is Stream calling fn with each element whose name match accepts
+func StreamMatch(r io.Reader, match func(xml.Name) bool, fn func(*Node) error) error {
+	d := xml.NewDecoder(r)
+
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok || !match(start.Name) {
+			continue
+		}
+
+		n, err := decode(d, start)
+		if err != nil {
+			return err
+		}
+
+		err = fn(n)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// decode builds the tree of the element opened by start, reading d up to the element's end
+func decode(d *xml.Decoder, start xml.StartElement) (*Node, error) {
+	root := &Node{Name: start.Name, Attrs: start.Attr}
+	stack := []*Node{root}
+
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &Node{Name: t.Name, Attrs: t.Attr}
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, n)
+			stack = append(stack, n)
+		case xml.CharData:
+			stack[len(stack)-1].Text += string(t)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return root, nil
+			}
+		}
+	}
+}
+
+// Value returns the text of the element with surrounding whitespace removed
+func (n *Node) Value() string {
+	return strings.TrimSpace(n.Text)
+}
+
+// Attr returns the value of the attribute with the given local name, or "" if it is not set
+func (n *Node) Attr(local string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+
+	return ""
+}
+
+// Find returns the first element at path below n, or nil if there is none. The path is a list of local
+// element names separated by "/", e.g. "software-information/junos-version", where "*" matches any name.
+func (n *Node) Find(path string) *Node {
+	found := n.FindAll(path)
+	if len(found) == 0 {
+		return nil
+	}
+
+	return found[0]
+}
+
+// FindAll returns every element at path below n, in document order
+func (n *Node) FindAll(path string) []*Node {
+	nodes := []*Node{n}
+
+	for _, local := range strings.Split(strings.Trim(path, "/"), "/") {
+		var next []*Node
+		for _, node := range nodes {
+			for _, c := range node.Children {
+				if local == "*" || c.Name.Local == local {
+					next = append(next, c)
+				}
+			}
+		}
+		nodes = next
+	}
+
+	return nodes
+}
@@ -0,0 +1,119 @@
+package xmlnode
+
+import (
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const interfacesXML = `<interface-information xmlns="http://xml.juniper.net/junos/18.4R1/junos-interface">
+<physical-interface>
+<name>ge-0/0/0</name>
+<oper-status>up</oper-status>
+<logical-interface><name>ge-0/0/0.0</name></logical-interface>
+<logical-interface><name>ge-0/0/0.100</name></logical-interface>
+</physical-interface>
+<physical-interface junos:style="brief" xmlns:junos="http://xml.juniper.net/junos/*/junos">
+<name>ge-0/0/1</name>
+<oper-status>down</oper-status>
+</physical-interface>
+</interface-information>`
+
+func TestParse(t *testing.T) {
+	root, err := Parse(interfacesXML)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if root.Name.Local != "interface-information" {
+		t.Errorf("got root %s, expected interface-information", root.Name.Local)
+	}
+
+	if name := root.Find("physical-interface/name"); name == nil || name.Value() != "ge-0/0/0" {
+		t.Errorf("got %+v, expected the name of the first interface", name)
+	}
+
+	units := root.FindAll("physical-interface/logical-interface/name")
+	if len(units) != 2 || units[1].Value() != "ge-0/0/0.100" {
+		t.Errorf("got %d logical interfaces, expected 2", len(units))
+	}
+
+	if statuses := root.FindAll("*/oper-status"); len(statuses) != 2 || statuses[1].Value() != "down" {
+		t.Errorf("got %d statuses, expected 2 with a wildcard", len(statuses))
+	}
+
+	if style := root.FindAll("physical-interface")[1].Attr("style"); style != "brief" {
+		t.Errorf("got style %q, expected brief", style)
+	}
+
+	if root.Find("physical-interface/speed") != nil {
+		t.Error("expected no element at a missing path")
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	_, err := Parse("  ")
+	if err != ErrNoElement {
+		t.Errorf("got error %v, expected %v", err, ErrNoElement)
+	}
+
+	_, err = Parse("<unclosed>")
+	if err == nil {
+		t.Error("expected an error for malformed XML")
+	}
+}
+
+func TestStream(t *testing.T) {
+	var names []string
+
+	err := Stream(strings.NewReader(interfacesXML), "physical-interface", func(n *Node) error {
+		names = append(names, n.Find("name").Value())
+
+		if len(n.FindAll("logical-interface")) > 0 && n.Find("logical-interface/name") == nil {
+			t.Error("expected the whole tree of each match")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if strings.Join(names, ",") != "ge-0/0/0,ge-0/0/1" {
+		t.Errorf("got %q, expected both interfaces in document order", names)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+
+	err = Stream(strings.NewReader(interfacesXML), "logical-interface", func(n *Node) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Errorf("got error %v after %d calls, expected the callback's error to stop the stream", err, calls)
+	}
+
+	err = Stream(strings.NewReader("<a><b>"), "b", func(n *Node) error { return nil })
+	if err == nil {
+		t.Error("expected an error for a truncated match")
+	}
+}
+
+func TestStreamMatch(t *testing.T) {
+	var names []string
+
+	err := StreamMatch(strings.NewReader("<r><a/><b/><c/></r>"), func(name xml.Name) bool {
+		return name.Local != "r" && name.Local != "b"
+	}, func(n *Node) error {
+		names = append(names, n.Name.Local)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if strings.Join(names, ",") != "a,c" {
+		t.Errorf("got %q, expected the matched elements a and c", names)
+	}
+}
@@ -10,8 +10,13 @@ import (
 	"bytes"
 	"crypto/rand"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
 )
 
 // RPCMessage represents an RPC Message to be sent.
@@ -22,8 +27,14 @@ type RPCMessage struct {
 
 // NewRPCMessage generates a new RPC Message structure with the provided methods
 func NewRPCMessage(methods []RPCMethod) *RPCMessage {
+	return NewRPCMessageID(msgID(), methods)
+}
+
+// NewRPCMessageID generates a new RPC Message structure with the provided methods and message-id, e.g. one
+// embedding a trace id so device logs can be correlated with the automation that sent the RPC
+func NewRPCMessageID(messageID string, methods []RPCMethod) *RPCMessage {
 	return &RPCMessage{
-		MessageID: msgID(),
+		MessageID: messageID,
 		Methods:   methods,
 	}
 }
@@ -52,13 +63,18 @@ func (m *RPCMessage) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 
 // RPCReply defines a reply to a RPC request
 type RPCReply struct {
-	XMLName  xml.Name   `xml:"rpc-reply"`
-	Errors   []RPCError `xml:"rpc-error,omitempty"`
-	Data     string     `xml:",innerxml"`
-	Ok       bool       `xml:",omitempty"`
-	RawReply string     `xml:"-"`
+	XMLName   xml.Name   `xml:"rpc-reply"`
+	MessageID string     `xml:"message-id,attr"` // Echoed from the RPC, empty if the device left it out
+	Errors    []RPCError `xml:"rpc-error,omitempty"`
+	Data      string     `xml:",innerxml"`
+	Ok        bool       `xml:",omitempty"`
+	RawReply  string     `xml:"-"`
 }
 
+// ErrEmptyReply is returned when an rpc-reply carries no data, no <ok/> and no rpc-error, which
+// usually means a protocol problem rather than success
+var ErrEmptyReply = errors.New("empty or unrecognized rpc-reply")
+
 // NewRPCReply creates a new RPC Reply
 func NewRPCReply(rawXML []byte, ErrOnWarning bool) (*RPCReply, error) {
 	reply := &RPCReply{}
@@ -68,15 +84,70 @@ func NewRPCReply(rawXML []byte, ErrOnWarning bool) (*RPCReply, error) {
 		return nil, err
 	}
 
-	if reply.Errors != nil {
-		for _, rpcErr := range reply.Errors {
-			if rpcErr.Severity == "error" || ErrOnWarning {
-				return reply, &rpcErr
+	if !hasContent(reply.Data) {
+		return reply, ErrEmptyReply
+	}
+
+	var failed []RPCError
+	for _, rpcErr := range reply.Errors {
+		if rpcErr.Severity == "error" || ErrOnWarning {
+			failed = append(failed, rpcErr)
+		}
+	}
+
+	switch len(failed) {
+	case 0:
+		return reply, nil
+	case 1:
+		return reply, &failed[0]
+	default:
+		return reply, &RPCErrors{Errors: failed}
+	}
+}
+
+// hasContent reports whether data contains an element or any non-whitespace text, such as the bare
+// JSON Junos replies with. Malformed data is treated as content.
+func hasContent(data string) bool {
+	d := xml.NewDecoder(strings.NewReader(data))
+
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return false
+		}
+		if err != nil {
+			return true
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			return true
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) > 0 {
+				return true
 			}
 		}
 	}
+}
+
+// Notification defines an event notification received on a subscription (RFC 5277)
+type Notification struct {
+	XMLName   xml.Name `xml:"notification"`
+	EventTime string   `xml:"eventTime"`
+	Data      string   `xml:",innerxml"`
+	RawReply  string   `xml:"-"`
+}
+
+// NewNotification creates a new Notification
+func NewNotification(rawXML []byte) (*Notification, error) {
+	notification := &Notification{}
+	notification.RawReply = string(rawXML)
+
+	if err := xml.Unmarshal(rawXML, notification); err != nil {
+		return nil, err
+	}
 
-	return reply, nil
+	return notification, nil
 }
 
 // RPCError defines an error reply to a RPC request
@@ -94,6 +165,66 @@ func (re *RPCError) Error() string {
 	return fmt.Sprintf("netconf rpc [%s] '%s'", re.Severity, re.Message)
 }
 
+// RPCErrors is returned when a reply carries more than one rpc-error, e.g. several rejected config lines.
+// Each RPCError is in Errors, errors.As with a **RPCError recovers the first, FindRPCError searches them all.
+type RPCErrors struct {
+	Errors []RPCError
+}
+
+// Error generates a string representation of every RPC error in the reply
+func (re *RPCErrors) Error() string {
+	msgs := make([]string, len(re.Errors))
+	for i := range re.Errors {
+		msgs[i] = re.Errors[i].Error()
+	}
+
+	return fmt.Sprintf("%d netconf rpc errors: %s", len(re.Errors), strings.Join(msgs, "; "))
+}
+
+// Is reports whether any RPC error in the reply matches target, so errors.Is looks through RPCErrors
+func (re *RPCErrors) Is(target error) bool {
+	for i := range re.Errors {
+		if errors.Is(&re.Errors[i], target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// As sets target to the first RPC error in the reply assignable to it, so errors.As with a **RPCError
+// recovers the first error. Every error remains in Errors.
+func (re *RPCErrors) As(target interface{}) bool {
+	for i := range re.Errors {
+		if errors.As(&re.Errors[i], target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FindRPCError returns the first RPC error in err that match holds for, looking at every error of an
+// RPCErrors rather than the first errors.As recovers, or nil when there is none
+func FindRPCError(err error, match func(*RPCError) bool) *RPCError {
+	var rpcErrs *RPCErrors
+	if errors.As(err, &rpcErrs) {
+		for i := range rpcErrs.Errors {
+			if match(&rpcErrs.Errors[i]) {
+				return &rpcErrs.Errors[i]
+			}
+		}
+		return nil
+	}
+
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) && match(rpcErr) {
+		return rpcErr
+	}
+
+	return nil
+}
+
 // RPCMethod defines the interface for creating an RPC method.
 type RPCMethod interface {
 	MarshalMethod() string
@@ -107,22 +238,473 @@ func (r RawMethod) MarshalMethod() string {
 	return string(r)
 }
 
+// NamespacedMethod is a method whose operation element declares namespaces, e.g. those of the OpenConfig or
+// IETF models its filter or config uses, so they need not be repeated on every element inside it
+type NamespacedMethod struct {
+	Method     RPCMethod
+	Xmlns      string            // Default namespace of the operation element, inherited from <rpc> when empty
+	Namespaces map[string]string // Additional declarations, by prefix, emitted in prefix order
+}
+
+// MethodWithNamespaces declares the namespaces, by prefix, on the operation element of method
+func MethodWithNamespaces(method RPCMethod, namespaces map[string]string) NamespacedMethod {
+	return NamespacedMethod{Method: method, Namespaces: namespaces}
+}
+
+// MarshalMethod converts the method into its XML representation, adding the namespace declarations to the
+// first element
+func (n NamespacedMethod) MarshalMethod() string {
+	method := n.Method.MarshalMethod()
+
+	// The operation element's name ends at the first space, "/" or ">" after its "<"
+	start := strings.Index(method, "<")
+	if start == -1 {
+		return method
+	}
+	end := strings.IndexAny(method[start:], " \t\r\n/>")
+	if end == -1 {
+		return method
+	}
+	end += start
+
+	var buf bytes.Buffer
+
+	buf.WriteString(method[:end])
+
+	if n.Xmlns != "" {
+		buf.WriteString(` xmlns="`)
+		xml.EscapeText(&buf, []byte(n.Xmlns))
+		buf.WriteString(`"`)
+	}
+
+	prefixes := make([]string, 0, len(n.Namespaces))
+	for prefix := range n.Namespaces {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	for _, prefix := range prefixes {
+		buf.WriteString(fmt.Sprintf(` xmlns:%s="`, prefix))
+		xml.EscapeText(&buf, []byte(n.Namespaces[prefix]))
+		buf.WriteString(`"`)
+	}
+
+	buf.WriteString(method[end:])
+
+	return buf.String()
+}
+
+// Datastore names an RFC 6241 configuration datastore
+type Datastore string
+
+// Datastores defined by RFC 6241
+const (
+	DatastoreCandidate Datastore = "candidate" // Needs the :candidate capability
+	DatastoreRunning   Datastore = "running"
+	DatastoreStartup   Datastore = "startup" // Needs the :startup capability
+)
+
+// Validate returns an error unless d is one of the Datastore constants, catching a misspelt
+// datastore before it is sent to the device
+func (d Datastore) Validate() error {
+	switch d {
+	case DatastoreCandidate, DatastoreRunning, DatastoreStartup:
+		return nil
+	}
+
+	return fmt.Errorf("unknown datastore %q, expected candidate, running or startup", string(d))
+}
+
+// Element renders the datastore as the empty element naming it inside a target or source, e.g. <candidate/>
+func (d Datastore) Element() string {
+	return "<" + string(d) + "/>"
+}
+
 // MethodLock files a NETCONF lock target request with the remote host
 func MethodLock(target string) RawMethod {
-	return RawMethod(fmt.Sprintf("<lock><target><%s/></target></lock>", target))
+	return RawMethod("<lock><target>" + Datastore(target).Element() + "</target></lock>")
+}
+
+// MethodLockDatastore is MethodLock for a Datastore, returning an error for an unknown one instead of
+// rendering it
+func MethodLockDatastore(target Datastore) (RawMethod, error) {
+	err := target.Validate()
+	if err != nil {
+		return "", err
+	}
+
+	return MethodLock(string(target)), nil
 }
 
 // MethodUnlock files a NETCONF unlock target request with the remote host
 func MethodUnlock(target string) RawMethod {
-	return RawMethod(fmt.Sprintf("<unlock><target><%s/></target></unlock>", target))
+	return RawMethod("<unlock><target>" + Datastore(target).Element() + "</target></unlock>")
+}
+
+// MethodUnlockDatastore is MethodUnlock for a Datastore, returning an error for an unknown one instead of
+// rendering it
+func MethodUnlockDatastore(target Datastore) (RawMethod, error) {
+	err := target.Validate()
+	if err != nil {
+		return "", err
+	}
+
+	return MethodUnlock(string(target)), nil
 }
 
 // MethodGetConfig files a NETCONF get-config source request with the remote host
 func MethodGetConfig(source string) RawMethod {
-	return RawMethod(fmt.Sprintf("<get-config><source><%s/></source></get-config>", source))
+	return RawMethod("<get-config><source>" + Datastore(source).Element() + "</source></get-config>")
 }
 
-var msgID = uuid
+// MethodGetConfigDatastore is MethodGetConfig for a Datastore, returning an error for an unknown one instead
+// of rendering it
+func MethodGetConfigDatastore(source Datastore) (RawMethod, error) {
+	err := source.Validate()
+	if err != nil {
+		return "", err
+	}
+
+	return MethodGetConfig(string(source)), nil
+}
+
+// MethodGet files a NETCONF get request for configuration and state data with the remote host.
+// A filter starting with "<" is sent as a subtree filter, any other non-empty filter as an XPath
+// select expression (which requires the device to advertise the xpath capability).
+func MethodGet(filter string) RawMethod {
+	filter = strings.TrimSpace(filter)
+
+	if filter == "" {
+		return RawMethod("<get/>")
+	}
+
+	if strings.HasPrefix(filter, "<") {
+		return RawMethod(fmt.Sprintf(`<get><filter type="subtree">%s</filter></get>`, filter))
+	}
+
+	var buf bytes.Buffer
+
+	buf.WriteString(`<get><filter type="xpath" select="`)
+	xml.EscapeText(&buf, []byte(filter))
+	buf.WriteString(`"/></get>`)
+
+	return RawMethod(buf.String())
+}
+
+// MethodValidate files a NETCONF validate request for the source datastore with the remote host
+func MethodValidate(source string) RawMethod {
+	return RawMethod("<validate><source>" + Datastore(source).Element() + "</source></validate>")
+}
+
+// MethodValidateDatastore is MethodValidate for a Datastore, returning an error for an unknown one instead of
+// rendering it
+func MethodValidateDatastore(source Datastore) (RawMethod, error) {
+	err := source.Validate()
+	if err != nil {
+		return "", err
+	}
+
+	return MethodValidate(string(source)), nil
+}
+
+// MethodCommit files a NETCONF commit request with the remote host
+func MethodCommit() RawMethod {
+	return RawMethod("<commit/>")
+}
+
+// MethodConfirmedCommit files a NETCONF confirmed commit request with the remote host, rolled back unless
+// confirmed within timeoutSeconds (the device default of 600 when zero). A non-empty persist token keeps the
+// confirmed commit alive past the end of the session, so another session can confirm or cancel it.
+func MethodConfirmedCommit(timeoutSeconds uint32, persist string) RawMethod {
+	var buf bytes.Buffer
+
+	buf.WriteString("<commit><confirmed/>")
+
+	if timeoutSeconds > 0 {
+		buf.WriteString(fmt.Sprintf("<confirm-timeout>%d</confirm-timeout>", timeoutSeconds))
+	}
+
+	if persist != "" {
+		buf.WriteString("<persist>")
+		xml.EscapeText(&buf, []byte(persist))
+		buf.WriteString("</persist>")
+	}
+
+	buf.WriteString("</commit>")
+
+	return RawMethod(buf.String())
+}
+
+// MethodConfirmCommit files a NETCONF commit request confirming a pending confirmed commit.
+// A non-empty persistID confirms a persistent confirmed commit made by another session.
+func MethodConfirmCommit(persistID string) RawMethod {
+	if persistID == "" {
+		return MethodCommit()
+	}
+
+	var buf bytes.Buffer
+
+	buf.WriteString("<commit><persist-id>")
+	xml.EscapeText(&buf, []byte(persistID))
+	buf.WriteString("</persist-id></commit>")
+
+	return RawMethod(buf.String())
+}
+
+// MethodCancelCommit files a NETCONF cancel-commit request with the remote host, rolling back a pending confirmed commit.
+// A non-empty persistID cancels a persistent confirmed commit made by another session.
+func MethodCancelCommit(persistID string) RawMethod {
+	if persistID == "" {
+		return RawMethod("<cancel-commit/>")
+	}
+
+	var buf bytes.Buffer
+
+	buf.WriteString("<cancel-commit><persist-id>")
+	xml.EscapeText(&buf, []byte(persistID))
+	buf.WriteString("</persist-id></cancel-commit>")
+
+	return RawMethod(buf.String())
+}
+
+// MethodDiscardChanges files a NETCONF discard-changes request with the remote host
+func MethodDiscardChanges() RawMethod {
+	return RawMethod("<discard-changes/>")
+}
+
+// MethodCloseSession files a NETCONF close-session request, gracefully ending the session
+func MethodCloseSession() RawMethod {
+	return RawMethod("<close-session/>")
+}
+
+// MethodCreateSubscription files a RFC 5277 create-subscription request for stream with the remote host.
+// A non-empty startTime (RFC 3339) asks the device to replay the events logged since then.
+func MethodCreateSubscription(stream string, startTime string) RawMethod {
+	var buf bytes.Buffer
+
+	buf.WriteString(`<create-subscription xmlns="urn:ietf:params:xml:ns:netconf:notification:1.0">`)
+	buf.WriteString("<stream>")
+	xml.EscapeText(&buf, []byte(stream))
+	buf.WriteString("</stream>")
+
+	if startTime != "" {
+		buf.WriteString("<startTime>")
+		xml.EscapeText(&buf, []byte(startTime))
+		buf.WriteString("</startTime>")
+	}
+
+	buf.WriteString("</create-subscription>")
+
+	return RawMethod(buf.String())
+}
+
+// MethodPartialLock files a RFC 5717 partial-lock request for the nodes matched by the XPath selects
+func MethodPartialLock(selects []string) RawMethod {
+	var buf bytes.Buffer
+
+	buf.WriteString(`<partial-lock xmlns="urn:ietf:params:xml:ns:netconf:partial-lock:1.0">`)
+	for _, s := range selects {
+		buf.WriteString("<select>")
+		xml.EscapeText(&buf, []byte(s))
+		buf.WriteString("</select>")
+	}
+	buf.WriteString("</partial-lock>")
+
+	return RawMethod(buf.String())
+}
+
+// MethodPartialUnlock files a RFC 5717 partial-unlock request releasing lockID
+func MethodPartialUnlock(lockID uint32) RawMethod {
+	return RawMethod(fmt.Sprintf(`<partial-unlock xmlns="urn:ietf:params:xml:ns:netconf:partial-lock:1.0"><lock-id>%d</lock-id></partial-unlock>`, lockID))
+}
+
+// Values of the edit-config error-option, controlling what the device does when part of an edit fails
+const (
+	ErrorOptionStopOnError     = "stop-on-error"     // Abort at the first error, keeping what was applied so far
+	ErrorOptionContinueOnError = "continue-on-error" // Apply what can be applied, reporting every error
+	ErrorOptionRollbackOnError = "rollback-on-error" // Undo the whole edit on any error, needs :rollback-on-error
+)
+
+// CapabilityRollbackOnError is advertised by devices supporting ErrorOptionRollbackOnError
+const CapabilityRollbackOnError = "urn:ietf:params:netconf:capability:rollback-on-error:1.0"
+
+// CheckErrorOption returns an error unless a device advertising capabilities accepts the error-option option.
+// An empty option is always accepted.
+func CheckErrorOption(option string, capabilities []string) error {
+	err := validateErrorOption(option)
+	if err != nil {
+		return err
+	}
+
+	if option == ErrorOptionRollbackOnError && !advertises(capabilities, CapabilityRollbackOnError) {
+		return fmt.Errorf("error-option %s requires the %s capability", option, CapabilityRollbackOnError)
+	}
+
+	return nil
+}
+
+// validateErrorOption returns an error unless option is empty or one of the ErrorOption values
+func validateErrorOption(option string) error {
+	switch option {
+	case "", ErrorOptionStopOnError, ErrorOptionContinueOnError, ErrorOptionRollbackOnError:
+		return nil
+	}
+
+	return fmt.Errorf("unknown error-option %q, expected stop-on-error, continue-on-error or rollback-on-error", option)
+}
+
+// Values of the edit-config test-option, controlling whether the device validates an edit before applying it
+const (
+	TestOptionTestThenSet = "test-then-set" // Validate the edit and only apply it if valid, needs :validate
+	TestOptionSet         = "set"           // Apply the edit without validating it, needs :validate
+	TestOptionTestOnly    = "test-only"     // Validate the edit without applying it, needs :validate:1.1
+)
+
+// Capabilities advertised by devices supporting the edit-config test-option
+const (
+	CapabilityValidate10 = "urn:ietf:params:netconf:capability:validate:1.0"
+	CapabilityValidate11 = "urn:ietf:params:netconf:capability:validate:1.1"
+)
+
+// CheckTestOption returns an error unless a device advertising capabilities accepts the test-option option.
+// An empty option is always accepted.
+func CheckTestOption(option string, capabilities []string) error {
+	required, err := testOptionCapabilities(option)
+	if err != nil || required == nil {
+		return err
+	}
+
+	for _, r := range required {
+		if advertises(capabilities, r) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("test-option %s requires the %s capability", option, required[len(required)-1])
+}
+
+// testOptionCapabilities returns the capabilities of which a device must advertise one to accept the
+// test-option option, nil for an empty option, or an error unless option is one of the TestOption values
+func testOptionCapabilities(option string) ([]string, error) {
+	switch option {
+	case "":
+		return nil, nil
+	case TestOptionTestThenSet, TestOptionSet:
+		return []string{CapabilityValidate10, CapabilityValidate11}, nil
+	case TestOptionTestOnly:
+		return []string{CapabilityValidate11}, nil
+	}
+
+	return nil, fmt.Errorf("unknown test-option %q, expected test-then-set, set or test-only", option)
+}
+
+// advertises reports whether capabilities holds uri
+func advertises(capabilities []string, uri string) bool {
+	for _, c := range capabilities {
+		// Capabilities may carry parameters after a ?
+		if c == uri || strings.HasPrefix(c, uri+"?") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// EditConfig defines an RFC 6241 edit-config request
+type EditConfig struct {
+	Target           Datastore // Datastore to edit, e.g. DatastoreCandidate or DatastoreRunning
+	DefaultOperation string    // merge, replace or none. Omitted when empty
+	TestOption       string    // One of the TestOption values, see CheckTestOption. Omitted when empty
+	ErrorOption      string    // One of the ErrorOption values. Omitted when empty, the device then stops on error
+	Config           string    // Content of the <config> element
+	URL              string    // Location the device fetches the configuration from, sent in place of Config when set
+}
+
+// Validate returns an error unless the target, default-operation, test-option and error-option of the
+// edit-config are values the RFC defines. Whether the device supports them is left to CheckTestOption and
+// CheckErrorOption.
+func (e EditConfig) Validate() error {
+	err := e.Target.Validate()
+	if err != nil {
+		return err
+	}
+
+	switch e.DefaultOperation {
+	case "", "merge", "replace", "none":
+	default:
+		return fmt.Errorf("unknown default-operation %q, expected merge, replace or none", e.DefaultOperation)
+	}
+
+	_, err = testOptionCapabilities(e.TestOption)
+	if err != nil {
+		return err
+	}
+
+	return validateErrorOption(e.ErrorOption)
+}
+
+// MarshalMethod converts the edit-config into its XML representation. It renders the fields as they are,
+// callers building an EditConfig themselves call Validate first.
+func (e EditConfig) MarshalMethod() string {
+	var buf bytes.Buffer
+
+	buf.WriteString("<edit-config><target>" + e.Target.Element() + "</target>")
+
+	if e.DefaultOperation != "" {
+		buf.WriteString(fmt.Sprintf("<default-operation>%s</default-operation>", e.DefaultOperation))
+	}
+
+	if e.TestOption != "" {
+		buf.WriteString(fmt.Sprintf("<test-option>%s</test-option>", e.TestOption))
+	}
+
+	if e.ErrorOption != "" {
+		buf.WriteString(fmt.Sprintf("<error-option>%s</error-option>", e.ErrorOption))
+	}
+
+	if e.URL != "" {
+		buf.WriteString("<url>")
+		xml.EscapeText(&buf, []byte(e.URL))
+		buf.WriteString("</url></edit-config>")
+	} else {
+		buf.WriteString(fmt.Sprintf("<config>%s</config></edit-config>", e.Config))
+	}
+
+	return buf.String()
+}
+
+// MethodEditConfig files a NETCONF edit-config request merging config into the target
+func MethodEditConfig(target string, config string) EditConfig {
+	return EditConfig{Target: Datastore(target), Config: config}
+}
+
+// MethodEditConfigDatastore is MethodEditConfig for a Datastore, returning an error for an unknown one
+func MethodEditConfigDatastore(target Datastore, config string) (EditConfig, error) {
+	e := EditConfig{Target: target, Config: config}
+
+	err := e.Validate()
+	if err != nil {
+		return EditConfig{}, err
+	}
+
+	return e, nil
+}
+
+var msgID = NextMessageID
+
+// lastMessageID is the message-id NextMessageID last returned
+var lastMessageID uint64
+
+// NextMessageID returns the next of a sequence of message-ids counting up from 1, shared by every session.
+// It is the default message-id scheme.
+func NextMessageID() string {
+	return strconv.FormatUint(atomic.AddUint64(&lastMessageID, 1), 10)
+}
+
+// RandomMessageID returns a random UUID as the message-id, unique across processes
+func RandomMessageID() string {
+	return uuid()
+}
 
 // uuid generates a "good enough" uuid without adding external dependencies
 func uuid() string {
@@ -9,6 +9,9 @@ package netconf
 import (
 	"bytes"
 	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -76,6 +79,80 @@ func TestRPCErrorError(t *testing.T) {
 	}
 }
 
+func TestDatastore(t *testing.T) {
+	tt := []struct {
+		datastore Datastore
+		expected  string
+	}{
+		{datastore: DatastoreCandidate, expected: "<candidate/>"},
+		{datastore: DatastoreRunning, expected: "<running/>"},
+		{datastore: DatastoreStartup, expected: "<startup/>"},
+	}
+
+	for _, tc := range tt {
+		err := tc.datastore.Validate()
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.datastore, err)
+		}
+
+		if tc.datastore.Element() != tc.expected {
+			t.Errorf("got %s, expected %s", tc.datastore.Element(), tc.expected)
+		}
+	}
+
+	for _, ds := range []Datastore{"", "candiate", "Running", "<running/>"} {
+		if ds.Validate() == nil {
+			t.Errorf("%q: expected an error", string(ds))
+		}
+	}
+}
+
+func TestMethodDatastore(t *testing.T) {
+	tt := []struct {
+		name     string
+		build    func(Datastore) (RawMethod, error)
+		expected string
+	}{
+		{"lock", MethodLockDatastore, "<lock><target><candidate/></target></lock>"},
+		{"unlock", MethodUnlockDatastore, "<unlock><target><candidate/></target></unlock>"},
+		{"get-config", MethodGetConfigDatastore, "<get-config><source><candidate/></source></get-config>"},
+		{"validate", MethodValidateDatastore, "<validate><source><candidate/></source></validate>"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := tc.build(DatastoreCandidate)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if m.MarshalMethod() != tc.expected {
+				t.Errorf("got %s, expected %s", m, tc.expected)
+			}
+
+			_, err = tc.build("candiate")
+			if err == nil {
+				t.Error("expected an error for the misspelt datastore")
+			}
+		})
+	}
+
+	e, err := MethodEditConfigDatastore(DatastoreRunning, "<system/>")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "<edit-config><target><running/></target><config><system/></config></edit-config>"
+	if e.MarshalMethod() != expected {
+		t.Errorf("got %s, expected %s", e.MarshalMethod(), expected)
+	}
+
+	_, err = MethodEditConfigDatastore("runing", "<system/>")
+	if err == nil {
+		t.Error("expected an error for the misspelt datastore")
+	}
+}
+
 func TestMethodLock(t *testing.T) {
 	expected := "<lock><target><what.target/></target></lock>"
 
@@ -103,7 +180,419 @@ func TestMethodGetConfig(t *testing.T) {
 	}
 }
 
+func TestMethodGet(t *testing.T) {
+	tt := []struct {
+		name     string
+		filter   string
+		expected string
+	}{
+		{"unfiltered", "", "<get/>"},
+		{"subtree", "<interfaces/>", `<get><filter type="subtree"><interfaces/></filter></get>`},
+		{"xpath", `/interfaces/interface[name="eth0"]`, `<get><filter type="xpath" select="/interfaces/interface[name=&#34;eth0&#34;]"/></get>`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			mGet := MethodGet(tc.filter)
+			if mGet.MarshalMethod() != tc.expected {
+				t.Errorf("got %s, expected %s", mGet, tc.expected)
+			}
+		})
+	}
+}
+
+func TestMethodValidate(t *testing.T) {
+	expected := "<validate><source><candidate/></source></validate>"
+
+	mValidate := MethodValidate("candidate")
+	if mValidate.MarshalMethod() != expected {
+		t.Errorf("got %s, expected %s", mValidate, expected)
+	}
+}
+
+func TestMethodCommit(t *testing.T) {
+	expected := "<commit/>"
+
+	mCommit := MethodCommit()
+	if mCommit.MarshalMethod() != expected {
+		t.Errorf("got %s, expected %s", mCommit, expected)
+	}
+}
+
+func TestMethodPartialLock(t *testing.T) {
+	expected := `<partial-lock xmlns="urn:ietf:params:xml:ns:netconf:partial-lock:1.0">` +
+		`<select>/configuration/interfaces/interface[name=&#39;ge-0/0/0&#39;]</select>` +
+		`<select>/configuration/system</select>` +
+		`</partial-lock>`
+
+	mPartialLock := MethodPartialLock([]string{"/configuration/interfaces/interface[name='ge-0/0/0']", "/configuration/system"})
+	if mPartialLock.MarshalMethod() != expected {
+		t.Errorf("got %s, expected %s", mPartialLock, expected)
+	}
+}
+
+func TestMethodPartialUnlock(t *testing.T) {
+	expected := `<partial-unlock xmlns="urn:ietf:params:xml:ns:netconf:partial-lock:1.0"><lock-id>127</lock-id></partial-unlock>`
+
+	mPartialUnlock := MethodPartialUnlock(127)
+	if mPartialUnlock.MarshalMethod() != expected {
+		t.Errorf("got %s, expected %s", mPartialUnlock, expected)
+	}
+}
+
+func TestMethodConfirmedCommit(t *testing.T) {
+	tt := []struct {
+		name     string
+		timeout  uint32
+		persist  string
+		expected string
+	}{
+		{name: "default", expected: "<commit><confirmed/></commit>"},
+		{name: "timeout", timeout: 300, expected: "<commit><confirmed/><confirm-timeout>300</confirm-timeout></commit>"},
+		{name: "persist", timeout: 300, persist: "change-42", expected: "<commit><confirmed/><confirm-timeout>300</confirm-timeout><persist>change-42</persist></commit>"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			mConfirmedCommit := MethodConfirmedCommit(tc.timeout, tc.persist)
+			if mConfirmedCommit.MarshalMethod() != tc.expected {
+				t.Errorf("got %s, expected %s", mConfirmedCommit, tc.expected)
+			}
+		})
+	}
+}
+
+func TestMethodConfirmCommit(t *testing.T) {
+	tt := []struct {
+		name      string
+		persistID string
+		expected  string
+	}{
+		{name: "session", expected: "<commit/>"},
+		{name: "persist", persistID: "change-42", expected: "<commit><persist-id>change-42</persist-id></commit>"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			mConfirmCommit := MethodConfirmCommit(tc.persistID)
+			if mConfirmCommit.MarshalMethod() != tc.expected {
+				t.Errorf("got %s, expected %s", mConfirmCommit, tc.expected)
+			}
+		})
+	}
+}
+
+func TestMethodCancelCommit(t *testing.T) {
+	tt := []struct {
+		name      string
+		persistID string
+		expected  string
+	}{
+		{name: "session", expected: "<cancel-commit/>"},
+		{name: "persist", persistID: "change-42", expected: "<cancel-commit><persist-id>change-42</persist-id></cancel-commit>"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			mCancelCommit := MethodCancelCommit(tc.persistID)
+			if mCancelCommit.MarshalMethod() != tc.expected {
+				t.Errorf("got %s, expected %s", mCancelCommit, tc.expected)
+			}
+		})
+	}
+}
+
+func TestMethodEditConfig(t *testing.T) {
+	tt := []struct {
+		name     string
+		method   EditConfig
+		expected string
+	}{
+		{
+			name:     "merge",
+			method:   MethodEditConfig("candidate", "<system/>"),
+			expected: "<edit-config><target><candidate/></target><config><system/></config></edit-config>",
+		},
+		{
+			name:     "defaultOperation",
+			method:   EditConfig{Target: "running", DefaultOperation: "replace", Config: "<system/>"},
+			expected: "<edit-config><target><running/></target><default-operation>replace</default-operation><config><system/></config></edit-config>",
+		},
+		{
+			name:     "stopOnError",
+			method:   EditConfig{Target: "candidate", ErrorOption: ErrorOptionStopOnError, Config: "<system/>"},
+			expected: "<edit-config><target><candidate/></target><error-option>stop-on-error</error-option><config><system/></config></edit-config>",
+		},
+		{
+			name:     "continueOnError",
+			method:   EditConfig{Target: "candidate", ErrorOption: ErrorOptionContinueOnError, Config: "<system/>"},
+			expected: "<edit-config><target><candidate/></target><error-option>continue-on-error</error-option><config><system/></config></edit-config>",
+		},
+		{
+			name:     "rollbackOnError",
+			method:   EditConfig{Target: "running", DefaultOperation: "merge", ErrorOption: ErrorOptionRollbackOnError, Config: "<system/>"},
+			expected: "<edit-config><target><running/></target><default-operation>merge</default-operation><error-option>rollback-on-error</error-option><config><system/></config></edit-config>",
+		},
+		{
+			name:     "testThenSet",
+			method:   EditConfig{Target: "candidate", TestOption: TestOptionTestThenSet, Config: "<system/>"},
+			expected: "<edit-config><target><candidate/></target><test-option>test-then-set</test-option><config><system/></config></edit-config>",
+		},
+		{
+			name:     "set",
+			method:   EditConfig{Target: "candidate", TestOption: TestOptionSet, Config: "<system/>"},
+			expected: "<edit-config><target><candidate/></target><test-option>set</test-option><config><system/></config></edit-config>",
+		},
+		{
+			name:     "testOnly",
+			method:   EditConfig{Target: "running", DefaultOperation: "merge", TestOption: TestOptionTestOnly, ErrorOption: ErrorOptionRollbackOnError, Config: "<system/>"},
+			expected: "<edit-config><target><running/></target><default-operation>merge</default-operation><test-option>test-only</test-option><error-option>rollback-on-error</error-option><config><system/></config></edit-config>",
+		},
+		{
+			name:     "url",
+			method:   EditConfig{Target: "candidate", URL: "http://192.0.2.10/r1.xml?a=1&b=2"},
+			expected: "<edit-config><target><candidate/></target><url>http://192.0.2.10/r1.xml?a=1&amp;b=2</url></edit-config>",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.method.MarshalMethod() != tc.expected {
+				t.Errorf("got %s, expected %s", tc.method.MarshalMethod(), tc.expected)
+			}
+		})
+	}
+}
+
+func TestCheckTestOption(t *testing.T) {
+	validate10 := []string{"urn:ietf:params:netconf:base:1.0", CapabilityValidate10}
+	validate11 := []string{"urn:ietf:params:netconf:base:1.1", CapabilityValidate11}
+
+	tt := []struct {
+		name         string
+		option       string
+		capabilities []string
+		valid        bool
+	}{
+		{name: "empty", option: "", capabilities: nil, valid: true},
+		{name: "testThenSet validate 1.0", option: TestOptionTestThenSet, capabilities: validate10, valid: true},
+		{name: "set validate 1.1", option: TestOptionSet, capabilities: validate11, valid: true},
+		{name: "testThenSet without validate", option: TestOptionTestThenSet, capabilities: []string{"urn:ietf:params:netconf:base:1.0"}, valid: false},
+		{name: "testOnly validate 1.0", option: TestOptionTestOnly, capabilities: validate10, valid: false},
+		{name: "testOnly validate 1.1", option: TestOptionTestOnly, capabilities: validate11, valid: true},
+		{name: "unknown", option: "test-maybe", capabilities: validate11, valid: false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckTestOption(tc.option, tc.capabilities)
+			if (err == nil) != tc.valid {
+				t.Errorf("got error %v, expected valid %v", err, tc.valid)
+			}
+		})
+	}
+}
+
+func TestCheckErrorOption(t *testing.T) {
+	base := []string{"urn:ietf:params:netconf:base:1.0"}
+	rollback := []string{"urn:ietf:params:netconf:base:1.0", CapabilityRollbackOnError}
+
+	tt := []struct {
+		name         string
+		option       string
+		capabilities []string
+		valid        bool
+	}{
+		{name: "empty", option: "", capabilities: nil, valid: true},
+		{name: "stop-on-error", option: ErrorOptionStopOnError, capabilities: base, valid: true},
+		{name: "continue-on-error", option: ErrorOptionContinueOnError, capabilities: base, valid: true},
+		{name: "rollback-on-error advertised", option: ErrorOptionRollbackOnError, capabilities: rollback, valid: true},
+		{name: "rollback-on-error not advertised", option: ErrorOptionRollbackOnError, capabilities: base, valid: false},
+		{name: "unknown", option: "ignore-error", capabilities: rollback, valid: false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckErrorOption(tc.option, tc.capabilities)
+			if (err == nil) != tc.valid {
+				t.Errorf("got error %v, expected valid %v", err, tc.valid)
+			}
+		})
+	}
+}
+
+func TestEditConfigValidate(t *testing.T) {
+	tt := []struct {
+		name   string
+		method EditConfig
+		valid  bool
+	}{
+		{name: "minimal", method: EditConfig{Target: DatastoreCandidate}, valid: true},
+		{name: "all options", method: EditConfig{Target: DatastoreRunning, DefaultOperation: "replace", TestOption: TestOptionTestThenSet, ErrorOption: ErrorOptionRollbackOnError}, valid: true},
+		{name: "unknown target", method: EditConfig{Target: "scratch"}, valid: false},
+		{name: "unknown default-operation", method: EditConfig{Target: DatastoreCandidate, DefaultOperation: "delete"}, valid: false},
+		{name: "unknown error-option", method: EditConfig{Target: DatastoreCandidate, ErrorOption: "ignore-error"}, valid: false},
+		{name: "test-only", method: EditConfig{Target: DatastoreCandidate, TestOption: TestOptionTestOnly}, valid: true},
+		{name: "unknown test-option", method: EditConfig{Target: DatastoreCandidate, TestOption: "test-maybe"}, valid: false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.method.Validate()
+			if (err == nil) != tc.valid {
+				t.Errorf("got error %v, expected valid %v", err, tc.valid)
+			}
+		})
+	}
+}
+
+func TestMethodWithNamespaces(t *testing.T) {
+	const ocInterfaces = "http://openconfig.net/yang/interfaces"
+	const ietfInterfaces = "urn:ietf:params:xml:ns:yang:ietf-interfaces"
+
+	tt := []struct {
+		name     string
+		method   NamespacedMethod
+		expected string
+	}{
+		{
+			name:     "get",
+			method:   MethodWithNamespaces(MethodGet("<oc-if:interfaces/>"), map[string]string{"oc-if": ocInterfaces}),
+			expected: `<get xmlns:oc-if="http://openconfig.net/yang/interfaces"><filter type="subtree"><oc-if:interfaces/></filter></get>`,
+		},
+		{
+			name: "base",
+			method: NamespacedMethod{
+				Method:     MethodEditConfig("candidate", "<if:interfaces/>"),
+				Xmlns:      "urn:ietf:params:xml:ns:netconf:base:1.0",
+				Namespaces: map[string]string{"oc-if": ocInterfaces, "if": ietfInterfaces},
+			},
+			expected: `<edit-config xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:if="urn:ietf:params:xml:ns:yang:ietf-interfaces" xmlns:oc-if="http://openconfig.net/yang/interfaces"><target><candidate/></target><config><if:interfaces/></config></edit-config>`,
+		},
+		{
+			name:     "empty element",
+			method:   MethodWithNamespaces(MethodDiscardChanges(), map[string]string{"x": `urn:a&"b"`}),
+			expected: `<discard-changes xmlns:x="urn:a&amp;&#34;b&#34;"/>`,
+		},
+		{
+			name:     "none",
+			method:   MethodWithNamespaces(MethodCommit(), nil),
+			expected: MethodCommit().MarshalMethod(),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.method.MarshalMethod() != tc.expected {
+				t.Errorf("got %s, expected %s", tc.method.MarshalMethod(), tc.expected)
+			}
+		})
+	}
+
+	// The declarations are in scope for the elements inside the operation once wrapped in <rpc>
+	msg := NewRPCMessage([]RPCMethod{tt[0].method})
+
+	out, err := xml.Marshal(msg)
+	if err != nil {
+		t.Fatalf("failed to marshal xml: %v", err)
+	}
+
+	var parsed struct {
+		Filter struct {
+			Interfaces struct {
+				XMLName xml.Name
+			} `xml:"interfaces"`
+		} `xml:"get>filter"`
+	}
+	err = xml.Unmarshal(out, &parsed)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", out, err)
+	}
+	if parsed.Filter.Interfaces.XMLName.Space != ocInterfaces {
+		t.Errorf("got namespace %q for the filter, expected %q", parsed.Filter.Interfaces.XMLName.Space, ocInterfaces)
+	}
+}
+
+func TestMethodCreateSubscription(t *testing.T) {
+	tt := []struct {
+		name      string
+		stream    string
+		startTime string
+		expected  string
+	}{
+		{
+			name:     "live",
+			stream:   "NETCONF",
+			expected: `<create-subscription xmlns="urn:ietf:params:xml:ns:netconf:notification:1.0"><stream>NETCONF</stream></create-subscription>`,
+		},
+		{
+			name:      "replay",
+			stream:    "NETCONF",
+			startTime: "2020-06-01T10:00:00Z",
+			expected:  `<create-subscription xmlns="urn:ietf:params:xml:ns:netconf:notification:1.0"><stream>NETCONF</stream><startTime>2020-06-01T10:00:00Z</startTime></create-subscription>`,
+		},
+		{
+			name:     "escaped",
+			stream:   "a<b>&c",
+			expected: `<create-subscription xmlns="urn:ietf:params:xml:ns:netconf:notification:1.0"><stream>a&lt;b&gt;&amp;c</stream></create-subscription>`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			m := MethodCreateSubscription(tc.stream, tc.startTime)
+			if m.MarshalMethod() != tc.expected {
+				t.Errorf("got %s, expected %s", m, tc.expected)
+			}
+		})
+	}
+}
+
+func TestNewNotification(t *testing.T) {
+	raw := []byte(`<notification xmlns="urn:ietf:params:xml:ns:netconf:notification:1.0"><eventTime>2020-06-01T10:00:00Z</eventTime><netconf-config-change/></notification>`)
+
+	n, err := NewNotification(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if n.EventTime != "2020-06-01T10:00:00Z" {
+		t.Errorf("got event time %s, expected 2020-06-01T10:00:00Z", n.EventTime)
+	}
+
+	if n.RawReply != string(raw) {
+		t.Errorf("got raw reply %s, expected %s", n.RawReply, raw)
+	}
+}
+
 // TestUUIDLength verifies that UUID length is cor([a-zA-Z]|\d|-)rect
+func TestNextMessageID(t *testing.T) {
+	first, err := strconv.ParseUint(NextMessageID(), 10, 64)
+	if err != nil {
+		t.Fatalf("got a message-id that is not an integer: %v", err)
+	}
+
+	second, err := strconv.ParseUint(NextMessageID(), 10, 64)
+	if err != nil {
+		t.Fatalf("got a message-id that is not an integer: %v", err)
+	}
+
+	if second != first+1 {
+		t.Errorf("got message-ids %d and %d, expected them to count up by one", first, second)
+	}
+}
+
+func TestNewRPCReplyMessageID(t *testing.T) {
+	reply, err := NewRPCReply([]byte(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="trace-1"><ok/></rpc-reply>`), false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if reply.MessageID != "trace-1" {
+		t.Errorf("got message-id %q, expected trace-1", reply.MessageID)
+	}
+}
+
 func TestUUIDLength(t *testing.T) {
 	expectedLength := 36
 
@@ -234,3 +723,111 @@ func TestNewRPCReply(t *testing.T) {
 		}
 	}
 }
+
+func TestNewRPCReplyEmpty(t *testing.T) {
+	tt := []struct {
+		name   string
+		rawXML string
+		err    error
+	}{
+		{name: "empty", rawXML: `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"></rpc-reply>`, err: ErrEmptyReply},
+		{name: "whitespace", rawXML: "<rpc-reply xmlns=\"urn:ietf:params:xml:ns:netconf:base:1.0\">\n  \n</rpc-reply>", err: ErrEmptyReply},
+		{name: "self closing", rawXML: `<rpc-reply message-id="1"/>`, err: ErrEmptyReply},
+		{name: "ok", rawXML: `<rpc-reply><ok/></rpc-reply>`},
+		{name: "data", rawXML: `<rpc-reply><data><system/></data></rpc-reply>`},
+		{name: "text", rawXML: `<rpc-reply>{"configuration": {}}</rpc-reply>`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			reply, err := NewRPCReply([]byte(tc.rawXML), false)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("got error %v, expected %v", err, tc.err)
+			}
+
+			if reply == nil || reply.RawReply != tc.rawXML {
+				t.Errorf("got reply %+v, expected one carrying the raw reply", reply)
+			}
+		})
+	}
+}
+
+func TestNewRPCReplyMultipleErrors(t *testing.T) {
+	rawXML := `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<rpc-error>
+<error-type>application</error-type>
+<error-severity>error</error-severity>
+<error-message>first</error-message>
+</rpc-error>
+<rpc-error>
+<error-type>application</error-type>
+<error-severity>warning</error-severity>
+<error-message>ignored warning</error-message>
+</rpc-error>
+<rpc-error>
+<error-type>application</error-type>
+<error-severity>error</error-severity>
+<error-message>second</error-message>
+</rpc-error>
+<rpc-error>
+<error-type>application</error-type>
+<error-severity>error</error-severity>
+<error-message>third</error-message>
+</rpc-error>
+</rpc-reply>`
+
+	_, err := NewRPCReply([]byte(rawXML), false)
+
+	var rpcErrs *RPCErrors
+	if !errors.As(err, &rpcErrs) {
+		t.Fatalf("got error %v, expected *RPCErrors", err)
+	}
+
+	var messages []string
+	for _, e := range rpcErrs.Errors {
+		messages = append(messages, e.Message)
+	}
+
+	expected := []string{"first", "second", "third"}
+	if !cmp.Equal(messages, expected) {
+		t.Errorf("got %v, expected %v", messages, expected)
+	}
+
+	// errors.As and errors.Is look through to the individual errors, wrapped or not
+	wrapped := fmt.Errorf("driver error: %w", err)
+
+	var rpcErr *RPCError
+	if !errors.As(wrapped, &rpcErr) || rpcErr.Message != "first" {
+		t.Errorf("got error %v, expected the first *RPCError", rpcErr)
+	}
+
+	if !errors.Is(wrapped, &rpcErrs.Errors[2]) {
+		t.Errorf("expected errors.Is to match the third *RPCError")
+	}
+
+	if errors.Is(wrapped, &RPCError{Message: "first"}) {
+		t.Errorf("expected errors.Is not to match an RPCError outside the reply")
+	}
+
+	// FindRPCError looks past the first error
+	found := FindRPCError(wrapped, func(e *RPCError) bool { return e.Message == "third" })
+	if found != &rpcErrs.Errors[2] {
+		t.Errorf("got %v, expected the third *RPCError", found)
+	}
+
+	if FindRPCError(wrapped, func(e *RPCError) bool { return e.Message == "fourth" }) != nil {
+		t.Error("expected no match for an error outside the reply")
+	}
+
+	// A single error is still returned as a *RPCError
+	_, err = NewRPCReply([]byte(`<rpc-reply><rpc-error><error-severity>error</error-severity><error-message>only</error-message></rpc-error></rpc-reply>`), false)
+
+	rpcErr = nil
+	if !errors.As(err, &rpcErr) || rpcErr.Message != "only" {
+		t.Errorf("got error %v, expected a single *RPCError", err)
+	}
+
+	if FindRPCError(err, func(e *RPCError) bool { return e.Message == "only" }) != rpcErr {
+		t.Errorf("expected FindRPCError to match the single *RPCError")
+	}
+}
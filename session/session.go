@@ -11,18 +11,44 @@ Package netconf is a simple NETCONF client based on RFC6241 and RFC6242
 package netconf
 
 import (
+	"bytes"
 	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
 
 	rpc "github.com/davedotdev/go-netconf/rpc"
 	transport "github.com/davedotdev/go-netconf/transport"
 )
 
+// DefaultHelloTimeout is how long NewSessionTimeout waits for the hello exchange when no timeout is given
+const DefaultHelloTimeout = 15 * time.Second
+
+// ErrHelloTimeout is returned when the server does not complete the hello exchange in time, typically
+// because the far end is not a NETCONF server
+var ErrHelloTimeout = errors.New("timed out waiting for NETCONF hello")
+
+// ErrMessageIDMismatch is returned when a reply carries a message-id other than that of the RPC it answers,
+// meaning requests and replies are out of step on the session
+var ErrMessageIDMismatch = errors.New("rpc-reply message-id does not match the rpc")
+
+// framer is implemented by transports supporting the chunked framing of base:1.1
+type framer interface {
+	SetFraming(chunked bool) error
+}
+
 // Session defines the necessary components for a NETCONF session
 type Session struct {
 	Transport          transport.Transport
 	SessionID          int
 	ServerCapabilities []string
+	BaseCapability     string // Base protocol version negotiated in the hello exchange
 	ErrOnWarning       bool
+
+	// MessageID generates the message-id of every RPC sent with Exec, rpc.NextMessageID when nil
+	MessageID func() string
 }
 
 // Close is used to close and end a transport session
@@ -30,9 +56,21 @@ func (s *Session) Close() error {
 	return s.Transport.Close()
 }
 
-// Exec is used to execute an RPC method or methods
+// Exec is used to execute an RPC method or methods. A reply holding rpc-errors is returned along with
+// the error, so its data and message-id can still be inspected.
 func (s *Session) Exec(methods ...rpc.RPCMethod) (*rpc.RPCReply, error) {
-	rpcm := rpc.NewRPCMessage(methods)
+	nextID := s.MessageID
+	if nextID == nil {
+		nextID = rpc.NextMessageID
+	}
+
+	return s.ExecID(nextID(), methods...)
+}
+
+// ExecID is Exec sending the RPC with the given message-id. A reply echoing a different message-id fails
+// with ErrMessageIDMismatch, one without a message-id is accepted.
+func (s *Session) ExecID(messageID string, methods ...rpc.RPCMethod) (*rpc.RPCReply, error) {
+	rpcm := rpc.NewRPCMessageID(messageID, methods)
 
 	request, err := xml.Marshal(rpcm)
 	if err != nil {
@@ -53,11 +91,58 @@ func (s *Session) Exec(methods ...rpc.RPCMethod) (*rpc.RPCReply, error) {
 	}
 
 	reply, err := rpc.NewRPCReply(rawXML, s.ErrOnWarning)
+	if reply != nil && reply.MessageID != "" && reply.MessageID != messageID {
+		return nil, fmt.Errorf("%w: sent %s, got %s", ErrMessageIDMismatch, messageID, reply.MessageID)
+	}
+
+	return reply, err
+}
+
+// streamReceiver is implemented by transports able to return a message as it is read
+type streamReceiver interface {
+	ReceiveStream() (io.Reader, error)
+}
+
+// ExecStream is Exec returning a reader over the raw rpc-reply, read from the transport as it is consumed,
+// for replies too large to buffer. The reply's message-id and rpc-errors are left to the caller, and it
+// must be read to io.EOF before the session is used again. Transports unable to stream return the reply
+// buffered.
+func (s *Session) ExecStream(methods ...rpc.RPCMethod) (io.Reader, error) {
+	nextID := s.MessageID
+	if nextID == nil {
+		nextID = rpc.NextMessageID
+	}
+
+	request, err := xml.Marshal(rpc.NewRPCMessageID(nextID(), methods))
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Transport.Send(append([]byte(xml.Header), request...))
+	if err != nil {
+		return nil, err
+	}
+
+	if sr, ok := s.Transport.(streamReceiver); ok {
+		return sr.ReceiveStream()
+	}
+
+	rawXML, err := s.Transport.Receive()
 	if err != nil {
 		return nil, err
 	}
 
-	return reply, nil
+	return bytes.NewReader(rawXML), nil
+}
+
+// ReceiveNotification blocks until the next notification arrives on a subscribed session
+func (s *Session) ReceiveNotification() (*rpc.Notification, error) {
+	rawXML, err := s.Transport.Receive()
+	if err != nil {
+		return nil, err
+	}
+
+	return rpc.NewNotification(rawXML)
 }
 
 // NewSession creates a new NETCONF session using the provided transport layer.
@@ -73,8 +158,51 @@ func NewSession(t transport.Transport) (*Session, error) {
 	s.SessionID = serverHello.SessionID
 	s.ServerCapabilities = serverHello.Capabilities
 
+	// Carrying on without a common base version would corrupt the framing on the first large message
+	s.BaseCapability, err = transport.NegotiateBase(transport.DefaultCapabilities, serverHello.Capabilities)
+	if err != nil {
+		t.Close()
+		return nil, err
+	}
+
 	// Send our hello using default capabilities.
 	t.SendHello(&transport.HelloMessage{Capabilities: transport.DefaultCapabilities})
 
+	// Both peers switch to chunked framing once the hello exchange settles on base:1.1
+	if f, ok := t.(framer); ok {
+		err = f.SetFraming(s.BaseCapability == transport.CapabilityBase11)
+		if err != nil {
+			t.Close()
+			return nil, err
+		}
+	}
+
 	return s, nil
 }
+
+// NewSessionTimeout is NewSession, failing with ErrHelloTimeout unless the hello exchange completes within
+// timeout (DefaultHelloTimeout when zero, no limit when negative). abort must unblock the exchange, usually
+// by closing the underlying connection, as the transport can not be interrupted otherwise.
+func NewSessionTimeout(t transport.Transport, timeout time.Duration, abort func()) (*Session, error) {
+	if timeout < 0 {
+		return NewSession(t)
+	}
+
+	if timeout == 0 {
+		timeout = DefaultHelloTimeout
+	}
+
+	var timedOut int32
+	timer := time.AfterFunc(timeout, func() {
+		atomic.StoreInt32(&timedOut, 1)
+		abort()
+	})
+
+	s, err := NewSession(t)
+
+	if !timer.Stop() && atomic.LoadInt32(&timedOut) == 1 {
+		return nil, fmt.Errorf("%w after %v", ErrHelloTimeout, timeout)
+	}
+
+	return s, err
+}
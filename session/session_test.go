@@ -0,0 +1,293 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	rpc "github.com/davedotdev/go-netconf/rpc"
+	transport "github.com/davedotdev/go-netconf/transport"
+)
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// newTestTransport returns a transport reading input and writing to the returned buffer
+func newTestTransport(input string) (*transport.TransportBasicIO, *bytes.Buffer) {
+	out := new(bytes.Buffer)
+
+	t := &transport.TransportBasicIO{}
+	t.ReadWriteCloser = transport.NewReadWriteCloser(strings.NewReader(input), nopWriteCloser{out})
+
+	return t, out
+}
+
+func TestNewSession(t *testing.T) {
+	tr, out := newTestTransport(`<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<capabilities>
+<capability>urn:ietf:params:netconf:base:1.0</capability>
+</capabilities>
+<session-id>42</session-id>
+</hello>
+]]>]]>`)
+
+	s, err := NewSession(tr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if s.SessionID != 42 || s.BaseCapability != transport.CapabilityBase10 {
+		t.Errorf("got session %d with base %s, expected 42 with %s", s.SessionID, s.BaseCapability, transport.CapabilityBase10)
+	}
+
+	if !strings.Contains(out.String(), "<hello") {
+		t.Errorf("client hello not sent, got %q", out.String())
+	}
+}
+
+func TestNewSessionNoCommonBase(t *testing.T) {
+	tr, out := newTestTransport(`<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<capabilities>
+<capability>urn:ietf:params:netconf:base:2.0</capability>
+</capabilities>
+<session-id>42</session-id>
+</hello>
+]]>]]>`)
+
+	_, err := NewSession(tr)
+	if !errors.Is(err, transport.ErrNoCommonBase) {
+		t.Fatalf("got error %v, expected %v", err, transport.ErrNoCommonBase)
+	}
+
+	if strings.Contains(out.String(), "<hello") {
+		t.Errorf("client hello sent despite the failed negotiation, got %q", out.String())
+	}
+}
+
+func TestNewSessionBase11Only(t *testing.T) {
+	tr, out := newTestTransport(`<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<capabilities>
+<capability>urn:ietf:params:netconf:base:1.1</capability>
+</capabilities>
+<session-id>42</session-id>
+</hello>
+]]>]]>`)
+
+	// Negotiated through the default capabilities
+	s, err := NewSession(tr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if s.BaseCapability != transport.CapabilityBase11 {
+		t.Errorf("got base %s, expected %s", s.BaseCapability, transport.CapabilityBase11)
+	}
+
+	if !strings.Contains(out.String(), "<capability>"+transport.CapabilityBase11+"</capability>") {
+		t.Errorf("got hello %q, expected it to advertise %s", out.String(), transport.CapabilityBase11)
+	}
+
+	out.Reset()
+
+	err = s.Transport.Send([]byte("<rpc><get/></rpc>"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.HasSuffix(out.String(), "\n##\n") {
+		t.Errorf("got %q, expected chunked framing", out.String())
+	}
+}
+
+func TestForceFraming(t *testing.T) {
+	hello := func(base string) string {
+		return `<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><capabilities><capability>` + base +
+			`</capability></capabilities><session-id>1</session-id></hello>]]>]]>`
+	}
+
+	tt := []struct {
+		name    string
+		server  string
+		force   string
+		framing string
+		err     bool
+	}{
+		{name: "negotiated chunked", server: transport.CapabilityBase11, framing: "\n##\n"},
+		{name: "forced eom", server: transport.CapabilityBase11, force: transport.FramingEOM, framing: "]]>]]>\n"},
+		{name: "forced chunked", server: transport.CapabilityBase10, force: transport.FramingChunked, framing: "\n##\n"},
+		{name: "invalid", server: transport.CapabilityBase10, force: "xml", err: true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			tr, out := newTestTransport(hello(tc.server))
+			tr.ForceFraming = tc.force
+
+			s, err := NewSession(tr)
+			if tc.err {
+				if err == nil {
+					t.Fatal("expected an error for an invalid framing")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			out.Reset()
+
+			err = s.Transport.Send([]byte("<rpc><get/></rpc>"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !strings.HasSuffix(out.String(), tc.framing) {
+				t.Errorf("got %q, expected it framed with %q", out.String(), tc.framing)
+			}
+		})
+	}
+}
+
+func TestExecMessageID(t *testing.T) {
+	const hello = `<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><capabilities><capability>` +
+		`urn:ietf:params:netconf:base:1.0</capability></capabilities><session-id>1</session-id></hello>]]>]]>`
+
+	reply := func(messageID string) string {
+		attr := ""
+		if messageID != "" {
+			attr = ` message-id="` + messageID + `"`
+		}
+		return `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"` + attr + `><ok/></rpc-reply>]]>]]>`
+	}
+
+	tt := []struct {
+		name      string
+		generator func() string
+		messageID string // Passed to ExecID, Exec is used when empty
+		reply     string
+		sent      string
+		err       error
+	}{
+		{name: "supplied", messageID: "trace-1", reply: reply("trace-1"), sent: `message-id="trace-1"`},
+		{name: "generator", generator: func() string { return "job-7" }, reply: reply("job-7"), sent: `message-id="job-7"`},
+		{name: "not echoed", messageID: "trace-1", reply: reply(""), sent: `message-id="trace-1"`},
+		{name: "mismatch", messageID: "trace-1", reply: reply("trace-2"), err: ErrMessageIDMismatch},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// The reply is read separately, as it would only arrive once the RPC was sent
+			out := new(bytes.Buffer)
+			tr := &transport.TransportBasicIO{}
+			tr.ReadWriteCloser = transport.NewReadWriteCloser(
+				io.MultiReader(strings.NewReader(hello), strings.NewReader(tc.reply)), nopWriteCloser{out})
+
+			s, err := NewSession(tr)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			s.MessageID = tc.generator
+			out.Reset()
+
+			var r *rpc.RPCReply
+			if tc.messageID != "" {
+				r, err = s.ExecID(tc.messageID, rpc.MethodCommit())
+			} else {
+				r, err = s.Exec(rpc.MethodCommit())
+			}
+
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("got error %v, expected %v", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !strings.Contains(out.String(), tc.sent) {
+				t.Errorf("got RPC %q, expected it to carry %s", out.String(), tc.sent)
+			}
+			if r == nil || !strings.Contains(r.Data, "<ok/>") {
+				t.Errorf("got reply %+v, expected the <ok/> reply", r)
+			}
+		})
+	}
+}
+
+func TestExecRPCError(t *testing.T) {
+	const hello = `<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><capabilities><capability>` +
+		`urn:ietf:params:netconf:base:1.0</capability></capabilities><session-id>1</session-id></hello>]]>]]>`
+	const reply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><data><system/></data>` +
+		`<rpc-error><error-severity>error</error-severity><error-message>failed</error-message></rpc-error></rpc-reply>]]>]]>`
+
+	tr := &transport.TransportBasicIO{}
+	tr.ReadWriteCloser = transport.NewReadWriteCloser(
+		io.MultiReader(strings.NewReader(hello), strings.NewReader(reply)), nopWriteCloser{new(bytes.Buffer)})
+
+	s, err := NewSession(tr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r, err := s.Exec(rpc.MethodGetConfig("running"))
+
+	var rpcErr *rpc.RPCError
+	if !errors.As(err, &rpcErr) {
+		t.Errorf("got error %v, expected *rpc.RPCError", err)
+	}
+
+	if r == nil || !strings.Contains(r.Data, "<system/>") {
+		t.Errorf("got reply %+v, expected the reply to be returned with the error", r)
+	}
+}
+
+func TestExecStream(t *testing.T) {
+	const hello = `<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><capabilities><capability>` +
+		`urn:ietf:params:netconf:base:1.0</capability></capabilities><session-id>1</session-id></hello>]]>]]>`
+	const reply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><route-information/></rpc-reply>`
+
+	out := new(bytes.Buffer)
+	tr := &transport.TransportBasicIO{}
+	tr.ReadWriteCloser = transport.NewReadWriteCloser(
+		io.MultiReader(strings.NewReader(hello), strings.NewReader(reply+"]]>]]>")), nopWriteCloser{out})
+
+	s, err := NewSession(tr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out.Reset()
+
+	r, err := s.ExecStream(rpc.RawMethod("<get-route-information/>"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.Contains(out.String(), "<get-route-information/>") {
+		t.Errorf("got RPC %q, expected get-route-information", out.String())
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if string(data) != reply {
+		t.Errorf("got reply %q, expected %q", data, reply)
+	}
+}
@@ -0,0 +1,200 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+)
+
+// DefaultChunkSize is the largest chunk the encoder emits when ChunkSize is not set.
+// Fewer, larger chunks keep the number of writes down on multi-megabyte loads.
+const DefaultChunkSize = 64 * 1024
+
+// maxChunkSize is the largest chunk-size RFC 6242 allows
+const maxChunkSize = 4294967295
+
+// ErrBadChunk is returned when a message received with chunked framing is malformed
+var ErrBadChunk = errors.New("malformed chunked framing")
+
+// endOfChunks terminates a message sent with chunked framing
+const endOfChunks = "\n##\n"
+
+// Message framings ForceFraming can select
+const (
+	FramingEOM     = "eom"     // The ]]>]]> end-of-message framing of base:1.0
+	FramingChunked = "chunked" // The chunked framing of base:1.1
+)
+
+// SetFraming applies the framing negotiated in the hello exchange, chunked when both peers advertised
+// base:1.1, unless ForceFraming overrides it
+func (t *TransportBasicIO) SetFraming(chunked bool) error {
+	switch t.ForceFraming {
+	case "":
+	case FramingEOM, FramingChunked:
+		log.Printf("WARNING: netconf framing forced to %s regardless of the negotiated base version, only use this to debug interop", t.ForceFraming)
+		chunked = t.ForceFraming == FramingChunked
+	default:
+		return fmt.Errorf("invalid framing %q, expected %s or %s", t.ForceFraming, FramingEOM, FramingChunked)
+	}
+
+	if chunked {
+		t.EnableChunkedFraming()
+	}
+
+	return nil
+}
+
+// EnableChunkedFraming switches the transport to the chunked framing of RFC 6242 section 4.2,
+// used once both peers have advertised base:1.1 in their hello messages.
+func (t *TransportBasicIO) EnableChunkedFraming() {
+	t.chunkedFraming = true
+	t.reader = bufio.NewReader(t.ReadWriteCloser)
+}
+
+// chunkSize returns the configured chunk size, falling back to DefaultChunkSize
+func (t *TransportBasicIO) chunkSize() int {
+	if t.ChunkSize <= 0 || t.ChunkSize > maxChunkSize {
+		return DefaultChunkSize
+	}
+	return t.ChunkSize
+}
+
+// chunkWriter buffers written data and emits it as chunks of up to size bytes
+type chunkWriter struct {
+	w    io.Writer
+	size int
+	buf  []byte
+}
+
+// newChunkWriter returns a chunkWriter emitting chunks of up to size bytes to w
+func newChunkWriter(w io.Writer, size int) *chunkWriter {
+	return &chunkWriter{w: w, size: size}
+}
+
+// Write buffers p, emitting every full chunk
+func (c *chunkWriter) Write(p []byte) (int, error) {
+	c.buf = append(c.buf, p...)
+
+	for len(c.buf) >= c.size {
+		err := c.emit(c.buf[:c.size])
+		if err != nil {
+			return 0, err
+		}
+		c.buf = c.buf[c.size:]
+	}
+
+	return len(p), nil
+}
+
+// Close emits the remaining buffered data and the end-of-chunks marker
+func (c *chunkWriter) Close() error {
+	if len(c.buf) > 0 {
+		err := c.emit(c.buf)
+		if err != nil {
+			return err
+		}
+		c.buf = nil
+	}
+
+	_, err := io.WriteString(c.w, endOfChunks)
+	return err
+}
+
+// emit writes a single chunk, header and data together, in one write
+func (c *chunkWriter) emit(data []byte) error {
+	header := fmt.Sprintf("\n#%d\n", len(data))
+
+	frame := make([]byte, 0, len(header)+len(data))
+	frame = append(frame, header...)
+	frame = append(frame, data...)
+
+	_, err := c.w.Write(frame)
+	return err
+}
+
+// readChunked reassembles a message sent with chunked framing, whatever the chunk boundaries,
+// returning ErrReplyTooLarge once it would exceed limit bytes (unlimited when zero)
+func readChunked(r *bufio.Reader, limit int64) ([]byte, error) {
+	var msg bytes.Buffer
+
+	for {
+		size, err := readChunkHeader(r)
+		if err != nil {
+			return nil, err
+		}
+
+		if size == 0 {
+			return msg.Bytes(), nil
+		}
+
+		if limit > 0 && int64(msg.Len())+size > limit {
+			return nil, ErrReplyTooLarge
+		}
+
+		_, err = io.CopyN(&msg, r, size)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readChunkHeader consumes the header of the next chunk and returns its size, or zero at the end of the message
+func readChunkHeader(r *bufio.Reader) (int64, error) {
+	err := expect(r, "\n#")
+	if err != nil {
+		return 0, err
+	}
+
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+
+	if b == '#' {
+		return 0, expect(r, "\n")
+	}
+
+	r.UnreadByte()
+
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return 0, err
+	}
+
+	// chunk-size is 1-9 followed by up to 9 digits
+	digits := line[:len(line)-1]
+	if len(digits) == 0 || len(digits) > 10 || digits[0] == '0' {
+		return 0, fmt.Errorf("%w: chunk-size %q", ErrBadChunk, digits)
+	}
+
+	size, err := strconv.ParseUint(digits, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%w: chunk-size %q", ErrBadChunk, digits)
+	}
+
+	return int64(size), nil
+}
+
+// expect consumes s from r, failing if anything else is read
+func expect(r *bufio.Reader, s string) error {
+	for i := 0; i < len(s); i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		if b != s[i] {
+			return fmt.Errorf("%w: expected %q, got %q", ErrBadChunk, s[i], b)
+		}
+	}
+	return nil
+}
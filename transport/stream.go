@@ -0,0 +1,136 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// ReceiveStream returns a reader over the next message, read from the transport as the caller consumes it
+// rather than buffered, for replies too large to hold in memory. MaxReplySize does not apply. The message
+// must be read to io.EOF before anything else is received on the transport.
+func (t *TransportBasicIO) ReceiveStream() (io.Reader, error) {
+	var r io.Reader
+	if t.chunkedFraming {
+		r = &chunkReader{r: t.reader}
+	} else {
+		r = &eomReader{r: t.ReadWriteCloser}
+	}
+
+	if t.Debug != nil {
+		fmt.Fprint(t.Debug, "S: ")
+		r = &debugReader{r: io.TeeReader(r, t.Debug), w: t.Debug}
+	}
+
+	return r, nil
+}
+
+// eomReader reads a message framed with the ]]>]]> end-of-message separator up to the separator.
+// As with Receive, anything read past the separator is dropped.
+type eomReader struct {
+	r    io.Reader
+	buf  []byte // Read from r but not yet returned
+	done bool   // The separator has been read
+}
+
+func (e *eomReader) Read(p []byte) (int, error) {
+	for {
+		if !e.done {
+			if i := bytes.Index(e.buf, []byte(msgSeperator)); i > -1 {
+				e.buf = e.buf[:i]
+				e.done = true
+			}
+		}
+
+		// Hold back what could be the start of a separator split across reads
+		safe := len(e.buf)
+		if !e.done {
+			safe -= len(msgSeperator) - 1
+		}
+
+		if safe > 0 {
+			n := copy(p, e.buf[:safe])
+			e.buf = e.buf[n:]
+			return n, nil
+		}
+
+		if e.done {
+			return 0, io.EOF
+		}
+
+		chunk := make([]byte, 4096)
+		n, err := e.r.Read(chunk)
+		e.buf = append(e.buf, chunk[:n]...)
+
+		if err == io.EOF {
+			return 0, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// chunkReader reads a message sent with chunked framing, one chunk at a time
+type chunkReader struct {
+	r         *bufio.Reader
+	remaining int64 // Bytes left in the current chunk
+	done      bool  // The end-of-chunks marker has been read
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	for c.remaining == 0 {
+		if c.done {
+			return 0, io.EOF
+		}
+
+		size, err := readChunkHeader(c.r)
+		if err != nil {
+			return 0, err
+		}
+
+		if size == 0 {
+			c.done = true
+			return 0, io.EOF
+		}
+
+		c.remaining = size
+	}
+
+	if int64(len(p)) > c.remaining {
+		p = p[:c.remaining]
+	}
+
+	n, err := c.r.Read(p)
+	c.remaining -= int64(n)
+
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+
+	return n, err
+}
+
+// debugReader ends the Debug transcript line of a streamed message once it has been read
+type debugReader struct {
+	r    io.Reader
+	w    io.Writer
+	done bool
+}
+
+func (d *debugReader) Read(p []byte) (int, error) {
+	n, err := d.r.Read(p)
+	if err == io.EOF && !d.done {
+		d.done = true
+		fmt.Fprintln(d.w)
+	}
+
+	return n, err
+}
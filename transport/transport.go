@@ -7,11 +7,14 @@
 package netconf
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"regexp"
+	"strings"
 )
 
 const (
@@ -19,9 +22,82 @@ const (
 	msgSeperator = "]]>]]>"
 )
 
-// DefaultCapabilities sets the default capabilities of the client library
+// NETCONF base protocol capabilities
+const (
+	CapabilityBase10 = "urn:ietf:params:netconf:base:1.0"
+	CapabilityBase11 = "urn:ietf:params:netconf:base:1.1"
+)
+
+// legacyCapabilityBase10 is the namespace older Junos releases advertise in place of CapabilityBase10
+const legacyCapabilityBase10 = "urn:ietf:params:xml:ns:netconf:base:1.0"
+
+// DefaultMaxReplySize is the largest reply read when MaxReplySize is not set
+const DefaultMaxReplySize = 512 * 1024 * 1024
+
+// ErrReplyTooLarge is returned when a reply exceeds MaxReplySize, the transport is closed as the rest
+// of the reply can not be skipped reliably
+var ErrReplyTooLarge = errors.New("reply exceeds the maximum reply size")
+
+// ErrNoHello is returned when the first message from the server carries no hello element
+var ErrNoHello = errors.New("no NETCONF hello received")
+
+// helloStartRe matches the start of a hello element, with or without a namespace prefix
+var helloStartRe = regexp.MustCompile(`<([\w.-]+:)?hello[\s/>]`)
+
+// secretRe matches the content of the elements carrying a plain-text password, as an element or escaped
+// within a load-configuration
+var secretRe = regexp.MustCompile(`(<plain-text-password-value>)(?s:.*?)(</plain-text-password-value>)|` +
+	`(&lt;plain-text-password-value&gt;)(?s:.*?)(&lt;/plain-text-password-value&gt;)`)
+
+// RedactSecrets returns message with plain-text passwords replaced, for writing it where it may be kept,
+// such as the Debug transcript or a recorded script
+func RedactSecrets(message []byte) []byte {
+	return secretRe.ReplaceAll(message, []byte("${1}${3}REDACTED${2}${4}"))
+}
+
+// ErrNoCommonBase is returned when the client and server share no NETCONF base version
+var ErrNoCommonBase = errors.New("no common NETCONF base version")
+
+// DefaultCapabilities sets the default capabilities of the client library. Both base versions are
+// advertised, so chunked framing is used with servers supporting base:1.1, including those supporting nothing else.
 var DefaultCapabilities = []string{
-	"urn:ietf:params:netconf:base:1.0",
+	CapabilityBase10,
+	CapabilityBase11,
+}
+
+// NegotiateBase returns the highest base capability advertised by both the client and the server
+func NegotiateBase(client []string, server []string) (string, error) {
+	advertised := map[string]bool{}
+	for _, c := range server {
+		if c == legacyCapabilityBase10 {
+			c = CapabilityBase10
+		}
+		advertised[c] = true
+	}
+
+	supported := map[string]bool{}
+	for _, c := range client {
+		supported[c] = true
+	}
+
+	for _, base := range []string{CapabilityBase11, CapabilityBase10} {
+		if supported[base] && advertised[base] {
+			return base, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: client supports %v, server advertised %v", ErrNoCommonBase, baseCapabilities(client), baseCapabilities(server))
+}
+
+// baseCapabilities filters the base protocol capabilities out of a capability list
+func baseCapabilities(capabilities []string) []string {
+	var bases []string
+	for _, c := range capabilities {
+		if strings.Contains(c, ":netconf:base:") {
+			bases = append(bases, c)
+		}
+	}
+	return bases
 }
 
 // HelloMessage is used when bringing up a NETCONF session
@@ -45,11 +121,65 @@ type Transport interface {
 type TransportBasicIO struct {
 	io.ReadWriteCloser
 	chunkedFraming bool
+	reader         *bufio.Reader // Buffers reads once chunked framing is enabled
+
+	// ChunkSize is the largest chunk sent with chunked framing, DefaultChunkSize when zero
+	ChunkSize int
+
+	// MaxReplySize caps the bytes buffered for a single message, DefaultMaxReplySize when zero
+	// and unlimited when negative, so a runaway reply can not exhaust memory
+	MaxReplySize int64
+
+	// ForceFraming overrides the framing negotiated in the hello exchange with FramingEOM or FramingChunked.
+	// It breaks sessions with compliant devices and is only meant for debugging framing interop.
+	ForceFraming string
+
+	// Debug, when set, receives a transcript of every message sent ("C: ") and received ("S: ").
+	// Only NETCONF messages are written, SSH authentication happens below this layer, and plain-text
+	// passwords are redacted.
+	Debug io.Writer
+}
+
+// debug writes a message to the Debug transcript, prefixed with its direction
+func (t *TransportBasicIO) debug(direction string, data []byte) {
+	if t.Debug == nil {
+		return
+	}
+
+	fmt.Fprintf(t.Debug, "%s %s\n", direction, RedactSecrets(data))
+}
+
+// maxReplySize returns the configured reply size limit, zero meaning unlimited
+func (t *TransportBasicIO) maxReplySize() int64 {
+	switch {
+	case t.MaxReplySize == 0:
+		return DefaultMaxReplySize
+	case t.MaxReplySize < 0:
+		return 0
+	}
+	return t.MaxReplySize
+}
+
+// replyTooLarge closes the transport and returns ErrReplyTooLarge
+func (t *TransportBasicIO) replyTooLarge() error {
+	t.ReadWriteCloser.Close()
+	return fmt.Errorf("%w of %d bytes", ErrReplyTooLarge, t.maxReplySize())
 }
 
 // Send a well formated NETCONF rpc message as a slice of bytes adding on the
 // necessary framing messages.
 func (t *TransportBasicIO) Send(data []byte) error {
+	t.debug("C:", data)
+
+	if t.chunkedFraming {
+		w := newChunkWriter(t.ReadWriteCloser, t.chunkSize())
+		_, err := w.Write(data)
+		if err != nil {
+			return err
+		}
+		return w.Close()
+	}
+
 	t.Write(data)
 	// Pad to make sure the msgSeparator isn't sent across a 4096-byte boundary
 	if (len(data)+len(msgSeperator))%4096 < 6 {
@@ -62,7 +192,24 @@ func (t *TransportBasicIO) Send(data []byte) error {
 
 // Receive data over transport
 func (t *TransportBasicIO) Receive() ([]byte, error) {
-	return t.WaitForBytes([]byte(msgSeperator))
+	var (
+		data []byte
+		err  error
+	)
+
+	if t.chunkedFraming {
+		data, err = readChunked(t.reader, t.maxReplySize())
+		if errors.Is(err, ErrReplyTooLarge) {
+			err = t.replyTooLarge()
+		}
+	} else {
+		data, err = t.WaitForBytes([]byte(msgSeperator))
+	}
+	if err == nil {
+		t.debug("S:", data)
+	}
+
+	return data, err
 }
 
 // SendHello over transport
@@ -98,7 +245,16 @@ func (t *TransportBasicIO) ReceiveHello() (*HelloMessage, error) {
 		return hello, err
 	}
 
-	err = xml.Unmarshal([]byte(val), hello)
+	// Discard any login banner or MOTD the device sent ahead of the hello, it need not be valid XML
+	loc := helloStartRe.FindIndex(val)
+	if loc == nil {
+		if len(val) > 80 {
+			val = val[:80]
+		}
+		return hello, fmt.Errorf("%w, got %q", ErrNoHello, val)
+	}
+
+	err = xml.Unmarshal(val[loc[0]:], hello)
 	return hello, err
 }
 
@@ -140,6 +296,10 @@ func (t *TransportBasicIO) WaitForFunc(f func([]byte) (int, error)) ([]byte, err
 				copy(buf, buf[pos:pos+n])
 			}
 
+			if limit := t.maxReplySize(); limit > 0 && int64(out.Len()) > limit {
+				return nil, t.replyTooLarge()
+			}
+
 			pos = n
 		}
 	}
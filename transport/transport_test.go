@@ -9,9 +9,15 @@ package netconf
 import (
 	"bytes"
 	"encoding/xml"
+	"errors"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"regexp"
+	"strconv"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -98,6 +104,38 @@ func TestReceiveHello(t *testing.T) {
 	}
 }
 
+func TestReceiveHelloBanner(t *testing.T) {
+	input := `<<< Authorised access only & all activity is logged >>>
+Welcome to r1
+
+<?xml version="1.0" encoding="UTF-8"?>
+<nc:hello xmlns:nc="urn:ietf:params:xml:ns:netconf:base:1.0">
+<nc:capabilities><nc:capability>urn:ietf:params:netconf:base:1.0</nc:capability></nc:capabilities>
+<nc:session-id>7</nc:session-id>
+</nc:hello>
+]]>]]>`
+
+	trans, _ := newTransportTest(input)
+
+	hello, err := trans.ReceiveHello()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if hello.SessionID != 7 || !cmp.Equal(hello.Capabilities, []string{CapabilityBase10}) {
+		t.Errorf("got session %d with capabilities %v, expected 7 with %v", hello.SessionID, hello.Capabilities, []string{CapabilityBase10})
+	}
+}
+
+func TestReceiveHelloMissing(t *testing.T) {
+	trans, _ := newTransportTest("SSH-2.0-OpenSSH_8.0\r\nProtocol mismatch.\n]]>]]>")
+
+	_, err := trans.ReceiveHello()
+	if !errors.Is(err, ErrNoHello) {
+		t.Errorf("got error %v, expected %v", err, ErrNoHello)
+	}
+}
+
 func TestSendHello(t *testing.T) {
 	tt := []struct {
 		name     string
@@ -108,7 +146,7 @@ func TestSendHello(t *testing.T) {
 			name:  "default",
 			input: &HelloMessage{Capabilities: DefaultCapabilities},
 			expected: `<?xml version="1.0" encoding="UTF-8"?>
-<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><capabilities><capability>urn:ietf:params:netconf:base:1.0</capability></capabilities></hello>]]>]]>
+<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><capabilities><capability>urn:ietf:params:netconf:base:1.0</capability><capability>urn:ietf:params:netconf:base:1.1</capability></capabilities></hello>]]>]]>
 `,
 		},
 	}
@@ -207,3 +245,372 @@ func TestWaitForBytesEmpty(t *testing.T) {
 		t.Errorf("WaitForBytes should error on empty input!")
 	}
 }
+
+func TestNegotiateBase(t *testing.T) {
+	tt := []struct {
+		name     string
+		client   []string
+		server   []string
+		expected string
+		err      bool
+	}{
+		{
+			name:     "base10",
+			client:   []string{CapabilityBase10},
+			server:   []string{CapabilityBase10, "urn:ietf:params:netconf:capability:candidate:1.0"},
+			expected: CapabilityBase10,
+		},
+		{
+			name:     "legacyJunos",
+			client:   []string{CapabilityBase10},
+			server:   []string{"urn:ietf:params:xml:ns:netconf:base:1.0"},
+			expected: CapabilityBase10,
+		},
+		{
+			name:     "highestCommon",
+			client:   []string{CapabilityBase10, CapabilityBase11},
+			server:   []string{CapabilityBase10, CapabilityBase11},
+			expected: CapabilityBase11,
+		},
+		{
+			name:   "noCommon",
+			client: []string{CapabilityBase10},
+			server: []string{CapabilityBase11},
+			err:    true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			base, err := NegotiateBase(tc.client, tc.server)
+			if tc.err {
+				if !errors.Is(err, ErrNoCommonBase) {
+					t.Fatalf("got error %v, expected %v", err, ErrNoCommonBase)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if base != tc.expected {
+				t.Errorf("got %s, expected %s", base, tc.expected)
+			}
+		})
+	}
+}
+
+func TestDebugTranscript(t *testing.T) {
+	reply := `<rpc-reply message-id="1"><ok/></rpc-reply>`
+	request := `<rpc message-id="1"><get-config><source><running/></source></get-config></rpc>`
+
+	trans, _ := newTransportTest(reply + msgSeperator)
+
+	var transcript bytes.Buffer
+	trans.Debug = &transcript
+
+	err := trans.Send([]byte(request))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = trans.Receive()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "C: " + request + "\nS: " + reply + "\n"
+	if transcript.String() != expected {
+		t.Errorf("got %q, expected %q", transcript.String(), expected)
+	}
+}
+
+func TestDebugTranscriptRedactsPasswords(t *testing.T) {
+	reply := `<rpc-reply message-id="1"><ok/></rpc-reply>`
+	request := `<rpc message-id="1"><load-configuration><configuration><system><login><user><name>admin</name>` +
+		`<authentication><plain-text-password-value>s3cret!</plain-text-password-value></authentication>` +
+		`</user></login></system></configuration></load-configuration></rpc>`
+
+	trans, _ := newTransportTest(reply + msgSeperator)
+
+	var transcript bytes.Buffer
+	trans.Debug = &transcript
+
+	err := trans.Send([]byte(request))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if strings.Contains(transcript.String(), "s3cret!") {
+		t.Errorf("got transcript %q, expected the password redacted", transcript.String())
+	}
+
+	escaped := "&lt;plain-text-password-value&gt;s3&amp;cret!&lt;/plain-text-password-value&gt;"
+	expected := "&lt;plain-text-password-value&gt;REDACTED&lt;/plain-text-password-value&gt;"
+	if redacted := string(RedactSecrets([]byte(escaped))); redacted != expected {
+		t.Errorf("got %q, expected %q", redacted, expected)
+	}
+}
+
+// largePayload builds an rpc of roughly n bytes
+func largePayload(n int) []byte {
+	var buf bytes.Buffer
+
+	buf.WriteString("<rpc><load-configuration><configuration>")
+	for i := 0; buf.Len() < n; i++ {
+		fmt.Fprintf(&buf, "<interface><name>ge-0/0/%d</name><description>uplink %d</description></interface>", i, i)
+	}
+	buf.WriteString("</configuration></load-configuration></rpc>")
+
+	return buf.Bytes()
+}
+
+// chunkSizes returns the chunk-size of every chunk of a framed message
+func chunkSizes(t *testing.T, framed []byte) []int {
+	var sizes []int
+
+	re := regexp.MustCompile(`\n#([0-9]+)\n`)
+	for _, m := range re.FindAllSubmatch(framed, -1) {
+		size, err := strconv.Atoi(string(m[1]))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		sizes = append(sizes, size)
+	}
+
+	return sizes
+}
+
+func TestChunkedRoundTrip(t *testing.T) {
+	payload := largePayload(3 * 1024 * 1024)
+
+	tt := []struct {
+		name      string
+		chunkSize int
+		expected  int
+	}{
+		{name: "default", chunkSize: 0, expected: DefaultChunkSize},
+		{name: "small", chunkSize: 1000, expected: 1000},
+		{name: "large", chunkSize: 1024 * 1024, expected: 1024 * 1024},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			sender, framed := newTransportTest("")
+			sender.ChunkSize = tc.chunkSize
+			sender.EnableChunkedFraming()
+
+			err := sender.Send(payload)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			sizes := chunkSizes(t, framed.Bytes())
+			expectedChunks := (len(payload) + tc.expected - 1) / tc.expected
+			if len(sizes) != expectedChunks {
+				t.Errorf("got %d chunks, expected %d", len(sizes), expectedChunks)
+			}
+			for i, size := range sizes[:len(sizes)-1] {
+				if size != tc.expected {
+					t.Errorf("got chunk %d of %d bytes, expected %d", i, size, tc.expected)
+				}
+			}
+
+			receiver, _ := newTransportTest(framed.String())
+			receiver.EnableChunkedFraming()
+
+			received, err := receiver.Receive()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !bytes.Equal(received, payload) {
+				t.Errorf("got %d bytes back, expected the %d bytes sent", len(received), len(payload))
+			}
+		})
+	}
+}
+
+func TestChunkedReceive(t *testing.T) {
+	tt := []struct {
+		name     string
+		input    string
+		expected string
+		err      error
+	}{
+		{
+			// The example of RFC 6242 section 4.2
+			name:     "rfc",
+			input:    "\n#4\n<rpc\n#18\n message-id=\"102\"\n\n#79\n     xmlns=\"urn:ietf:params:xml:ns:netconf:base:1.0\">\n  <close-session/>\n</rpc>\n##\n",
+			expected: "<rpc message-id=\"102\"\n     xmlns=\"urn:ietf:params:xml:ns:netconf:base:1.0\">\n  <close-session/>\n</rpc>",
+		},
+		{
+			name:     "single byte chunks",
+			input:    "\n#1\n<\n#1\no\n#1\nk\n#1\n/\n#1\n>\n##\n",
+			expected: "<ok/>",
+		},
+		{
+			name:  "zero size",
+			input: "\n#0\n\n##\n",
+			err:   ErrBadChunk,
+		},
+		{
+			name:  "bad header",
+			input: "<ok/>]]>]]>",
+			err:   ErrBadChunk,
+		},
+		{
+			name:  "truncated",
+			input: "\n#10\n<ok/>",
+			err:   io.EOF,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			trans, _ := newTransportTest(tc.input)
+			trans.EnableChunkedFraming()
+
+			got, err := trans.Receive()
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("got error %v, expected %v", err, tc.err)
+			}
+
+			if string(got) != tc.expected {
+				t.Errorf("got %q, expected %q", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestReceiveStream(t *testing.T) {
+	reply := `<rpc-reply><data>` + strings.Repeat("<rt>]]></rt>", 1000) + `</data></rpc-reply>`
+
+	tt := []struct {
+		name    string
+		input   string
+		chunked bool
+	}{
+		{name: "eom", input: reply + "]]>]]>"},
+		{name: "chunked", input: "\n#7\n" + reply[:7] + "\n#" + strconv.Itoa(len(reply)-7) + "\n" + reply[7:] + "\n##\n", chunked: true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// One byte per read, so the separator is split across reads
+			trans := &TransportBasicIO{ReadWriteCloser: newNilCloser(iotest.OneByteReader(strings.NewReader(tc.input)), ioutil.Discard)}
+			if tc.chunked {
+				trans.EnableChunkedFraming()
+			}
+
+			r, err := trans.ReceiveStream()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got, err := ioutil.ReadAll(r)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if string(got) != reply {
+				t.Errorf("got %d bytes, expected the %d bytes of the reply", len(got), len(reply))
+			}
+		})
+	}
+
+	trans, _ := newTransportTest("<rpc-reply><ok/>")
+
+	r, _ := trans.ReceiveStream()
+	_, err := ioutil.ReadAll(r)
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("got error %v, expected %v for a truncated message", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func BenchmarkSendChunked(b *testing.B) {
+	payload := largePayload(4 * 1024 * 1024)
+
+	for _, size := range []int{4096, DefaultChunkSize, 1024 * 1024} {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			trans := &TransportBasicIO{ReadWriteCloser: newNilCloser(nil, ioutil.Discard), ChunkSize: size}
+			trans.EnableChunkedFraming()
+
+			b.SetBytes(int64(len(payload)))
+			for i := 0; i < b.N; i++ {
+				err := trans.Send(payload)
+				if err != nil {
+					b.Fatalf("unexpected error: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// endlessReader streams the same byte forever, like a runaway reply
+type endlessReader struct{}
+
+func (endlessReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 'a'
+	}
+	return len(p), nil
+}
+
+// closeRecorder records whether the transport was torn down
+type closeRecorder struct {
+	io.Reader
+	io.Writer
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestMaxReplySize(t *testing.T) {
+	tt := []struct {
+		name    string
+		chunked bool
+		input   io.Reader
+	}{
+		{name: "eom", input: endlessReader{}},
+		{name: "chunked", chunked: true, input: io.MultiReader(strings.NewReader("\n#1000000\n"), endlessReader{})},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			rwc := &closeRecorder{Reader: tc.input, Writer: ioutil.Discard}
+
+			trans := &TransportBasicIO{ReadWriteCloser: rwc, MaxReplySize: 64 * 1024}
+			if tc.chunked {
+				trans.EnableChunkedFraming()
+			}
+
+			_, err := trans.Receive()
+			if !errors.Is(err, ErrReplyTooLarge) {
+				t.Fatalf("got error %v, expected %v", err, ErrReplyTooLarge)
+			}
+
+			if !rwc.closed {
+				t.Errorf("transport not closed after an oversized reply")
+			}
+		})
+	}
+}
+
+func TestMaxReplySizeWithinLimit(t *testing.T) {
+	reply := "<rpc-reply><ok/></rpc-reply>"
+
+	trans, _ := newTransportTest(reply + msgSeperator)
+	trans.MaxReplySize = int64(len(reply) + len(msgSeperator))
+
+	data, err := trans.Receive()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if string(data) != reply {
+		t.Errorf("got %q, expected %q", data, reply)
+	}
+}